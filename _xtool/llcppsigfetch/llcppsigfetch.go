@@ -155,7 +155,7 @@ func runFromConfig(cfgFile string, useStdin bool, outputToFile bool, verbose boo
 	}
 
 	cflag := cfgparse.ParseCFlags(conf.CFlags)
-	files, notFounds, err := cflag.GenHeaderFilePaths(conf.Include, syspath.GetIncludePaths())
+	files, notFounds, err := cflag.GenPkgHeaderFilePaths(conf.Config, syspath.GetIncludePaths())
 	check(err)
 
 	if verbose {
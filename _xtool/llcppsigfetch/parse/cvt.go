@@ -2,8 +2,10 @@ package parse
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"unsafe"
 
@@ -49,7 +51,10 @@ func NewConverter(config *clangutils.Config) (*Converter, error) {
 		fmt.Fprintln(os.Stderr, "config.Temp", config.Temp)
 	}
 
-	index, unit, err := clangutils.CreateTranslationUnit(config)
+	// keep the nullability of pointers as attributed types
+	cfg := *config
+	cfg.AttributedTypes = true
+	index, unit, err := clangutils.CreateTranslationUnit(&cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -170,10 +175,15 @@ func (ct *Converter) GetCurFile(cursor clang.Cursor) *ast.File {
 }
 
 func (ct *Converter) CreateDeclBase(cursor clang.Cursor) ast.DeclBase {
+	_, line, column, offset := clangutils.GetLocation(cursor.Location())
 	base := ast.DeclBase{
 		Loc: &ast.Location{
-			File: ct.curLoc.File,
+			File:   ct.curLoc.File,
+			Line:   uint(line),
+			Column: uint(column),
+			Offset: uint(offset),
 		},
+		Attrs:  ct.ProcessAttrs(cursor),
 		Parent: ct.BuildScopingExpr(cursor.SemanticParent()),
 	}
 	commentGroup, isDoc := ct.ParseCommentGroup(cursor)
@@ -183,6 +193,117 @@ func (ct *Converter) CreateDeclBase(cursor clang.Cursor) ast.DeclBase {
 	return base
 }
 
+// attrNames maps the spellings of the recorded attributes to their names.
+var attrNames = map[string]string{
+	"deprecated":         ast.AttrDeprecated,
+	"unavailable":        ast.AttrUnavailable,
+	"noreturn":           ast.AttrNoReturn,
+	"_Noreturn":          ast.AttrNoReturn,
+	"format":             ast.AttrFormat,
+	"nonnull":            ast.AttrNonNull,
+	"malloc":             ast.AttrMalloc,
+	"warn_unused_result": ast.AttrWarnUnusedResult,
+	"nodiscard":          ast.AttrWarnUnusedResult,
+	"visibility":         ast.AttrVisibility,
+}
+
+// ProcessAttrs collects the attributes of a declaration, which libclang
+// visits as its children. Attributes not listed in attrNames are ignored.
+func (ct *Converter) ProcessAttrs(cursor clang.Cursor) []*ast.Attr {
+	var attrs []*ast.Attr
+	clangutils.VisitChildren(cursor, func(child, parent clang.Cursor) clang.ChildVisitResult {
+		if child.Kind >= clang.CursorFirstAttr && child.Kind <= clang.CursorLastAttr {
+			if attr := ct.ProcessAttr(child); attr != nil {
+				ct.logln("ProcessAttrs: Name:", attr.Name, "Args:", attr.Args)
+				attrs = append(attrs, attr)
+			}
+		}
+		return clang.ChildVisit_Continue
+	})
+	return attrs
+}
+
+// ProcessAttr reads an attribute from its tokens, as libclang doesn't expose
+// the arguments of most attributes. An attribute spelled by a macro expansion
+// has the tokens of the macro invocation, so only its cursor kind is known.
+func (ct *Converter) ProcessAttr(cursor clang.Cursor) *ast.Attr {
+	if attr := parseAttr(ct.GetTokens(cursor)); attr != nil {
+		return attr
+	}
+	switch cursor.Kind {
+	case clang.CursorVisibilityAttr:
+		return &ast.Attr{Name: ast.AttrVisibility}
+	case clang.CursorWarnUnusedResultAttr:
+		return &ast.Attr{Name: ast.AttrWarnUnusedResult}
+	}
+	return nil
+}
+
+// parseAttr parses the tokens of an attribute like format(printf, 1, 2)
+// or gnu::format(__printf__, 1, 2).
+func parseAttr(toks []*ast.Token) *ast.Attr {
+	for len(toks) > 2 && toks[1].Lit == "::" {
+		toks = toks[2:]
+	}
+	if len(toks) == 0 {
+		return nil
+	}
+	name, ok := attrNames[trimAttrUnderscores(toks[0].Lit)]
+	if !ok {
+		return nil
+	}
+	attr := &ast.Attr{Name: name}
+	if len(toks) < 2 || toks[1].Lit != "(" {
+		return attr
+	}
+	var arg []string
+	depth := 0
+	for _, tok := range toks[2:] {
+		switch tok.Lit {
+		case "(":
+			depth++
+		case ")":
+			if depth == 0 {
+				attr.Args = append(attr.Args, attrArg(arg))
+				return attr
+			}
+			depth--
+		case ",":
+			if depth == 0 {
+				attr.Args = append(attr.Args, attrArg(arg))
+				arg = nil
+				continue
+			}
+		}
+		arg = append(arg, tok.Lit)
+	}
+	return attr
+}
+
+// attrArg returns the text of an attribute argument, unquoting and
+// concatenating its string literals.
+func attrArg(lits []string) string {
+	var b strings.Builder
+	for _, lit := range lits {
+		if strings.HasPrefix(lit, `"`) {
+			if s, err := strconv.Unquote(lit); err == nil {
+				b.WriteString(s)
+				continue
+			}
+		}
+		b.WriteString(trimAttrUnderscores(lit))
+	}
+	return b.String()
+}
+
+// trimAttrUnderscores removes the surrounding __ of an attribute name, eg. __format__.
+func trimAttrUnderscores(name string) string {
+	if len(name) > 4 && strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__") {
+		return name[2 : len(name)-2]
+	}
+	return name
+}
+
 // extracts and parses comments associated with a given Clang cursor,
 // distinguishing between documentation comments and line comments.
 // It uses libclang to parse only Doxygen-style comments.
@@ -261,6 +382,7 @@ func (ct *Converter) visitTop(cursor, parent clang.Cursor) clang.ChildVisitResul
 		curFile.Decls = append(curFile.Decls, classDecl)
 		// class havent anonymous situation
 		ct.logln("visitTop: ProcessClassDecl END", classDecl.Name.Name)
+		ct.ProcessNestedDecls(cursor)
 	case clang.CursorStructDecl:
 		structDecl := ct.ProcessStructDecl(cursor)
 		curFile.Decls = append(curFile.Decls, structDecl)
@@ -271,6 +393,7 @@ func (ct *Converter) visitTop(cursor, parent clang.Cursor) clang.ChildVisitResul
 		} else {
 			ct.logln("ANONY")
 		}
+		ct.ProcessNestedDecls(cursor)
 	case clang.CursorUnionDecl:
 		unionDecl := ct.ProcessUnionDecl(cursor)
 		curFile.Decls = append(curFile.Decls, unionDecl)
@@ -281,25 +404,206 @@ func (ct *Converter) visitTop(cursor, parent clang.Cursor) clang.ChildVisitResul
 		} else {
 			ct.logln("ANONY")
 		}
+		ct.ProcessNestedDecls(cursor)
 	case clang.CursorFunctionDecl, clang.CursorCXXMethod, clang.CursorConstructor, clang.CursorDestructor:
 		// Handle functions and class methods (including out-of-class method)
 		// Example: void MyClass::myMethod() { ... } out-of-class method
 		funcDecl := ct.ProcessFuncDecl(cursor)
 		curFile.Decls = append(curFile.Decls, funcDecl)
 		ct.logln("visitTop: ProcessFuncDecl END", funcDecl.Name.Name, funcDecl.MangledName, "isStatic:", funcDecl.IsStatic, "isInline:", funcDecl.IsInline)
-	case clang.CursorTypedefDecl:
+	case clang.CursorTypedefDecl, clang.CursorTypeAliasDecl:
+		// using Alias = T; is the same as typedef T Alias;
 		typedefDecl := ct.ProcessTypeDefDecl(cursor)
 		if typedefDecl == nil {
 			return clang.ChildVisit_Continue
 		}
 		curFile.Decls = append(curFile.Decls, typedefDecl)
 		ct.logln("visitTop: ProcessTypeDefDecl END", typedefDecl.Name.Name)
-	case clang.CursorNamespace:
+	case clang.CursorVarDecl:
+		varDecl := ct.ProcessVarDecl(cursor)
+		if varDecl == nil {
+			return clang.ChildVisit_Continue
+		}
+		curFile.Decls = append(curFile.Decls, varDecl)
+		ct.logln("visitTop: ProcessVarDecl END", varDecl.Name.Name, varDecl.Value.Value)
+	case clang.CursorNamespace, clang.CursorLinkageSpec:
+		// the declarations of an extern "C" block or an inline namespace are those
+		// of the enclosing scope, see clangutils.BuildScopingParts
 		clangutils.VisitChildren(cursor, ct.visitTop)
 	}
 	return clang.ChildVisit_Continue
 }
 
+// ProcessVarDecl returns the constant of a const variable whose initializer
+// evaluates to an integer, a float or a string, like
+//
+//	static const int FOO_MAX = 64;
+//	constexpr double kPi = 3.14;
+//	static const char NAME[] = "foo";
+//
+// It returns nil for other variables, which are not converted.
+func (ct *Converter) ProcessVarDecl(cursor clang.Cursor) *ast.VarDecl {
+	ct.incIndent()
+	defer ct.decIndent()
+	name := toStr(cursor.String())
+	typ := cursor.Type()
+	constTyp := typ
+	if typ.Kind == clang.TypeConstantArray || typ.Kind == clang.TypeIncompleteArray {
+		constTyp = typ.ArrayElementType()
+	}
+	if constTyp.IsConstQualifiedType() == 0 {
+		ct.logln("ProcessVarDecl: skip non-const variable", name)
+		return nil
+	}
+	res, ok := clangutils.Evaluate(cursor)
+	if !ok {
+		ct.logln("ProcessVarDecl: skip variable without constant initializer", name)
+		return nil
+	}
+	value := &ast.BasicLit{}
+	switch res.Kind {
+	case clangutils.EvalInt:
+		value.Kind = ast.IntLit
+		if res.Unsigned {
+			value.Value = strconv.FormatUint(res.Uint, 10)
+		} else {
+			value.Value = strconv.FormatInt(res.Int, 10)
+		}
+	case clangutils.EvalFloat:
+		if math.IsInf(res.Float, 0) || math.IsNaN(res.Float) {
+			ct.logln("ProcessVarDecl: skip non-finite constant", name)
+			return nil
+		}
+		value.Kind = ast.FloatLit
+		value.Value = strconv.FormatFloat(res.Float, 'g', -1, 64)
+	case clangutils.EvalStrLiteral:
+		value.Kind = ast.StringLit
+		value.Value = strconv.Quote(res.Str)
+	}
+	ct.logln("ProcessVarDecl:", name, "=", value.Value)
+	varTyp := ct.ProcessType(typ)
+	ct.ProcessArrayLens(cursor, varTyp)
+	return &ast.VarDecl{
+		DeclBase: ct.CreateDeclBase(cursor),
+		Name:     &ast.Ident{Name: name},
+		Type:     varTyp,
+		Value:    value,
+	}
+}
+
+// ProcessArrayLens records the spelled lengths of the array type expr of the
+// declaration cursor which are names, like FOO_NAME_MAX of
+//
+//	char name[FOO_NAME_MAX];
+//
+// which clang has evaluated to the length of the array type, in LenExpr.
+func (ct *Converter) ProcessArrayLens(cursor clang.Cursor, expr ast.Expr) {
+	arr, ok := expr.(*ast.ArrayType)
+	if !ok {
+		return
+	}
+	lens := arrayLens(ct.GetTokens(cursor), toStr(cursor.String()))
+	var arrs []*ast.ArrayType
+	for ; arr != nil; arr, _ = arr.Elt.(*ast.ArrayType) {
+		arrs = append(arrs, arr)
+	}
+	if len(lens) != len(arrs) {
+		ct.logln("ProcessArrayLens: array declarator not found")
+		return
+	}
+	for i, name := range lens {
+		if name != "" && arrs[i].Len != nil {
+			arrs[i].LenExpr = &ast.Ident{Name: name}
+		}
+	}
+}
+
+// arrayLens returns the spelled lengths of the array declarator of name in the
+// tokens of its declaration, like FOO_MAX and "" of int a[FOO_MAX][2] = {0};,
+// where a length which is not a name is "". Brackets in parentheses, braces and
+// initializers and [[...]] attributes are not array declarators.
+func arrayLens(toks []*ast.Token, name string) []string {
+	// the declarator starts at the last name before the initializer, as in
+	// int a[2], b[FOO_MAX]; the tokens of b start at int
+	start, depth := -1, 0
+	for i, tok := range toks {
+		if depth == 0 && tok.Lit == "=" {
+			break
+		}
+		switch tok.Lit {
+		case "(", "{", "[":
+			depth++
+		case ")", "}", "]":
+			depth--
+		case name:
+			if depth == 0 && tok.Token == token.IDENT {
+				start = i
+			}
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+	var lens []string
+	depth = 0
+	for i := start + 1; i < len(toks); i++ {
+		switch toks[i].Lit {
+		case "(", "{":
+			depth++
+		case ")", "}":
+			depth--
+		case "=", ",", ";":
+			if depth == 0 {
+				return lens
+			}
+		case "[":
+			end, nested := i+1, 1
+			for ; end < len(toks) && nested > 0; end++ {
+				switch toks[end].Lit {
+				case "[":
+					nested++
+				case "]":
+					nested--
+				}
+			}
+			if depth == 0 && i+1 < len(toks) && toks[i+1].Lit != "[" {
+				spelled := toks[i+1 : end-1]
+				if len(spelled) == 1 && spelled[0].Token == token.IDENT {
+					lens = append(lens, spelled[0].Lit)
+				} else {
+					lens = append(lens, "")
+				}
+			}
+			i = end - 1
+		}
+	}
+	return lens
+}
+
+// ProcessNestedDecls hoists the named records, enums and typedefs declared in a
+// record out of it, after the record. Their Parent is the record in C++, like
+// Outer of struct Outer { struct Inner {}; }, and nil in C, where a nested
+// declaration is in the file scope. Anonymous records and enums are converted
+// inline as the types of their fields, and non-public ones are skipped.
+func (ct *Converter) ProcessNestedDecls(cursor clang.Cursor) {
+	clangutils.VisitChildren(cursor, func(subcsr, parent clang.Cursor) clang.ChildVisitResult {
+		switch subcsr.Kind {
+		case clang.CursorStructDecl, clang.CursorUnionDecl, clang.CursorClassDecl, clang.CursorEnumDecl:
+			if subcsr.IsAnonymous() != 0 {
+				return clang.ChildVisit_Continue
+			}
+		case clang.CursorTypedefDecl, clang.CursorTypeAliasDecl:
+		default:
+			return clang.ChildVisit_Continue
+		}
+		if access := subcsr.CXXAccessSpecifier(); access == clang.CXXPrivate || access == clang.CXXProtected {
+			return clang.ChildVisit_Continue
+		}
+		ct.logln("ProcessNestedDecls:", toStr(subcsr.String()))
+		return ct.visitTop(subcsr, parent)
+	})
+}
+
 func (ct *Converter) Convert() ([]*ast.FileEntry, error) {
 	cursor := ct.unit.Cursor()
 	// visit top decls (struct,class,function & macro,include)
@@ -314,11 +618,70 @@ func (ct *Converter) ProcessType(t clang.Type) ast.Expr {
 	typeName, typeKind := getTypeDesc(t)
 	ct.logln("ProcessType: TypeName:", typeName, "TypeKind:", typeKind)
 
+	quals := typeQualifiers(t)
+	var expr ast.Expr
+	switch t.Kind {
+	case clang.TypeAttributed:
+		// the nullability of a pointer, like int *_Nonnull
+		quals |= nullabilityQualifier(clangutils.TypeNullability(t))
+		expr = ct.ProcessType(clangutils.TypeModifiedType(t))
+	case clang.TypeAtomic:
+		quals |= ast.Atomic
+		expr = ct.ProcessType(clangutils.TypeValueType(t))
+	default:
+		expr = ct.processUnqualifiedType(t)
+	}
+	return qualifiedType(expr, quals)
+}
+
+// typeQualifiers returns the const, volatile and restrict qualifiers of t,
+// without looking through typedefs.
+func typeQualifiers(t clang.Type) ast.Qualifier {
+	var quals ast.Qualifier
+	if t.IsConstQualifiedType() != 0 {
+		quals |= ast.Const
+	}
+	if t.IsVolatileQualifiedType() != 0 {
+		quals |= ast.Volatile
+	}
+	if t.IsRestrictQualifiedType() != 0 {
+		quals |= ast.Restrict
+	}
+	return quals
+}
+
+// qualifiedType returns expr qualified by quals, merging them with
+// the qualifiers of expr if it is already a QualifiedType.
+func qualifiedType(expr ast.Expr, quals ast.Qualifier) ast.Expr {
+	if expr == nil || quals == 0 {
+		return expr
+	}
+	if q, ok := expr.(*ast.QualifiedType); ok {
+		return &ast.QualifiedType{X: q.X, Qualifiers: q.Qualifiers | quals}
+	}
+	return &ast.QualifiedType{X: expr, Qualifiers: quals}
+}
+
+func nullabilityQualifier(kind c.Int) ast.Qualifier {
+	switch kind {
+	case clangutils.TypeNullabilityNonNull:
+		return ast.Nonnull
+	case clangutils.TypeNullabilityNullable, clangutils.TypeNullabilityNullableResult:
+		return ast.Nullable
+	case clangutils.TypeNullabilityUnspecified:
+		return ast.NullUnspecified
+	}
+	return 0
+}
+
+func (ct *Converter) processUnqualifiedType(t clang.Type) ast.Expr {
 	if t.Kind >= clang.TypeFirstBuiltin && t.Kind <= clang.TypeLastBuiltin {
 		return ct.ProcessBuiltinType(t)
 	}
 
-	if t.Kind == clang.TypeElaborated {
+	if t.Kind == clang.TypeElaborated || t.Kind == clang.TypeRecord {
+		// a record type is elaborated as written, but canonical as a template
+		// argument, like std::basic_string<char> of std::vector<std::string>
 		return ct.ProcessElaboratedType(t)
 	}
 
@@ -370,7 +733,8 @@ func (ct *Converter) ProcessType(t clang.Type) ast.Expr {
 
 // For function types, we can only obtain the parameter types, but not the parameter names.
 // This is because we cannot reverse-lookup the corresponding declaration node from a function type.
-// Note: For function declarations, parameter names are collected in the ProcessFuncDecl method.
+// Note: For function declarations, parameter names are collected in the ProcessFuncDecl method,
+// and for typedefs and fields of function pointer types in the ProcessParamNames method.
 func (ct *Converter) ProcessFunctionType(t clang.Type) *ast.FuncType {
 	ct.incIndent()
 	defer ct.decIndent()
@@ -421,6 +785,8 @@ func (ct *Converter) ProcessTypeDefDecl(cursor clang.Cursor) *ast.TypedefDecl {
 		return nil
 	}
 
+	ct.ProcessParamNames(cursor, typ)
+
 	decl := &ast.TypedefDecl{
 		DeclBase: ct.CreateDeclBase(cursor),
 		Name:     &ast.Ident{Name: name},
@@ -429,12 +795,59 @@ func (ct *Converter) ProcessTypeDefDecl(cursor clang.Cursor) *ast.TypedefDecl {
 	return decl
 }
 
+// ProcessParamNames names the params of the function type of a typedef or field
+// declaration, like typedef void (*on_data)(void *ctx, size_t len), with the
+// ParmDecl children of the declaration cursor.
+func (ct *Converter) ProcessParamNames(cursor clang.Cursor, typ ast.Expr) {
+	fnType := funcTypeOf(typ)
+	if fnType == nil || fnType.Params == nil {
+		return
+	}
+	var names []string
+	clangutils.VisitChildren(cursor, func(child, parent clang.Cursor) clang.ChildVisitResult {
+		if child.Kind == clang.CursorParmDecl {
+			names = append(names, toStr(child.String()))
+		}
+		return clang.ChildVisit_Continue
+	})
+	params := fnType.Params.List
+	if n := len(params); n > 0 {
+		if _, ok := params[n-1].Type.(*ast.Variadic); ok {
+			params = params[:n-1]
+		}
+	}
+	if len(names) != len(params) {
+		ct.logln("ProcessParamNames: got", len(names), "names for", len(params), "params")
+		return
+	}
+	for i, name := range names {
+		if name != "" {
+			params[i].Names = []*ast.Ident{{Name: name}}
+		}
+	}
+}
+
+// funcTypeOf returns the function type of a function or function pointer type.
+func funcTypeOf(typ ast.Expr) *ast.FuncType {
+	switch t := typ.(type) {
+	case *ast.FuncType:
+		return t
+	case *ast.PointerType:
+		return funcTypeOf(t.X)
+	case *ast.QualifiedType:
+		return funcTypeOf(t.X)
+	}
+	return nil
+}
+
 func (ct *Converter) ProcessUnderlyingType(cursor clang.Cursor) ast.Expr {
 	underlyingTyp := cursor.TypedefDeclUnderlyingType()
 
 	if underlyingTyp.Kind != clang.TypeElaborated {
 		ct.logln("ProcessUnderlyingType: not elaborated")
-		return ct.ProcessType(underlyingTyp)
+		expr := ct.ProcessType(underlyingTyp)
+		ct.ProcessArrayLens(cursor, expr)
+		return expr
 	}
 
 	referTypeCursor := underlyingTyp.TypeDeclaration()
@@ -454,7 +867,7 @@ func (ct *Converter) ProcessUnderlyingType(cursor clang.Cursor) ast.Expr {
 		return nil
 	}
 
-	return ct.ProcessElaboratedType(underlyingTyp)
+	return qualifiedType(ct.ProcessElaboratedType(underlyingTyp), typeQualifiers(underlyingTyp))
 }
 
 // getActualType gets the actual type by handling only the outer Elaborated and Typedef types.
@@ -613,6 +1026,14 @@ func (ct *Converter) ProcessEnumType(cursor clang.Cursor) *ast.EnumType {
 					Value: c.GoString(val),
 				},
 			}
+			commentGroup, isDoc := ct.ParseCommentGroup(cursor)
+			if commentGroup != nil {
+				if isDoc {
+					enum.Doc = commentGroup
+				} else {
+					enum.Comment = commentGroup
+				}
+			}
 			items = append(items, enum)
 		}
 		return clang.ChildVisit_Continue
@@ -678,6 +1099,8 @@ func (ct *Converter) createBaseField(cursor clang.Cursor) *ast.Field {
 	field := &ast.Field{
 		Type: ct.ProcessType(typ),
 	}
+	ct.ProcessArrayLens(cursor, field.Type)
+	ct.ProcessParamNames(cursor, field.Type)
 
 	commentGroup, isDoc := ct.ParseCommentGroup(cursor)
 	if commentGroup != nil {
@@ -799,8 +1222,18 @@ func (ct *Converter) ProcessRecordType(cursor clang.Cursor) *ast.RecordType {
 	tag := toTag(cursor.Kind)
 	ct.logln("ProcessRecordType: toTag", tag)
 
-	ct.logln("ProcessRecordType: ProcessFieldList")
-	fields := ct.ProcessFieldList(cursor)
+	// a forward declaration like struct A; has no fields
+	var fields *ast.FieldList
+	var layout *ast.RecordLayout
+	var bases []*ast.BaseSpec
+	if clangutils.IsDefinition(cursor) {
+		ct.logln("ProcessRecordType: ProcessBases")
+		bases = ct.ProcessBases(cursor)
+		ct.logln("ProcessRecordType: ProcessFieldList")
+		fields = ct.ProcessFieldList(cursor)
+		ct.logln("ProcessRecordType: ProcessRecordLayout")
+		layout = ct.ProcessRecordLayout(cursor)
+	}
 
 	ct.logln("ProcessRecordType: ProcessMethods")
 	methods := ct.ProcessMethods(cursor)
@@ -809,7 +1242,126 @@ func (ct *Converter) ProcessRecordType(cursor clang.Cursor) *ast.RecordType {
 		Tag:     tag,
 		Fields:  fields,
 		Methods: methods,
+		Layout:  layout,
+		Bases:   bases,
+	}
+}
+
+// ProcessBases returns the base classes of a C++ record definition, like
+//
+//	class D : public B, protected virtual V {};
+//
+// with the byte offsets of the non-virtual ones in D.
+func (ct *Converter) ProcessBases(cursor clang.Cursor) []*ast.BaseSpec {
+	ct.incIndent()
+	defer ct.decIndent()
+	var bases []*ast.BaseSpec
+	clangutils.VisitChildren(cursor, func(subcsr, parent clang.Cursor) clang.ChildVisitResult {
+		if subcsr.Kind != clang.CursorCXXBaseSpecifier {
+			return clang.ChildVisit_Continue
+		}
+		ct.logln("ProcessBases: CursorCXXBaseSpecifier")
+		base := &ast.BaseSpec{
+			Type:    ct.ProcessType(subcsr.Type()),
+			Access:  ast.AccessSpecifier(subcsr.CXXAccessSpecifier()),
+			Virtual: clangutils.IsVirtualBase(subcsr),
+			Offset:  -1,
+		}
+		// the offset of a virtual base depends on the dynamic type of the object
+		if !base.Virtual {
+			if offset := clangutils.OffsetOfBase(cursor, subcsr); offset >= 0 {
+				base.Offset = offset / 8
+			}
+		}
+		bases = append(bases, base)
+		return clang.ChildVisit_Continue
+	})
+	return bases
+}
+
+// ProcessRecordLayout returns the layout of a record definition, in the order of
+// the fields of ProcessFieldList, if it differs from the natural layout of its
+// fields, like the layout of
+//
+//	#pragma pack(1)
+//	struct A { char c; int i; };
+//
+// which has i at offset 1. It returns nil for a natural layout, a record with
+// bit fields, and a record whose layout is unknown, like a template.
+func (ct *Converter) ProcessRecordLayout(cursor clang.Cursor) *ast.RecordLayout {
+	typ := cursor.Type()
+	layout := &ast.RecordLayout{
+		Size:  int64(typ.SizeOf()),
+		Align: clangutils.TypeAlignOf(typ),
+	}
+	if layout.Size < 0 || layout.Align <= 0 {
+		return nil
 	}
+	isUnion := cursor.Kind == clang.CursorUnionDecl
+	natural, valid := true, true
+	var end, align int64 = 0, 1
+	clangutils.VisitChildren(cursor, func(subcsr, parent clang.Cursor) clang.ChildVisitResult {
+		switch subcsr.Kind {
+		case clang.CursorPackedAttr:
+			layout.Packed = true
+		case clang.CursorAlignedAttr:
+			layout.Aligned = true
+		case clang.CursorFieldDecl:
+			if clangutils.IsBitField(subcsr) {
+				valid = false
+				return clang.ChildVisit_Break
+			}
+			ct.processFieldAttrs(subcsr, layout)
+			fieldTyp := subcsr.Type()
+			offset := clangutils.OffsetOfField(subcsr)
+			size, fieldAlign := int64(fieldTyp.SizeOf()), clangutils.TypeAlignOf(fieldTyp)
+			if offset < 0 || size < 0 || fieldAlign <= 0 {
+				valid = false
+				return clang.ChildVisit_Break
+			}
+			offset /= 8
+			naturalOffset := int64(0)
+			if !isUnion {
+				naturalOffset = alignUp(end, fieldAlign)
+			}
+			if offset != naturalOffset {
+				natural = false
+			}
+			if offset+size > end {
+				end = offset + size
+			}
+			if fieldAlign > align {
+				align = fieldAlign
+			}
+			layout.Offsets = append(layout.Offsets, offset)
+		case clang.CursorVarDecl:
+			if subcsr.StorageClass() == clang.SCStatic {
+				layout.Offsets = append(layout.Offsets, -1)
+			}
+		}
+		return clang.ChildVisit_Continue
+	})
+	if !valid || natural && layout.Align == align && layout.Size == alignUp(end, align) {
+		return nil
+	}
+	return layout
+}
+
+// processFieldAttrs records the packed and aligned attributes of a field.
+func (ct *Converter) processFieldAttrs(cursor clang.Cursor, layout *ast.RecordLayout) {
+	clangutils.VisitChildren(cursor, func(subcsr, parent clang.Cursor) clang.ChildVisitResult {
+		switch subcsr.Kind {
+		case clang.CursorPackedAttr:
+			layout.Packed = true
+		case clang.CursorAlignedAttr:
+			layout.Aligned = true
+		}
+		return clang.ChildVisit_Continue
+	})
+}
+
+func alignUp(n, align int64) int64 {
+	return (n + align - 1) / align * align
 }
 
 // process ElaboratedType Reference
@@ -829,6 +1381,12 @@ func (ct *Converter) ProcessElaboratedType(t clang.Type) ast.Expr {
 
 	decl := t.TypeDeclaration()
 
+	if isAliasTemplateSpecialization(t, decl) {
+		// an alias template has no Go type, its specialization is the aliased type,
+		// like int * of Ptr<int> of template <class T> using Ptr = T *;
+		return ct.ProcessType(t.CanonicalType())
+	}
+
 	if decl.IsAnonymous() != 0 {
 		// anonymous type refer (except anonymous RecordType&EnumType in TypedefDecl)
 		if decl.Kind == clang.CursorEnumDecl {
@@ -839,23 +1397,76 @@ func (ct *Converter) ProcessElaboratedType(t clang.Type) ast.Expr {
 
 	// for elaborated type, it could have a tag description
 	// like struct A, union B, class C, enum D
+	var expr ast.Expr = ct.BuildScopingExpr(decl)
 	parts := strings.SplitN(typeName, " ", 2)
 	if len(parts) == 2 {
 		if tagValue, ok := tagMap[parts[0]]; ok {
-			return &ast.TagExpr{
+			expr = &ast.TagExpr{
 				Tag:  tagValue,
-				Name: ct.BuildScopingExpr(decl),
+				Name: expr,
 			}
 		}
 	}
+	if decl.Kind == clang.CursorTypedefDecl || decl.Kind == clang.CursorTypeAliasDecl {
+		// the template arguments of a typedef are those of its underlying type
+		return expr
+	}
+	return ct.ProcessTemplateArgs(t, expr)
+}
+
+// isAliasTemplateSpecialization reports whether t, whose declaration is decl,
+// is a specialization of an alias template. The declaration is the alias
+// template if it aliases a non-record type, and the aliased record otherwise,
+// whose name is not the spelled one, like std::vector of Vec<int> of
+// template <class T> using Vec = std::vector<T>;
+func isAliasTemplateSpecialization(t clang.Type, decl clang.Cursor) bool {
+	if decl.Kind == clang.CursorTypeAliasTemplateDecl {
+		return true
+	}
+	if clangutils.TypeNumTemplateArguments(t) <= 0 || !isRecord(decl) {
+		return false
+	}
+	name := toStr(t.String())
+	if i := strings.IndexByte(name, '<'); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, "::"); i >= 0 {
+		name = name[i+2:]
+	}
+	if i := strings.LastIndexByte(name, ' '); i >= 0 {
+		name = name[i+1:]
+	}
+	return name != toStr(decl.String())
+}
 
-	return ct.BuildScopingExpr(decl)
+// ProcessTemplateArgs returns the instantiation of the template expr by the
+// type arguments of t, like std::vector<int>, or expr if t isn't a template
+// specialization. Arguments that are not types, like the extent of
+// std::span<int, 4>, are left out.
+func (ct *Converter) ProcessTemplateArgs(t clang.Type, expr ast.Expr) ast.Expr {
+	n := clangutils.TypeNumTemplateArguments(t)
+	if n <= 0 {
+		return expr
+	}
+	args := &ast.FieldList{}
+	for i := 0; i < n; i++ {
+		arg := clangutils.TypeTemplateArgument(t, i)
+		if arg.Kind == clang.TypeInvalid {
+			continue
+		}
+		args.List = append(args.List, &ast.Field{Type: ct.ProcessType(arg)})
+	}
+	return &ast.InstantiationType{Template: expr, Args: args}
 }
 
 func (ct *Converter) ProcessTypeDefType(t clang.Type) ast.Expr {
 	cursor := t.TypeDeclaration()
 	ct.logln("ProcessTypeDefType: Typedef TypeDeclaration", toStr(cursor.String()), toStr(t.String()))
 	if name := toStr(cursor.String()); name != "" {
+		if isRecord(cursor.SemanticParent()) {
+			// a typedef nested in a record, like Outer::Size
+			return ct.BuildScopingExpr(cursor)
+		}
 		return &ast.Ident{Name: name}
 	}
 	ct.logln("ProcessTypeDefType: typedef type have no name")
@@ -982,6 +1593,14 @@ func toToken(tok clang.Token) token.Token {
 		return token.Token(tok.Kind() + 1)
 	}
 }
+func isRecord(cursor clang.Cursor) bool {
+	switch cursor.Kind {
+	case clang.CursorStructDecl, clang.CursorUnionDecl, clang.CursorClassDecl:
+		return true
+	}
+	return false
+}
+
 func isMethod(cursor clang.Cursor) bool {
 	return cursor.Kind == clang.CursorCXXMethod || cursor.Kind == clang.CursorConstructor || cursor.Kind == clang.CursorDestructor
 }
@@ -0,0 +1,19 @@
+package main
+
+import test "github.com/goplus/llcppg/_xtool/llcppsigfetch/parse/cvt_test"
+
+func main() {
+	TestAttr()
+}
+
+func TestAttr() {
+	testCases := []string{
+		`void foo() __attribute__((deprecated("use bar instead")));`,
+		`__attribute__((noreturn, visibility("hidden"))) void fail();`,
+		`int log_printf(char *fmt, ...) __attribute__((__format__(__printf__, 1, 2), nonnull(1)));`,
+		`[[nodiscard]] void *make() __attribute__((malloc));`,
+		`struct __attribute__((deprecated)) Old { int x; };`,
+		`void gone() __attribute__((unavailable("removed")));`,
+	}
+	test.RunTest("TestAttr", testCases)
+}
@@ -49,6 +49,19 @@ func TestClassDecl() {
 		}
 		void A::Foo::bar();
 		`,
+		`class A {
+		public:
+			int a;
+		};
+		class B {
+		public:
+			int b;
+		};
+		class C : public A, protected B {
+		public:
+			int c;
+		};
+		class D : virtual public A {};`,
 	}
 	test.RunTest("TestClassDecl", testCases)
 }
@@ -72,6 +72,12 @@ class Doc
     void Foo();
   protected:
     int value;       /*!< protected field comment */
+};`,
+		`
+enum Color {
+	/// item doc
+	Red,
+	Green, ///< item comment
 };`,
 	}
 	test.RunTest("TestDoc", testCases)
@@ -31,6 +31,11 @@ func TestFuncDecl() {
 		                                void **provctx);
 		OSSL_provider_init_fn OSSL_provider_init;
 		   `,
+		`const int *_Nonnull foo(int *__restrict _Nullable p, volatile int *v);`,
+		`namespace std {
+template <class T> class vector {};
+}
+std::vector<int> range(const std::vector<char> &v);`,
 	}
 	test.RunTest("TestFuncDecl", testCases)
 }
@@ -27,6 +27,14 @@ func TestScope() {
 			void foo(); 
 		 };
 	   	 }`,
+		`extern "C" {
+			void foo();
+		 }`,
+		`namespace a {
+		 inline namespace v1 {
+			void foo();
+		 }
+		 }`,
 	}
 	test.RunTest("TestScope", testCases)
 }
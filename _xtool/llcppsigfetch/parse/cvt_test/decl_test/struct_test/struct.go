@@ -30,6 +30,30 @@ func TestStructDecl() {
 				int month;
 			} birthday;
 		};`,
+		`struct Outer {
+			struct Inner {
+				int a;
+			};
+			enum Kind { A };
+			typedef int Size;
+			Inner in;
+			Kind kind;
+			Size size;
+		};`,
+		`#pragma pack(1)
+		struct Hdr {
+			unsigned char kind;
+			int len;
+		};
+		#pragma pack()
+		struct __attribute__((aligned(8))) Id {
+			int id __attribute__((packed));
+		};`,
+		`#define NAME_MAX 64
+		struct Rec {
+			char name[NAME_MAX];
+			int m[NAME_MAX][2];
+		};`,
 	}
 	test.RunTest("TestStructDecl", testCases)
 }
@@ -54,6 +54,11 @@ func TestTypeDefDecl() {
 				} MyStruct,MyStruct2,*StructPtr, StructArr[];
 			}
 		}`,
+
+		`typedef void (*Callback)(void *ctx, int, int len);`,
+		`using INT = int;`,
+		`template <class T> using Ptr = T *;
+		using IntPtr = Ptr<int>;`,
 	}
 	test.RunTest("TestTypeDefDecl", testCases)
 }
@@ -0,0 +1,21 @@
+package main
+
+import test "github.com/goplus/llcppg/_xtool/llcppsigfetch/parse/cvt_test"
+
+func main() {
+	TestVarDecl()
+}
+
+func TestVarDecl() {
+	testCases := []string{
+		`static const int FOO_MAX = 64;
+		constexpr double kPi = 3.5;
+		static const char NAME[] = "foo";
+		const char *const GREETING = "hi";
+		int counter;
+		extern const int LIMIT;
+		constexpr double kInf = __builtin_inf();
+		constexpr double kNaN = __builtin_nan("");`,
+	}
+	test.RunTest("TestVarDecl", testCases)
+}
@@ -141,6 +141,12 @@ func MarshalASTDecl(decl ast.Decl) *cjson.JSON {
 		root.SetItem(c.Str("IsDestructor"), boolField(d.IsDestructor))
 		root.SetItem(c.Str("IsVirtual"), boolField(d.IsVirtual))
 		root.SetItem(c.Str("IsOverride"), boolField(d.IsOverride))
+	case *ast.VarDecl:
+		root.SetItem(c.Str("_Type"), stringField("VarDecl"))
+		MarshalASTDeclBase(d.DeclBase, root)
+		root.SetItem(c.Str("Name"), MarshalASTExpr(d.Name))
+		root.SetItem(c.Str("Type"), MarshalASTExpr(d.Type))
+		root.SetItem(c.Str("Value"), MarshalASTExpr(d.Value))
 	case *ast.TypeDecl:
 		root.SetItem(c.Str("_Type"), stringField("TypeDecl"))
 		MarshalASTDeclBase(d.DeclBase, root)
@@ -154,11 +160,52 @@ func MarshalASTDeclBase(decl ast.DeclBase, root *cjson.JSON) {
 	loc := cjson.Object()
 	loc.SetItem(c.Str("_Type"), stringField("Location"))
 	loc.SetItem(c.Str("File"), stringField(decl.Loc.File))
+	loc.SetItem(c.Str("Line"), numberField(decl.Loc.Line))
+	loc.SetItem(c.Str("Column"), numberField(decl.Loc.Column))
+	loc.SetItem(c.Str("Offset"), numberField(decl.Loc.Offset))
 	root.SetItem(c.Str("Loc"), loc)
 	root.SetItem(c.Str("Doc"), MarshalASTExpr(decl.Doc))
+	root.SetItem(c.Str("Attrs"), MarshalAttrs(decl.Attrs))
 	root.SetItem(c.Str("Parent"), MarshalASTExpr(decl.Parent))
 }
 
+func MarshalAttrs(attrs []*ast.Attr) *cjson.JSON {
+	if attrs == nil {
+		return cjson.Null()
+	}
+	root := cjson.Array()
+	for _, attr := range attrs {
+		item := cjson.Object()
+		item.SetItem(c.Str("_Type"), stringField("Attr"))
+		item.SetItem(c.Str("Name"), stringField(attr.Name))
+		args := cjson.Array()
+		for _, arg := range attr.Args {
+			args.AddItem(stringField(arg))
+		}
+		item.SetItem(c.Str("Args"), args)
+		root.AddItem(item)
+	}
+	return root
+}
+
+func MarshalRecordLayout(layout *ast.RecordLayout) *cjson.JSON {
+	if layout == nil {
+		return cjson.Null()
+	}
+	root := cjson.Object()
+	root.SetItem(c.Str("_Type"), stringField("RecordLayout"))
+	root.SetItem(c.Str("Packed"), boolField(layout.Packed))
+	root.SetItem(c.Str("Aligned"), boolField(layout.Aligned))
+	root.SetItem(c.Str("Size"), cjson.Number(float64(layout.Size)))
+	root.SetItem(c.Str("Align"), cjson.Number(float64(layout.Align)))
+	offsets := cjson.Array()
+	for _, offset := range layout.Offsets {
+		offsets.AddItem(cjson.Number(float64(offset)))
+	}
+	root.SetItem(c.Str("Offsets"), offsets)
+	return root
+}
+
 func MarshalASTExpr(t ast.Expr) *cjson.JSON {
 	if t == nil {
 		return cjson.Null()
@@ -178,15 +225,33 @@ func MarshalASTExpr(t ast.Expr) *cjson.JSON {
 		root.SetItem(c.Str("_Type"), stringField("EnumItem"))
 		root.SetItem(c.Str("Name"), MarshalASTExpr(d.Name))
 		root.SetItem(c.Str("Value"), MarshalASTExpr(d.Value))
+		root.SetItem(c.Str("Doc"), MarshalASTExpr(d.Doc))
+		root.SetItem(c.Str("Comment"), MarshalASTExpr(d.Comment))
 	case *ast.RecordType:
 		root.SetItem(c.Str("_Type"), stringField("RecordType"))
 		root.SetItem(c.Str("Tag"), numberField(uint(d.Tag)))
-		root.SetItem(c.Str("Fields"), MarshalASTExpr(d.Fields))
+		if d.Fields != nil {
+			root.SetItem(c.Str("Fields"), MarshalASTExpr(d.Fields))
+		} else {
+			root.SetItem(c.Str("Fields"), cjson.Null())
+		}
 		methods := cjson.Array()
 		for _, m := range d.Methods {
 			methods.AddItem(MarshalASTDecl(m))
 		}
 		root.SetItem(c.Str("Methods"), methods)
+		root.SetItem(c.Str("Layout"), MarshalRecordLayout(d.Layout))
+		bases := cjson.Array()
+		for _, b := range d.Bases {
+			bases.AddItem(MarshalASTExpr(b))
+		}
+		root.SetItem(c.Str("Bases"), bases)
+	case *ast.BaseSpec:
+		root.SetItem(c.Str("_Type"), stringField("BaseSpec"))
+		root.SetItem(c.Str("Type"), MarshalASTExpr(d.Type))
+		root.SetItem(c.Str("Access"), numberField(uint(d.Access)))
+		root.SetItem(c.Str("Virtual"), boolField(d.Virtual))
+		root.SetItem(c.Str("Offset"), cjson.Number(float64(d.Offset)))
 	case *ast.FuncType:
 		root.SetItem(c.Str("_Type"), stringField("FuncType"))
 		root.SetItem(c.Str("Params"), MarshalASTExpr(d.Params))
@@ -214,6 +279,10 @@ func MarshalASTExpr(t ast.Expr) *cjson.JSON {
 		root.SetItem(c.Str("_Type"), stringField("TagExpr"))
 		root.SetItem(c.Str("Name"), MarshalASTExpr(d.Name))
 		root.SetItem(c.Str("Tag"), numberField(uint(d.Tag)))
+	case *ast.InstantiationType:
+		root.SetItem(c.Str("_Type"), stringField("InstantiationType"))
+		root.SetItem(c.Str("Template"), MarshalASTExpr(d.Template))
+		root.SetItem(c.Str("Args"), MarshalASTExpr(d.Args))
 	case *ast.BasicLit:
 		root.SetItem(c.Str("_Type"), stringField("BasicLit"))
 		root.SetItem(c.Str("Kind"), numberField(uint(d.Kind)))
@@ -227,10 +296,15 @@ func MarshalASTExpr(t ast.Expr) *cjson.JSON {
 	case *ast.PointerType:
 		root.SetItem(c.Str("_Type"), stringField("PointerType"))
 		root.SetItem(c.Str("X"), MarshalASTExpr(d.X))
+	case *ast.QualifiedType:
+		root.SetItem(c.Str("_Type"), stringField("QualifiedType"))
+		root.SetItem(c.Str("X"), MarshalASTExpr(d.X))
+		root.SetItem(c.Str("Qualifiers"), numberField(uint(d.Qualifiers)))
 	case *ast.ArrayType:
 		root.SetItem(c.Str("_Type"), stringField("ArrayType"))
 		root.SetItem(c.Str("Elt"), MarshalASTExpr(d.Elt))
 		root.SetItem(c.Str("Len"), MarshalASTExpr(d.Len))
+		root.SetItem(c.Str("LenExpr"), MarshalASTExpr(d.LenExpr))
 	case *ast.BuiltinType:
 		root.SetItem(c.Str("_Type"), stringField("BuiltinType"))
 		root.SetItem(c.Str("Kind"), numberField(uint(d.Kind)))
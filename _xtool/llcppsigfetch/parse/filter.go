@@ -0,0 +1,84 @@
+package parse
+
+import (
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/types"
+)
+
+// filterFile removes the declarations and macros of entry, a header of the
+// package, excluded by filters. An excluded header keeps its includes, so that
+// the headers it includes are still found.
+func filterFile(entry *ast.FileEntry, filters *types.Filters) {
+	if filters == nil || entry.Doc == nil {
+		return
+	}
+	doc := entry.Doc
+	if !filters.Header(entry.Path) {
+		doc.Decls, doc.Macros = nil, nil
+		return
+	}
+	decls := doc.Decls[:0]
+	for _, decl := range doc.Decls {
+		if declSelected(decl, filters) {
+			decls = append(decls, decl)
+		}
+	}
+	doc.Decls = decls
+	macros := doc.Macros[:0]
+	for _, macro := range doc.Macros {
+		if filters.Macro(macro.Name) {
+			macros = append(macros, macro)
+		}
+	}
+	doc.Macros = macros
+}
+
+// declSelected reports whether decl is selected by filters, functions and
+// variables by the symbol filter and types by the type filter, both by their
+// qualified C++ names like ns::Foo::bar. The excluded methods of a selected
+// record are removed from it.
+func declSelected(decl ast.Decl, filters *types.Filters) bool {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return filters.Symbol(qualifiedName(decl.Parent, decl.Name))
+	case *ast.VarDecl:
+		return filters.Symbol(qualifiedName(decl.Parent, decl.Name))
+	case *ast.TypedefDecl:
+		return filters.Type(qualifiedName(decl.Parent, decl.Name))
+	case *ast.EnumTypeDecl:
+		return decl.Name == nil || filters.Type(qualifiedName(decl.Parent, decl.Name))
+	case *ast.TypeDecl:
+		if decl.Name != nil && !filters.Type(qualifiedName(decl.Parent, decl.Name)) {
+			return false
+		}
+		methods := decl.Type.Methods[:0]
+		for _, method := range decl.Type.Methods {
+			if filters.Symbol(qualifiedName(method.Parent, method.Name)) {
+				methods = append(methods, method)
+			}
+		}
+		decl.Type.Methods = methods
+	}
+	return true
+}
+
+// qualifiedName returns the C++ name of name in the scope parent, like
+// ns::Foo::bar, or name itself in C.
+func qualifiedName(parent ast.Expr, name *ast.Ident) string {
+	if scope := scopeName(parent); scope != "" {
+		return scope + "::" + name.Name
+	}
+	return name.Name
+}
+
+func scopeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.ScopingExpr:
+		if x, ok := expr.X.(*ast.Ident); ok {
+			return qualifiedName(expr.Parent, x)
+		}
+	}
+	return ""
+}
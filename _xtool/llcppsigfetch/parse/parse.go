@@ -8,13 +8,15 @@ import (
 
 	"github.com/goplus/llcppg/_xtool/llcppsigfetch/dbg"
 	"github.com/goplus/llcppg/_xtool/llcppsymg/clangutils"
+	"github.com/goplus/llcppg/_xtool/llcppsymg/config/cfgparse"
 	"github.com/goplus/llcppg/ast"
 	"github.com/goplus/llcppg/types"
 	"github.com/goplus/llgo/c/cjson"
 )
 
 type Context struct {
-	FileSet []*ast.FileEntry
+	FileSet  []*ast.FileEntry
+	pkgFiles map[string]bool // the headers of the package, whose declarations are filtered
 	*ContextConfig
 }
 
@@ -37,12 +39,19 @@ func (p *Context) Output() *cjson.JSON {
 	return MarshalFileSet(p.FileSet)
 }
 
-// ProcessFiles processes the given files and adds them to the context
+// ProcessFiles processes the given files and adds them to the context. The
+// headers and declarations excluded by the filters of the config are left out.
 func (p *Context) ProcessFiles(files []string) error {
 	if dbg.GetDebugParse() {
 		fmt.Fprintln(os.Stderr, "ProcessFiles: files", files, "isCpp", p.Conf.Cplusplus)
 	}
+	if p.pkgFiles == nil {
+		p.pkgFiles = make(map[string]bool)
+	}
 	for _, file := range files {
+		p.pkgFiles[file] = true
+	}
+	for _, file := range cfgparse.FilterHeaderFilePaths(files, p.Conf.Filters) {
 		if err := p.processFile(file); err != nil {
 			return err
 		}
@@ -68,6 +77,13 @@ func (p *Context) processFile(path string) error {
 		return errors.New("failed to parse file: " + path)
 	}
 
+	// the system and dependency headers are kept whole, as the declarations
+	// of the package may refer to their types
+	for _, entry := range parsedFiles {
+		if p.pkgFiles[entry.Path] && !entry.IsSys {
+			filterFile(entry, p.Conf.Filters)
+		}
+	}
 	p.FileSet = append(p.FileSet, parsedFiles...)
 	return nil
 }
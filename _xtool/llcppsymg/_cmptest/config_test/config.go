@@ -9,6 +9,7 @@ import (
 
 	"github.com/goplus/llcppg/_xtool/llcppsymg/config"
 	"github.com/goplus/llcppg/_xtool/llcppsymg/config/cfgparse"
+	"github.com/goplus/llcppg/types"
 )
 
 func main() {
@@ -17,6 +18,7 @@ func main() {
 	TestGenDylibPaths()
 	TestParseCFlags()
 	TestGenHeaderFilePath()
+	TestGenPkgHeaderFilePaths()
 }
 
 func TestGetConf() {
@@ -44,6 +46,51 @@ func TestGetConf() {
   "libs": "-L/opt/homebrew/lib -llua -lm",
   "trimPrefixes": ["lua_", "lua_"],
   "cplusplus": false
+}`,
+		},
+		{
+			name: "Filters configuration",
+			input: `{
+  "name": "foo",
+  "include": ["foo.h"],
+  "filters": {
+    "symbols": {"exclude": ["foo_internal_*"]},
+    "headers": {"include": ["foo/*.h"], "exclude": ["foo/experimental.h"]}
+  }
+}`,
+		},
+		{
+			name: "Invalid filter pattern",
+			input: `{
+  "name": "foo",
+  "filters": {
+    "symbols": {"exclude": ["foo_*", "/foo_(/"]}
+  }
+}`,
+		},
+		{
+			name: "Include all headers configuration",
+			input: `{
+  "name": "foo",
+  "cflags": "-I/opt/foo/include",
+  "include": ["foo/*.h"],
+  "includeAll": true,
+  "includeExclude": ["foo/internal/**"]
+}`,
+		},
+		{
+			name: "Platforms configuration",
+			input: `{
+  "name": "foo",
+  "cflags": "-I/opt/foo/include",
+  "libs": "-lfoo",
+  "include": ["foo.h"],
+  "trimPrefixes": ["foo_"],
+  "platforms": [
+    {"when": "threads", "cflags": "-DFOO_USE_THREADS", "include": ["foo_threads.h"]},
+    {"when": "!threads", "include": ["foo_single.h"]},
+    {"when": "threads,!nodebug", "libs": "-lfoo_debug", "trimPrefixes": ["foo_debug_"]}
+  ]
 }`,
 		},
 		{
@@ -52,6 +99,10 @@ func TestGetConf() {
 		},
 	}
 
+	// the platform configs of the tag threads are merged
+	os.Setenv(types.TagsEnv, "threads")
+	defer os.Unsetenv(types.TagsEnv)
+
 	for _, tc := range testCases {
 		fmt.Printf("=== Test case: %s ===\n", tc.name)
 		result, err := config.GetConf([]byte(tc.input))
@@ -65,11 +116,24 @@ func TestGetConf() {
 			fmt.Println("Include:", strings.Join(result.Config.Include, ", "))
 			fmt.Println("TrimPrefixes:", strings.Join(result.Config.TrimPrefixes, ", "))
 			fmt.Println("Cplusplus:", result.Config.Cplusplus)
+			if filters := result.Config.Filters; filters != nil {
+				fmt.Printf("Filters: symbols %s, types %s, macros %s, headers %s\n", filterString(filters.Symbols), filterString(filters.Types), filterString(filters.Macros), filterString(filters.Headers))
+			}
+			if result.Config.IncludeAll {
+				fmt.Println("IncludeExclude:", strings.Join(result.Config.IncludeExclude, ", "))
+			}
 		}
 		fmt.Println()
 	}
 }
 
+func filterString(f *types.Filter) string {
+	if f == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("{Include:%v Exclude:%v}", f.Include, f.Exclude)
+}
+
 func TestParseLibs() {
 	fmt.Println("=== Test ParseLibs ===")
 
@@ -330,3 +394,58 @@ func TestGenHeaderFilePath() {
 		fmt.Println()
 	}
 }
+
+func TestGenPkgHeaderFilePaths() {
+	fmt.Println("=== Test GenPkgHeaderFilePaths ===")
+
+	tempDir, err := os.MkdirTemp("", "pkg_headers")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tempDir)
+	for _, file := range []string{"foo.h", "foo/bar.h", "foo/baz.hpp", "foo/readme.txt", "foo/internal/impl.h", "other/other.h"} {
+		path := filepath.Join(tempDir, file)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.Create(path)
+	}
+
+	testCases := []struct {
+		name string
+		conf *types.Config
+	}{
+		{
+			name: "Glob pattern",
+			conf: &types.Config{Include: []string{"foo.h", "foo/*.h"}},
+		},
+		{
+			name: "Recursive glob pattern",
+			conf: &types.Config{Include: []string{"foo/**/*.h", "missing/*.h"}},
+		},
+		{
+			name: "Include all headers",
+			conf: &types.Config{Include: []string{"foo.h"}, IncludeAll: true, IncludeExclude: []string{"foo/internal/**", "other/*.h"}},
+		},
+		{
+			name: "Include all headers without include list",
+			conf: &types.Config{IncludeAll: true},
+		},
+	}
+
+	cflag := cfgparse.ParseCFlags("-I" + tempDir)
+	for _, tc := range testCases {
+		fmt.Printf("Test case: %s\n", tc.name)
+		result, notFounds, err := cflag.GenPkgHeaderFilePaths(tc.conf, []string{})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		if len(notFounds) > 0 {
+			fmt.Println("notFounds", notFounds)
+		}
+		relativeResult := make([]string, len(result))
+		for i, path := range result {
+			rel, _ := filepath.Rel(tempDir, path)
+			relativeResult[i] = filepath.ToSlash(rel)
+		}
+		fmt.Printf("Output: %v\n", relativeResult)
+	}
+}
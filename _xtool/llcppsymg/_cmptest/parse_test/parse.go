@@ -65,10 +65,11 @@ func TestAddSuffix() {
 
 func TestParseHeaderFile() {
 	testCases := []struct {
-		name     string
-		content  string
-		isCpp    bool
-		prefixes []string
+		name       string
+		content    string
+		isCpp      bool
+		prefixes   []string
+		staticName string
 	}{
 		{
 			name: "C++ Class with Methods",
@@ -152,12 +153,75 @@ int(lua_sizecomp)(size_t s, int idx1, int idx2, int op);
 			isCpp:    false,
 			prefixes: []string{""},
 		},
+		{
+			name: "C++ Static Members",
+			content: `
+class Counter {
+  public:
+    static int count;
+    static const char *name;
+    static Counter *Create(int n);
+    int Get();
+};
+            `,
+			isCpp:    true,
+			prefixes: []string{},
+		},
+		{
+			name: "C++ Static Members With StaticName",
+			content: `
+class Logger {
+  public:
+    static Logger *Default();
+    static int level;
+  private:
+    static int hidden;
+};
+            `,
+			isCpp:      true,
+			prefixes:   []string{},
+			staticName: "{name}{class}",
+		},
+		{
+			name: "C++ Struct And Union Static Members",
+			content: `
+struct S {
+    static int n;
+    static S *Make();
+    int get();
+};
+union U {
+    static int u;
+    int i;
+};
+            `,
+			isCpp:    true,
+			prefixes: []string{},
+		},
+		{
+			name: "C++ Extern C And Inline Namespace",
+			content: `
+extern "C" {
+    int foo_add(int a, int b);
+}
+namespace ns {
+inline namespace v1 {
+class Item {
+  public:
+    int id();
+};
+}
+}
+            `,
+			isCpp:    true,
+			prefixes: []string{"foo_"},
+		},
 	}
 
 	for _, tc := range testCases {
 		fmt.Printf("=== Test Case: %s ===\n", tc.name)
 
-		symbolMap, err := parse.ParseHeaderFile([]string{tc.content}, tc.prefixes, []string{}, tc.isCpp, true)
+		symbolMap, err := parse.ParseHeaderFile([]string{tc.content}, tc.prefixes, tc.staticName, []string{}, tc.isCpp, true)
 
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
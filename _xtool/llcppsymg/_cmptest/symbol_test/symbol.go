@@ -23,6 +23,7 @@ func TestGetCommonSymbols() {
 		name          string
 		dylibSymbols  []*nm.Symbol
 		headerSymbols map[string]*parse.SymbolInfo
+		filters       *types.Filters
 	}{
 		{
 			name: "Lua symbols",
@@ -56,11 +57,31 @@ func TestGetCommonSymbols() {
 				"_ZNK9INIReader10GetBooleanERKNSt3__112basic_stringIcNS0_11char_traitsIcEENS0_9allocatorIcEEEES8_b": {GoName: "(*Reader).GetBoolean", ProtoName: "INIReader::GetBoolean(const std::string &, const std::string &, bool)"},
 			},
 		},
+		{
+			name: "Filtered symbols",
+			dylibSymbols: []*nm.Symbol{
+				{Name: symbol.AddSymbolPrefixUnder("lua_absindex", false)},
+				{Name: symbol.AddSymbolPrefixUnder("lua_arith", false)},
+				{Name: symbol.AddSymbolPrefixUnder("lua_callk", false)},
+				{Name: symbol.AddSymbolPrefixUnder("ZNK9INIReader10ParseErrorEv", true)},
+				{Name: symbol.AddSymbolPrefixUnder("ZNK9INIReader7GetRealERKNSt3__112basic_stringIcNS0_11char_traitsIcEENS0_9allocatorIcEEEES8_d", true)},
+			},
+			headerSymbols: map[string]*parse.SymbolInfo{
+				"lua_absindex":                 {ProtoName: "lua_absindex(lua_State *, int)", GoName: "Absindex"},
+				"lua_arith":                    {ProtoName: "lua_arith(lua_State *, int)", GoName: "Arith"},
+				"lua_callk":                    {ProtoName: "lua_callk(lua_State *, int, int, lua_KContext, lua_KFunction)", GoName: "Callk"},
+				"_ZNK9INIReader10ParseErrorEv": {GoName: "(*Reader).ParseError", ProtoName: "INIReader::ParseError()"},
+				"_ZNK9INIReader7GetRealERKNSt3__112basic_stringIcNS0_11char_traitsIcEENS0_9allocatorIcEEEES8_d": {GoName: "(*Reader).GetReal", ProtoName: "INIReader::GetReal(const std::string &, const std::string &, double)"},
+			},
+			filters: &types.Filters{
+				Symbols: &types.Filter{Exclude: []string{"lua_a*", "/^INIReader::Parse/"}},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		fmt.Printf("\nTest Case: %s\n", tc.name)
-		commonSymbols := symbol.GetCommonSymbols(tc.dylibSymbols, tc.headerSymbols)
+		commonSymbols := symbol.GetCommonSymbols(tc.dylibSymbols, tc.headerSymbols, tc.filters)
 		fmt.Printf("Common Symbols (%d):\n", len(commonSymbols))
 		for _, sym := range commonSymbols {
 			fmt.Printf("Mangle: %s, CPP: %s, Go: %s\n", sym.Mangle, sym.CPP, sym.Go)
@@ -91,7 +91,7 @@ func TestParseHeaderFile() {
 		}
 
 		cflags := []string{"-I" + projPath}
-		headerSymbolMap, err := parse.ParseHeaderFile(files, cfg.TrimPrefixes, cflags, cfg.Cplusplus, false)
+		headerSymbolMap, err := parse.ParseHeaderFile(files, cfg.TrimPrefixes, cfg.StaticName, cflags, cfg.Cplusplus, false)
 		if err != nil {
 			fmt.Println("Error:", err)
 		}
@@ -105,7 +105,7 @@ func TestParseHeaderFile() {
 		for _, symb := range tc.dylibSymbols {
 			dylibsymbs = append(dylibsymbs, &nm.Symbol{Name: symbol.AddSymbolPrefixUnder(symb, cfg.Cplusplus)})
 		}
-		symbolData, err := symbol.GenerateAndUpdateSymbolTable(dylibsymbs, headerSymbolMap, filepath.Join(projPath, "llcppg.symb.json"))
+		symbolData, err := symbol.GenerateAndUpdateSymbolTable(dylibsymbs, headerSymbolMap, cfg.Filters, filepath.Join(projPath, "llcppg.symb.json"))
 		if err != nil {
 			fmt.Println("Error:", err)
 		}
@@ -0,0 +1,35 @@
+#include <clang-c/Index.h>
+
+extern "C" {
+
+unsigned wrap_clang_isCursorDefinition(CXCursor *cursor) { return clang_isCursorDefinition(*cursor); }
+
+int wrap_clang_Type_getNullability(CXType *typ) { return clang_Type_getNullability(*typ); }
+
+void wrap_clang_Type_getModifiedType(CXType *typ, CXType *modifiedTyp) {
+    *modifiedTyp = clang_Type_getModifiedType(*typ);
+}
+
+void wrap_clang_Type_getValueType(CXType *typ, CXType *valueTyp) { *valueTyp = clang_Type_getValueType(*typ); }
+
+int wrap_clang_Type_getNumTemplateArguments(CXType *typ) { return clang_Type_getNumTemplateArguments(*typ); }
+
+void wrap_clang_Type_getTemplateArgumentAsType(CXType *typ, unsigned i, CXType *argTyp) {
+    *argTyp = clang_Type_getTemplateArgumentAsType(*typ, i);
+}
+
+long long wrap_clang_Type_getAlignOf(CXType *typ) { return clang_Type_getAlignOf(*typ); }
+
+long long wrap_clang_Cursor_getOffsetOfField(CXCursor *cursor) { return clang_Cursor_getOffsetOfField(*cursor); }
+
+unsigned wrap_clang_Cursor_isBitField(CXCursor *cursor) { return clang_Cursor_isBitField(*cursor); }
+
+unsigned wrap_clang_isVirtualBase(CXCursor *cursor) { return clang_isVirtualBase(*cursor); }
+
+long long wrap_clang_getOffsetOfBase(CXCursor *parent, CXCursor *base) { return clang_getOffsetOfBase(*parent, *base); }
+
+unsigned wrap_clang_Cursor_isInlineNamespace(CXCursor *cursor) { return clang_Cursor_isInlineNamespace(*cursor); }
+
+CXEvalResult wrap_clang_Cursor_Evaluate(CXCursor *cursor) { return clang_Cursor_Evaluate(*cursor); }
+
+} // extern "C"
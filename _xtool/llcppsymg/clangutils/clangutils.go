@@ -8,12 +8,20 @@ import (
 	"github.com/goplus/llgo/c/clang"
 )
 
+const (
+	LLGoFiles = "$(llvm-config --cflags): _wrap/wrap.cpp"
+)
+
 type Config struct {
 	File  string
 	Temp  bool
 	Args  []string
 	IsCpp bool
 	Index *clang.Index
+
+	// AttributedTypes keeps the type attributes like _Nonnull as types of
+	// kind clang.TypeAttributed, instead of their equivalent types.
+	AttributedTypes bool
 }
 
 type Visitor func(cursor, parent clang.Cursor) clang.ChildVisitResult
@@ -43,6 +51,11 @@ func CreateTranslationUnit(config *Config) (*clang.Index, *clang.TranslationUnit
 		index = clang.CreateIndex(0, 0)
 	}
 
+	options := c.Uint(clang.DetailedPreprocessingRecord)
+	if config.AttributedTypes {
+		options |= IncludeAttributedTypes
+	}
+
 	var unit *clang.TranslationUnit
 
 	if config.Temp {
@@ -57,7 +70,7 @@ func CreateTranslationUnit(config *Config) (*clang.Index, *clang.TranslationUnit
 			tempFile.Filename,
 			unsafe.SliceData(cArgs), c.Int(len(cArgs)),
 			tempFile, 1,
-			clang.DetailedPreprocessingRecord,
+			options,
 		)
 
 	} else {
@@ -66,7 +79,7 @@ func CreateTranslationUnit(config *Config) (*clang.Index, *clang.TranslationUnit
 			cFile,
 			unsafe.SliceData(cArgs), c.Int(len(cArgs)),
 			nil, 0,
-			clang.DetailedPreprocessingRecord,
+			options,
 		)
 	}
 
@@ -77,6 +90,201 @@ func CreateTranslationUnit(config *Config) (*clang.Index, *clang.TranslationUnit
 	return index, unit, nil
 }
 
+// IncludeAttributedTypes is the CXTranslationUnit_IncludeAttributedTypes option
+// of clang_parseTranslationUnit.
+const IncludeAttributedTypes = 0x1000
+
+// Nullability kinds of a type, the values of CXTypeNullabilityKind.
+const (
+	TypeNullabilityNonNull = iota
+	TypeNullabilityNullable
+	TypeNullabilityUnspecified
+	TypeNullabilityInvalid // the type has no nullability
+	TypeNullabilityNullableResult
+)
+
+//go:linkname typeNullability C.wrap_clang_Type_getNullability
+func typeNullability(typ *clang.Type) c.Int
+
+// TypeNullability returns the nullability kind of a pointer type.
+func TypeNullability(typ clang.Type) c.Int {
+	return typeNullability(&typ)
+}
+
+//go:linkname typeModifiedType C.wrap_clang_Type_getModifiedType
+func typeModifiedType(typ *clang.Type, ret *clang.Type)
+
+// TypeModifiedType returns the type modified by the attribute of an attributed type.
+func TypeModifiedType(typ clang.Type) (ret clang.Type) {
+	typeModifiedType(&typ, &ret)
+	return
+}
+
+//go:linkname typeValueType C.wrap_clang_Type_getValueType
+func typeValueType(typ *clang.Type, ret *clang.Type)
+
+// TypeValueType returns the value type of an _Atomic type.
+func TypeValueType(typ clang.Type) (ret clang.Type) {
+	typeValueType(&typ, &ret)
+	return
+}
+
+//go:linkname typeNumTemplateArguments C.wrap_clang_Type_getNumTemplateArguments
+func typeNumTemplateArguments(typ *clang.Type) c.Int
+
+// TypeNumTemplateArguments returns the number of template arguments of a
+// template specialization, like 1 of std::vector<int>, or -1 if the type
+// isn't a template specialization.
+func TypeNumTemplateArguments(typ clang.Type) int {
+	return int(typeNumTemplateArguments(&typ))
+}
+
+//go:linkname typeTemplateArgument C.wrap_clang_Type_getTemplateArgumentAsType
+func typeTemplateArgument(typ *clang.Type, i c.Uint, ret *clang.Type)
+
+// TypeTemplateArgument returns the i-th template argument of a template
+// specialization, or an invalid type if the argument isn't a type.
+func TypeTemplateArgument(typ clang.Type, i int) (ret clang.Type) {
+	typeTemplateArgument(&typ, c.Uint(i), &ret)
+	return
+}
+
+//go:linkname typeAlignOf C.wrap_clang_Type_getAlignOf
+func typeAlignOf(typ *clang.Type) c.LongLong
+
+// TypeAlignOf returns the alignment of a type in bytes, or a negative
+// CXTypeLayoutError if the type is incomplete, dependent or invalid.
+func TypeAlignOf(typ clang.Type) int64 {
+	return int64(typeAlignOf(&typ))
+}
+
+//go:linkname offsetOfField C.wrap_clang_Cursor_getOffsetOfField
+func offsetOfField(cursor *clang.Cursor) c.LongLong
+
+// OffsetOfField returns the offset of a field in its record in bits, or a
+// negative CXTypeLayoutError if the layout of the record can't be computed.
+func OffsetOfField(cursor clang.Cursor) int64 {
+	return int64(offsetOfField(&cursor))
+}
+
+//go:linkname isBitField C.wrap_clang_Cursor_isBitField
+func isBitField(cursor *clang.Cursor) c.Uint
+
+// IsBitField reports whether the cursor is a bit field, like int flag : 1.
+func IsBitField(cursor clang.Cursor) bool {
+	return isBitField(&cursor) != 0
+}
+
+//go:linkname isVirtualBase C.wrap_clang_isVirtualBase
+func isVirtualBase(cursor *clang.Cursor) c.Uint
+
+// IsVirtualBase reports whether the base specifier cursor is a virtual base, like
+// virtual B in class D : virtual B {}.
+func IsVirtualBase(cursor clang.Cursor) bool {
+	return isVirtualBase(&cursor) != 0
+}
+
+//go:linkname isInlineNamespace C.wrap_clang_Cursor_isInlineNamespace
+func isInlineNamespace(cursor *clang.Cursor) c.Uint
+
+// IsInlineNamespace reports whether the namespace cursor is an inline namespace,
+// like __1 of inline namespace __1 {} in std of libc++.
+func IsInlineNamespace(cursor clang.Cursor) bool {
+	return isInlineNamespace(&cursor) != 0
+}
+
+//go:linkname offsetOfBase C.wrap_clang_getOffsetOfBase
+func offsetOfBase(parent, base *clang.Cursor) c.LongLong
+
+// OffsetOfBase returns the offset of the base specified by the base specifier
+// cursor in the record parent in bits, or a negative value if it can't be
+// computed.
+func OffsetOfBase(parent, base clang.Cursor) int64 {
+	return int64(offsetOfBase(&parent, &base))
+}
+
+// Kinds of the result of Evaluate, the values of CXEvalResultKind.
+const (
+	EvalUnExposed = iota
+	EvalInt
+	EvalFloat
+	EvalObjCStrLiteral
+	EvalStrLiteral
+	EvalCFStr
+	EvalOther
+)
+
+// An EvalResult is the value of an evaluated expression.
+type EvalResult struct {
+	Kind     c.Int
+	Int      int64   // the value of a signed EvalInt
+	Uint     uint64  // the value of an unsigned EvalInt
+	Unsigned bool    // EvalInt is unsigned
+	Float    float64 // the value of an EvalFloat
+	Str      string  // the value of an EvalStrLiteral
+}
+
+//go:linkname cursorEvaluate C.wrap_clang_Cursor_Evaluate
+func cursorEvaluate(cursor *clang.Cursor) unsafe.Pointer
+
+//go:linkname evalResultKind C.clang_EvalResult_getKind
+func evalResultKind(res unsafe.Pointer) c.Int
+
+//go:linkname evalResultIsUnsignedInt C.clang_EvalResult_isUnsignedInt
+func evalResultIsUnsignedInt(res unsafe.Pointer) c.Uint
+
+//go:linkname evalResultAsLongLong C.clang_EvalResult_getAsLongLong
+func evalResultAsLongLong(res unsafe.Pointer) c.LongLong
+
+//go:linkname evalResultAsUnsigned C.clang_EvalResult_getAsUnsigned
+func evalResultAsUnsigned(res unsafe.Pointer) c.UlongLong
+
+//go:linkname evalResultAsDouble C.clang_EvalResult_getAsDouble
+func evalResultAsDouble(res unsafe.Pointer) c.Double
+
+//go:linkname evalResultAsStr C.clang_EvalResult_getAsStr
+func evalResultAsStr(res unsafe.Pointer) *c.Char
+
+//go:linkname evalResultDispose C.clang_EvalResult_dispose
+func evalResultDispose(res unsafe.Pointer)
+
+// Evaluate evaluates the expression of a cursor, or the initializer of a variable
+// declaration, like 64 of static const int max = 64;. It reports false if the
+// expression can't be evaluated.
+func Evaluate(cursor clang.Cursor) (ret EvalResult, ok bool) {
+	res := cursorEvaluate(&cursor)
+	if res == nil {
+		return
+	}
+	defer evalResultDispose(res)
+	ret.Kind = evalResultKind(res)
+	switch ret.Kind {
+	case EvalInt:
+		ret.Unsigned = evalResultIsUnsignedInt(res) != 0
+		if ret.Unsigned {
+			ret.Uint = uint64(evalResultAsUnsigned(res))
+		} else {
+			ret.Int = int64(evalResultAsLongLong(res))
+		}
+	case EvalFloat:
+		ret.Float = float64(evalResultAsDouble(res))
+	case EvalStrLiteral:
+		ret.Str = c.GoString(evalResultAsStr(res))
+	default:
+		return
+	}
+	return ret, true
+}
+
+//go:linkname isCursorDefinition C.wrap_clang_isCursorDefinition
+func isCursorDefinition(cursor *clang.Cursor) c.Uint
+
+// IsDefinition reports whether the cursor is the definition of the entity,
+// like struct a { int x; }, rather than a declaration, like struct a.
+func IsDefinition(cursor clang.Cursor) bool {
+	return isCursorDefinition(&cursor) != 0
+}
+
 func GetLocation(loc clang.SourceLocation) (file clang.File, line c.Uint, column c.Uint, offset c.Uint) {
 	loc.SpellingLocation(&file, &line, &column, &offset)
 	return
@@ -86,6 +294,10 @@ func GetLocation(loc clang.SourceLocation) (file clang.File, line c.Uint, column
 func BuildScopingParts(cursor clang.Cursor) []string {
 	var parts []string
 	for cursor.IsNull() != 1 && cursor.Kind != clang.CursorTranslationUnit {
+		if isTransparentScope(cursor) {
+			cursor = cursor.SemanticParent()
+			continue
+		}
 		name := cursor.String()
 		qualified := c.GoString(name.CStr())
 		parts = append([]string{qualified}, parts...)
@@ -95,6 +307,14 @@ func BuildScopingParts(cursor clang.Cursor) []string {
 	return parts
 }
 
+// isTransparentScope reports whether the scope cursor is left out of qualified
+// names, like an extern "C" block or an inline namespace, whose members are
+// members of the enclosing namespace.
+func isTransparentScope(cursor clang.Cursor) bool {
+	return cursor.Kind == clang.CursorLinkageSpec ||
+		cursor.Kind == clang.CursorNamespace && IsInlineNamespace(cursor)
+}
+
 func VisitChildren(cursor clang.Cursor, fn Visitor) c.Uint {
 	return clang.VisitChildren(cursor, func(cursor, parent clang.Cursor, clientData unsafe.Pointer) clang.ChildVisitResult {
 		cfn := *(*Visitor)(clientData)
@@ -0,0 +1,87 @@
+package cfgparse
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// headerExts are the extensions of the header files matched by glob patterns.
+var headerExts = map[string]bool{
+	".h":   true,
+	".hh":  true,
+	".hpp": true,
+	".hxx": true,
+}
+
+// IsGlobPattern reports whether the include file is a glob pattern.
+func IsGlobPattern(file string) bool {
+	return strings.ContainsAny(file, "*?[")
+}
+
+// MatchGlob reports whether the slash-separated path name matches pattern, where
+// ** matches any number of directories and the other elements are matched by
+// path.Match.
+func MatchGlob(pattern, name string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchElems(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchElems(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// globHeaderFiles returns the header files under root whose paths relative to
+// root match pattern, in lexical order.
+func globHeaderFiles(root, pattern string) []string {
+	var matches []string
+	filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !headerExts[filepath.Ext(file)] {
+			return nil
+		}
+		rel, err := filepath.Rel(root, file)
+		if err == nil && MatchGlob(pattern, filepath.ToSlash(rel)) {
+			matches = append(matches, file)
+		}
+		return nil
+	})
+	return matches
+}
+
+func dedupPaths(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	ret := paths[:0]
+	for _, path := range paths {
+		if !seen[path] {
+			seen[path] = true
+			ret = append(ret, path)
+		}
+	}
+	return ret
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+
+	"github.com/goplus/llcppg/types"
 )
 
 // Note: This package is not placed under the 'config' package because 'config'
@@ -82,6 +84,10 @@ func ParseCFlags(cflags string) *CFlags {
 	return cf
 }
 
+// GenHeaderFilePaths searches for each header file in the include paths and
+// default paths. A file can be a glob pattern like foo/*.h or **/*.h, where **
+// matches any number of directories, which is expanded in the first include
+// path having a matched header file, but not in the default paths.
 func (cf *CFlags) GenHeaderFilePaths(files []string, defaultPaths []string) ([]string, []string, error) {
 	var foundPaths []string
 	var notFound []string
@@ -90,6 +96,21 @@ func (cf *CFlags) GenHeaderFilePaths(files []string, defaultPaths []string) ([]s
 
 	for _, file := range files {
 		var found bool
+		if IsGlobPattern(file) {
+			// a glob is only expanded in the -I paths, as walking the system
+			// include paths would find headers which aren't the package's
+			for _, path := range cf.Paths {
+				if matches := globHeaderFiles(path, file); len(matches) > 0 {
+					foundPaths = append(foundPaths, matches...)
+					found = true
+					break
+				}
+			}
+			if !found {
+				notFound = append(notFound, file)
+			}
+			continue
+		}
 		for _, path := range searchPaths {
 			fullPath := filepath.Join(path, file)
 			if _, err := os.Stat(fullPath); err == nil {
@@ -107,5 +128,40 @@ func (cf *CFlags) GenHeaderFilePaths(files []string, defaultPaths []string) ([]s
 		return nil, notFound, fmt.Errorf("failed to find any header files")
 	}
 
-	return foundPaths, notFound, nil
+	return dedupPaths(foundPaths), notFound, nil
+}
+
+// GenPkgHeaderFilePaths returns the header files of the package of conf, which
+// are the ones of its include list, see GenHeaderFilePaths, and if IncludeAll
+// is set, all headers under the include paths but the ones matched by
+// IncludeExclude.
+func (cf *CFlags) GenPkgHeaderFilePaths(conf *types.Config, defaultPaths []string) ([]string, []string, error) {
+	foundPaths, notFound, err := cf.GenHeaderFilePaths(conf.Include, defaultPaths)
+	if !conf.IncludeAll {
+		return foundPaths, notFound, err
+	}
+	for _, path := range cf.Paths {
+		for _, file := range globHeaderFiles(path, "**/*") {
+			rel, _ := filepath.Rel(path, file)
+			if !matchAnyGlob(conf.IncludeExclude, filepath.ToSlash(rel)) {
+				foundPaths = append(foundPaths, file)
+			}
+		}
+	}
+	if len(foundPaths) == 0 {
+		return nil, notFound, fmt.Errorf("failed to find any header files")
+	}
+	return dedupPaths(foundPaths), notFound, nil
+}
+
+// FilterHeaderFilePaths returns the header file paths selected by the header
+// filter of filters.
+func FilterHeaderFilePaths(paths []string, filters *types.Filters) []string {
+	var selected []string
+	for _, path := range paths {
+		if filters.Header(path) {
+			selected = append(selected, path)
+		}
+	}
+	return selected
 }
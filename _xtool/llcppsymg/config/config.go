@@ -2,6 +2,7 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"unsafe"
 
 	"github.com/goplus/llcppg/types"
@@ -27,7 +28,19 @@ func GetConf(data []byte) (Conf, error) {
 		Include:      GetStringArrayItem(parsedConf, "include"),
 		TrimPrefixes: GetStringArrayItem(parsedConf, "trimPrefixes"),
 		Cplusplus:    GetBoolItem(parsedConf, "cplusplus"),
+		StaticName:   GetStringItem(parsedConf, "staticName", ""),
+
+		IncludeAll:     GetBoolItem(parsedConf, "includeAll"),
+		IncludeExclude: GetStringArrayItem(parsedConf, "includeExclude"),
+		Platforms:      GetPlatforms(parsedConf, "platforms"),
+	}
+	filters, err := GetFilters(parsedConf, "filters")
+	if err != nil {
+		parsedConf.Delete()
+		return Conf{}, err
 	}
+	config.Filters = filters
+	config.ResolvePlatform()
 
 	return Conf{
 		JSON:   parsedConf,
@@ -60,6 +73,69 @@ func GetStringArrayItem(obj *cjson.JSON, key string) (value []string) {
 	return
 }
 
+// GetFilters returns the filters of the object key of obj, or nil if there is
+// none, with their patterns compiled.
+func GetFilters(obj *cjson.JSON, key string) (*types.Filters, error) {
+	item := obj.GetObjectItemCaseSensitive(c.AllocaCStr(key))
+	if item == nil {
+		return nil, nil
+	}
+	filters := new(types.Filters)
+	for _, f := range []struct {
+		key    string
+		filter **types.Filter
+	}{
+		{"symbols", &filters.Symbols},
+		{"types", &filters.Types},
+		{"macros", &filters.Macros},
+		{"headers", &filters.Headers},
+	} {
+		filter, err := GetFilter(item, f.key)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s.%w", key, f.key, err)
+		}
+		*f.filter = filter
+	}
+	return filters, nil
+}
+
+// GetPlatforms returns the platform configs of the array key of obj.
+func GetPlatforms(obj *cjson.JSON, key string) []*types.PlatformConfig {
+	item := obj.GetObjectItemCaseSensitive(c.AllocaCStr(key))
+	if item == nil {
+		return nil
+	}
+	platforms := make([]*types.PlatformConfig, item.GetArraySize())
+	for i := range platforms {
+		platform := item.GetArrayItem(c.Int(i))
+		platforms[i] = &types.PlatformConfig{
+			When:         GetStringItem(platform, "when", ""),
+			CFlags:       GetStringItem(platform, "cflags", ""),
+			Libs:         GetStringItem(platform, "libs", ""),
+			Include:      GetStringArrayItem(platform, "include"),
+			TrimPrefixes: GetStringArrayItem(platform, "trimPrefixes"),
+		}
+	}
+	return platforms
+}
+
+// GetFilter returns the filter of the object key of obj, or nil if there is
+// none, with its patterns compiled.
+func GetFilter(obj *cjson.JSON, key string) (*types.Filter, error) {
+	item := obj.GetObjectItemCaseSensitive(c.AllocaCStr(key))
+	if item == nil {
+		return nil, nil
+	}
+	filter := &types.Filter{
+		Include: GetStringArrayItem(item, "include"),
+		Exclude: GetStringArrayItem(item, "exclude"),
+	}
+	if err := filter.Compile(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
 func GetBoolItem(obj *cjson.JSON, key string) bool {
 	item := obj.GetObjectItemCaseSensitive(c.AllocaCStr(key))
 	if item == nil {
@@ -84,7 +84,7 @@ func main() {
 	if ags.Verbose {
 		fmt.Println("syspaths", syspaths)
 	}
-	filepaths, notFounds, err := cflag.GenHeaderFilePaths(conf.Include, syspaths)
+	filepaths, notFounds, err := cflag.GenPkgHeaderFilePaths(conf.Config, syspaths)
 	check(err)
 
 	if ags.Verbose {
@@ -94,10 +94,14 @@ func main() {
 		}
 	}
 
-	headerInfos, err := parse.ParseHeaderFile(filepaths, conf.TrimPrefixes, strings.Fields(conf.CFlags), conf.Cplusplus, false)
+	// the declarations of an excluded header are not collected, even if it's
+	// included by other headers
+	filepaths = cfgparse.FilterHeaderFilePaths(filepaths, conf.Filters)
+
+	headerInfos, err := parse.ParseHeaderFile(filepaths, conf.TrimPrefixes, conf.StaticName, strings.Fields(conf.CFlags), conf.Cplusplus, false)
 	check(err)
 
-	symbolData, err := symbol.GenerateAndUpdateSymbolTable(symbols, headerInfos, symbFile)
+	symbolData, err := symbol.GenerateAndUpdateSymbolTable(symbols, headerInfos, conf.Filters, symbFile)
 	check(err)
 
 	err = os.WriteFile(symbFile, symbolData, 0644)
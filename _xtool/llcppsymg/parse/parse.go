@@ -10,6 +10,7 @@ import (
 	"github.com/goplus/llcppg/_xtool/llcppsymg/clangutils"
 	"github.com/goplus/llcppg/_xtool/llcppsymg/dbg"
 	"github.com/goplus/llcppg/_xtool/llcppsymg/names"
+	"github.com/goplus/llcppg/types"
 	"github.com/goplus/llgo/c/clang"
 )
 
@@ -21,6 +22,7 @@ type SymbolInfo struct {
 type SymbolProcessor struct {
 	Files      []string
 	Prefixes   []string
+	StaticName string // pattern of the Go names of static members, see types.Config
 	SymbolMap  map[string]*SymbolInfo
 	NameCounts map[string]int
 	// for independent files,signal that the file has been processed
@@ -185,8 +187,12 @@ func (p *SymbolProcessor) genGoName(cursor clang.Cursor) string {
 		convertedName = names.GoName(originName, p.Prefixes, p.inCurPkg(cursor, false))
 	}
 
-	if parent := cursor.SemanticParent(); parent.Kind == clang.CursorClassDecl {
+	if parent := cursor.SemanticParent(); isRecordDecl(parent) {
 		class := names.GoName(clang.GoString(parent.String()), p.Prefixes, p.inCurPkg(cursor, false))
+		if isStaticMember(cursor) {
+			// a static member has no this, so it's a package-level function or variable
+			return p.AddSuffix(types.StaticMemberName(p.StaticName, class, convertedName))
+		}
 		return p.AddSuffix(p.GenMethodName(class, convertedName, isDestructor, true))
 	} else if cursor.Kind == clang.CursorFunctionDecl {
 		numArgs := cursor.NumArguments()
@@ -199,6 +205,20 @@ func (p *SymbolProcessor) genGoName(cursor clang.Cursor) string {
 	return p.AddSuffix(convertedName)
 }
 
+// isRecordDecl reports whether the cursor is a class, struct or union, whose
+// members are all C++ members.
+func isRecordDecl(cursor clang.Cursor) bool {
+	switch cursor.Kind {
+	case clang.CursorClassDecl, clang.CursorStructDecl, clang.CursorUnionDecl:
+		return true
+	}
+	return false
+}
+
+func isStaticMember(cursor clang.Cursor) bool {
+	return cursor.Kind == clang.CursorVarDecl || cursor.Kind == clang.CursorCXXMethod && cursor.IsStatic() != 0
+}
+
 func (p *SymbolProcessor) genProtoName(cursor clang.Cursor) string {
 	scopingParts := clangutils.BuildScopingParts(cursor.SemanticParent())
 
@@ -261,13 +281,19 @@ func (p *SymbolProcessor) visitTop(cursor, parent clang.Cursor) clang.ChildVisit
 		fmt.Printf("visitTop: %s\n", filename)
 	}
 	switch cursor.Kind {
-	case clang.CursorNamespace, clang.CursorClassDecl:
+	case clang.CursorNamespace, clang.CursorClassDecl, clang.CursorStructDecl, clang.CursorUnionDecl, clang.CursorLinkageSpec:
 		clangutils.VisitChildren(cursor, p.visitTop)
 	case clang.CursorCXXMethod, clang.CursorFunctionDecl, clang.CursorConstructor, clang.CursorDestructor:
 		isPublicMethod := (cursor.CXXAccessSpecifier() == clang.CXXPublic) && cursor.Kind == clang.CursorCXXMethod || cursor.Kind == clang.CursorConstructor || cursor.Kind == clang.CursorDestructor
 		if p.isSelfFile(filename) && (cursor.Kind == clang.CursorFunctionDecl || isPublicMethod) {
 			p.collectFuncInfo(cursor)
 		}
+	case clang.CursorVarDecl:
+		// a static data member, the variables of namespaces are not linked
+		isPublicMember := isRecordDecl(cursor.SemanticParent()) && cursor.CXXAccessSpecifier() == clang.CXXPublic
+		if p.isSelfFile(filename) && isPublicMember {
+			p.collectFuncInfo(cursor)
+		}
 	}
 	return clang.ChildVisit_Continue
 }
@@ -303,12 +329,13 @@ func (p *SymbolProcessor) collect(cfg *clangutils.Config) error {
 	return nil
 }
 
-func ParseHeaderFile(files []string, prefixes []string, cflags []string, isCpp bool, isTemp bool) (map[string]*SymbolInfo, error) {
+func ParseHeaderFile(files []string, prefixes []string, staticName string, cflags []string, isCpp bool, isTemp bool) (map[string]*SymbolInfo, error) {
 	index := clang.CreateIndex(0, 0)
 	if isTemp {
 		files = append(files, clangutils.TEMP_FILE)
 	}
 	processer := NewSymbolProcessor(files, prefixes)
+	processer.StaticName = staticName
 	for _, file := range files {
 		processer.collect(&clangutils.Config{
 			File:  file,
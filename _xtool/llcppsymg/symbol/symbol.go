@@ -95,8 +95,8 @@ func ParseDylibSymbols(lib string) ([]*nm.Symbol, error) {
 }
 
 // finds the intersection of symbols from the dynamic library's symbol table and the symbols parsed from header files.
-// It returns a list of symbols that can be externally linked.
-func GetCommonSymbols(dylibSymbols []*nm.Symbol, headerSymbols map[string]*parse.SymbolInfo) []*types.SymbolInfo {
+// It returns a list of symbols that can be externally linked, except those excluded by the symbol filter of filters.
+func GetCommonSymbols(dylibSymbols []*nm.Symbol, headerSymbols map[string]*parse.SymbolInfo, filters *types.Filters) []*types.SymbolInfo {
 	var commonSymbols []*types.SymbolInfo
 	for _, dylibSym := range dylibSymbols {
 		symName := dylibSym.Name
@@ -104,6 +104,12 @@ func GetCommonSymbols(dylibSymbols []*nm.Symbol, headerSymbols map[string]*parse
 			symName = strings.TrimPrefix(symName, "_")
 		}
 		if symInfo, ok := headerSymbols[symName]; ok {
+			if name := protoSymbolName(symInfo.ProtoName); !filters.Symbol(name) {
+				if dbg.GetDebugSymbol() {
+					fmt.Println("GetCommonSymbols: symbol", name, "is filtered out")
+				}
+				continue
+			}
 			symbolInfo := &types.SymbolInfo{
 				Mangle: symName,
 				CPP:    symInfo.ProtoName,
@@ -115,6 +121,15 @@ func GetCommonSymbols(dylibSymbols []*nm.Symbol, headerSymbols map[string]*parse
 	return commonSymbols
 }
 
+// protoSymbolName returns the name of a symbol of its prototype, like ns::Foo::bar
+// of ns::Foo::bar(int), which is matched by the symbol filter.
+func protoSymbolName(protoName string) string {
+	if i := strings.IndexByte(protoName, '('); i >= 0 {
+		return protoName[:i]
+	}
+	return protoName
+}
+
 func ReadExistingSymbolTable(fileName string) (map[string]types.SymbolInfo, bool) {
 	if _, err := os.Stat(fileName); err != nil {
 		return nil, false
@@ -192,8 +207,8 @@ func GenSymbolTableData(commonSymbols []*types.SymbolInfo, existingSymbols map[s
 	return result, nil
 }
 
-func GenerateAndUpdateSymbolTable(symbols []*nm.Symbol, headerInfos map[string]*parse.SymbolInfo, symbFile string) ([]byte, error) {
-	commonSymbols := GetCommonSymbols(symbols, headerInfos)
+func GenerateAndUpdateSymbolTable(symbols []*nm.Symbol, headerInfos map[string]*parse.SymbolInfo, filters *types.Filters, symbFile string) ([]byte, error) {
+	commonSymbols := GetCommonSymbols(symbols, headerInfos, filters)
 	if dbg.GetDebugSymbol() {
 		fmt.Println("GenerateAndUpdateSymbolTable:", len(commonSymbols), "common symbols")
 	}
@@ -16,7 +16,11 @@
 
 package ast
 
-import "github.com/goplus/llcppg/token"
+import (
+	"strconv"
+
+	"github.com/goplus/llcppg/token"
+)
 
 // =============================================================================
 
@@ -112,6 +116,39 @@ func (*BuiltinType) exprNode() {}
 
 // ------------------------------------------------
 
+type Qualifier uint
+
+const (
+	Const Qualifier = 1 << iota
+	Volatile
+	Restrict
+	Atomic
+	Nonnull         // _Nonnull
+	Nullable        // _Nullable or _Nullable_result
+	NullUnspecified // _Null_unspecified
+)
+
+// const/volatile/restrict/_Atomic/_Nonnull/_Nullable X
+//
+// The qualifiers of a pointer type, like restrict and _Nonnull, qualify the
+// PointerType itself, eg. char *const p is QualifiedType{X: PointerType}.
+type QualifiedType struct {
+	X          Expr
+	Qualifiers Qualifier
+}
+
+func (*QualifiedType) exprNode() {}
+
+// Unqualified returns the type x without its qualifiers.
+func Unqualified(x Expr) Expr {
+	if q, ok := x.(*QualifiedType); ok {
+		return q.X
+	}
+	return x
+}
+
+// ------------------------------------------------
+
 // Name
 type Ident struct {
 	Name string
@@ -195,8 +232,9 @@ func (*RvalueRefType) exprNode() {}
 // Elt[Len]
 // Elt[]
 type ArrayType struct {
-	Elt Expr
-	Len Expr // optional
+	Elt     Expr
+	Len     Expr // optional
+	LenExpr Expr // optional, the spelled length if it's a name, like Ident FOO_MAX of [FOO_MAX]
 }
 
 func (*ArrayType) exprNode() {}
@@ -248,8 +286,32 @@ func (*FuncType) exprNode() {}
 
 type RecordType struct {
 	Tag     Tag
-	Fields  *FieldList
+	Fields  *FieldList // nil for a forward declaration like struct A;
 	Methods []*FuncDecl
+	Layout  *RecordLayout // nil for the natural layout of the fields
+	Bases   []*BaseSpec   // base classes of a C++ record, in declaration order
+}
+
+// A BaseSpec is a base class of a C++ record, like public B in
+// class D : public B {};
+type BaseSpec struct {
+	Type    Expr // the base class
+	Access  AccessSpecifier
+	Virtual bool
+	Offset  int64 // offset of the base in bytes, -1 for a virtual base or an unknown layout
+}
+
+func (*BaseSpec) exprNode() {}
+
+// A RecordLayout is the memory layout of a record which differs from the natural
+// layout of its fields, like a record changed by #pragma pack, a packed or aligned
+// attribute, or alignas.
+type RecordLayout struct {
+	Packed  bool    // the record or one of its fields has a packed attribute
+	Aligned bool    // the record or one of its fields has an aligned attribute or alignas
+	Size    int64   // size in bytes
+	Align   int64   // alignment in bytes
+	Offsets []int64 // offsets of the fields in bytes, -1 for a static field
 }
 
 func (*RecordType) exprNode() {}
@@ -268,13 +330,64 @@ func (*InstantiationType) exprNode() {}
 // Declarations
 
 type Location struct {
-	File string
+	File   string
+	Line   uint // 1-based line number; or 0 if unknown
+	Column uint // 1-based column number in bytes; or 0 if unknown
+	Offset uint // 0-based byte offset in File
+}
+
+// String returns the location in the form "file:line:column",
+// omitting the parts that are unknown.
+func (l *Location) String() string {
+	if l == nil {
+		return "-"
+	}
+	s := l.File
+	if l.Line > 0 {
+		s += ":" + strconv.FormatUint(uint64(l.Line), 10)
+		if l.Column > 0 {
+			s += ":" + strconv.FormatUint(uint64(l.Column), 10)
+		}
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Names of the declaration attributes recorded in DeclBase.Attrs.
+const (
+	AttrDeprecated       = "deprecated"         // deprecated or deprecated("message")
+	AttrUnavailable      = "unavailable"        // unavailable or unavailable("message")
+	AttrNoReturn         = "noreturn"           // noreturn, _Noreturn or [[noreturn]]
+	AttrFormat           = "format"             // format(printf, fmtIndex, firstArg)
+	AttrNonNull          = "nonnull"            // nonnull or nonnull(index, ...)
+	AttrMalloc           = "malloc"             // malloc
+	AttrWarnUnusedResult = "warn_unused_result" // warn_unused_result or [[nodiscard]]
+	AttrVisibility       = "visibility"         // visibility("default"|"hidden"|...)
+)
+
+// An Attr is a GNU __attribute__ or a [[...]] attribute of a declaration.
+type Attr struct {
+	Name string   // attribute name without the surrounding __, eg. deprecated
+	Args []string // arguments, string literals are unquoted
 }
 
 type DeclBase struct {
 	Doc    *CommentGroup // associated documentation; or nil
 	Loc    *Location
-	Parent Expr // namespace or class
+	Attrs  []*Attr // attributes; or nil
+	Parent Expr    // namespace or class
+}
+
+// Attr returns the first attribute named name, or nil if there is none.
+func (d *DeclBase) Attr(name string) *Attr {
+	for _, attr := range d.Attrs {
+		if attr.Name == name {
+			return attr
+		}
+	}
+	return nil
 }
 
 // ------------------------------------------------
@@ -291,8 +404,10 @@ func (*TypedefDecl) declNode() {}
 // ------------------------------------------------
 
 type EnumItem struct {
-	Name  *Ident
-	Value Expr // optional
+	Doc     *CommentGroup // associated documentation; or nil
+	Name    *Ident
+	Value   Expr          // optional
+	Comment *CommentGroup // line comments; or nil
 }
 
 func (*EnumItem) exprNode() {}
@@ -336,6 +451,18 @@ func (*FuncDecl) declNode() {}
 
 // ------------------------------------------------
 
+// const Type Name = Value; or constexpr Type Name = Value;
+type VarDecl struct {
+	DeclBase
+	Name  *Ident
+	Type  Expr
+	Value *BasicLit // the evaluated initializer
+}
+
+func (*VarDecl) declNode() {}
+
+// ------------------------------------------------
+
 // struct/union/class Name { Field1, Field2, ... };
 type TypeDecl struct {
 	DeclBase
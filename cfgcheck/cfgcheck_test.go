@@ -0,0 +1,226 @@
+package cfgcheck_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llcppg/cfgcheck"
+	"github.com/goplus/llcppg/types"
+)
+
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  string
+		want []string
+	}{
+		{
+			name: "valid",
+			cfg:  `{"$schema": "../schema/llcppg.cfg.schema.json", "name": "foo", "include": ["foo.h"], "deps": null, "outParams": {"foo_get": ["out"]}}`,
+		},
+		{
+			name: "unknown keys",
+			cfg:  `{"name": "foo", "trimPrefix": ["foo_"], "Include": ["foo.h"], "filters": {"symbol": {}}, "unrelated": 1}`,
+			want: []string{
+				`$.Include: unknown key, did you mean "include"?`,
+				`$.filters.symbol: unknown key, did you mean "symbols"?`,
+				`$.trimPrefix: unknown key, did you mean "trimPrefixes"?`,
+				`$.unrelated: unknown key`,
+			},
+		},
+		{
+			name: "bad types",
+			cfg:  `{"name": 1, "include": "foo.h", "cplusplus": "true", "trimPrefixes": ["foo_", 2], "outParams": {"foo-get": "out"}, "lifecycle": {"pairs": [{"new": null}]}}`,
+			want: []string{
+				`$.cplusplus: expected boolean, got string`,
+				`$.include: expected array, got string`,
+				`$.lifecycle.pairs[0].new: expected string, got null`,
+				`$.name: expected string, got number`,
+				`$.outParams["foo-get"]: expected array, got string`,
+				`$.trimPrefixes[1]: expected string, got number`,
+			},
+		},
+		{
+			name: "platform conditions",
+			cfg:  `{"name": "foo", "platforms": [{"when": "linux,!threads", "cflags": "-DFOO"}, {"when": ""}, {"when": "darwin,,arm64"}, {"when": "linux|darwin"}, {"cflag": ""}]}`,
+			want: []string{
+				`$.platforms[4].cflag: unknown key, did you mean "cflags"?`,
+			},
+		},
+		{
+			name: "platform condition terms",
+			cfg:  `{"name": "foo", "platforms": [{"when": "linux,!threads", "cflags": "-DFOO"}, {"when": ""}, {"when": "darwin,,arm64"}, {"when": "linux|darwin"}]}`,
+			want: []string{
+				`$.platforms[1].when: empty condition, expected GOOS, GOARCH or tags like linux,amd64`,
+				`$.platforms[2].when: invalid term "" in condition "darwin,,arm64"`,
+				`$.platforms[3].when: invalid term "linux|darwin" in condition "linux|darwin"`,
+			},
+		},
+		{
+			name: "filter patterns",
+			cfg:  `{"name": "foo", "filters": {"symbols": {"exclude": ["/foo(/", "foo_*"]}, "headers": {"include": ["foo/[a-.h"]}}}`,
+			want: []string{
+				`$.filters.symbols.exclude[0]: invalid pattern "/foo(/": error parsing regexp: missing closing ): ` + "`foo(`",
+				`$.filters.headers.include[0]: invalid pattern "foo/[a-.h": syntax error in pattern`,
+			},
+		},
+		{
+			name: "syntax error",
+			cfg:  "{\n  \"name\": \"foo\"\n  \"include\": []\n}",
+			want: []string{`$: invalid JSON at line 3, column 3: invalid character '"' after object key:value pair`},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := cfgcheck.Decode([]byte(tc.cfg))
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if conf.Name != "foo" || len(conf.OutParams["foo_get"]) != 1 {
+					t.Errorf("unexpected config %+v", conf)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if got := err.Error(); got != strings.Join(tc.want, "\n") {
+				t.Errorf("unexpected errors:\n%s\nwant:\n%s", got, strings.Join(tc.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	incDir := filepath.Join(dir, "include")
+	libDir := filepath.Join(dir, "lib")
+	ext := ".so"
+	if runtime.GOOS == "darwin" {
+		ext = ".dylib"
+	}
+	for _, file := range []string{filepath.Join(incDir, "foo.h"), filepath.Join(incDir, "foo", "bar.h"), filepath.Join(libDir, "libfoo"+ext)} {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := &cfgcheck.Options{CheckLibs: true}
+
+	err := cfgcheck.Check(&types.Config{
+		Name:    "foo",
+		CFlags:  "-I" + incDir,
+		Libs:    "-L" + libDir + " -lfoo",
+		Include: []string{"foo.h", "foo/*.h"},
+		Deps:    []string{"github.com/goplus/llgo/c/cjson"},
+	}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cfgcheck.Check(&types.Config{
+		Name:    "foo-bar",
+		CFlags:  "-I" + incDir,
+		Libs:    "-L" + libDir + " -lfoo -lmissing",
+		Include: []string{"foo.h", "missing.h", "foo/[.h"},
+		Deps:    []string{"github.com/goplus/llgo/c/cjson", "bad path"},
+		Filters: &types.Filters{
+			Symbols: &types.Filter{Exclude: []string{"/foo(/", "foo_*"}},
+		},
+	}, opts)
+	want := []string{
+		`$.name: "foo-bar" is not a valid Go package name`,
+		`$.include[2]: invalid glob pattern "foo/[.h": syntax error in pattern`,
+		`$.include[1]: header "missing.h" not found in -I paths ` + incDir + ` and the system paths`,
+		`$.libs: library -lmissing not found in -L paths ` + libDir + ` and the system paths`,
+		`$.deps[1]: can't import "bad path": malformed import path "bad path": invalid char ' '`,
+		`$.filters.symbols.exclude[0]: invalid pattern "/foo(/": error parsing regexp: missing closing ): ` + "`foo(`",
+	}
+	if err == nil || err.Error() != strings.Join(want, "\n") {
+		t.Errorf("unexpected errors:\n%v\nwant:\n%s", err, strings.Join(want, "\n"))
+	}
+
+	err = cfgcheck.Check(&types.Config{Name: "foo", Include: []string{"foo.h"}, Libs: "-L" + libDir + " -lbar"}, opts)
+	want = []string{
+		`$.include[0]: header "foo.h" not found in the system paths, -I paths may be missing`,
+		`$.libs: library -lbar not found in -L paths ` + libDir + ` and the system paths`,
+	}
+	if err == nil || err.Error() != strings.Join(want, "\n") {
+		t.Errorf("unexpected errors:\n%v\nwant:\n%s", err, strings.Join(want, "\n"))
+	}
+
+	// a glob pattern is only expanded in the -I paths, and includeAll can't make
+	// the headers of a system include path headers of the package
+	sysOpts := &cfgcheck.Options{IncPaths: []string{incDir}}
+	err = cfgcheck.Check(&types.Config{Name: "foo", Include: []string{"foo.h", "foo/*.h"}}, sysOpts)
+	want = []string{
+		`$.include[1]: no header matches "foo/*.h" in the -I paths, which are missing`,
+	}
+	if err == nil || err.Error() != strings.Join(want, "\n") {
+		t.Errorf("unexpected errors:\n%v\nwant:\n%s", err, strings.Join(want, "\n"))
+	}
+	err = cfgcheck.Check(&types.Config{Name: "foo", CFlags: "-I" + incDir + "/", IncludeAll: true}, sysOpts)
+	want = []string{
+		`$.includeAll: -I` + incDir + `/ is a system include path, all of its headers would be headers of the package`,
+	}
+	if err == nil || err.Error() != strings.Join(want, "\n") {
+		t.Errorf("unexpected errors:\n%v\nwant:\n%s", err, strings.Join(want, "\n"))
+	}
+
+	// the platform configs of the target platform are checked at their own paths
+	t.Setenv("GOOS", "linux")
+	t.Setenv("GOARCH", "amd64")
+	err = cfgcheck.Check(&types.Config{
+		Name:    "foo",
+		CFlags:  "-I" + incDir,
+		Libs:    "-L" + libDir + " -lfoo",
+		Include: []string{"foo.h"},
+		Platforms: []*types.PlatformConfig{
+			{When: "darwin", Include: []string{"foo_darwin.h"}, Libs: "-lfoo_darwin"},
+			{When: "linux", Include: []string{"foo/bar.h", "foo_linux.h", "foo/[.h"}, Libs: "-lfoo_linux -lfoo"},
+			{When: "amd64", Libs: "-lfoo_linux"},
+		},
+	}, opts)
+	want = []string{
+		`$.platforms[1].include[2]: invalid glob pattern "foo/[.h": syntax error in pattern`,
+		`$.platforms[1].include[1]: header "foo_linux.h" not found in -I paths ` + incDir + ` and the system paths`,
+		`$.platforms[1].libs: library -lfoo_linux not found in -L paths ` + libDir + ` and the system paths`,
+	}
+	if err == nil || err.Error() != strings.Join(want, "\n") {
+		t.Errorf("unexpected errors:\n%v\nwant:\n%s", err, strings.Join(want, "\n"))
+	}
+
+	err = cfgcheck.Check(&types.Config{}, nil)
+	want = []string{
+		`$.name: name is empty, it's the name of the generated Go package`,
+		`$.include: no header file is included`,
+	}
+	if err == nil || err.Error() != strings.Join(want, "\n") {
+		t.Errorf("unexpected errors:\n%v\nwant:\n%s", err, strings.Join(want, "\n"))
+	}
+}
+
+func TestSchemaFiles(t *testing.T) {
+	for name, gen := range map[string]func() ([]byte, error){
+		"llcppg.cfg.schema.json":       cfgcheck.CfgSchema,
+		"llcppg.symb.json.schema.json": cfgcheck.SymbSchema,
+	} {
+		want, err := gen()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join("..", "schema", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("schema/%s is out of date, run go generate in cfgcheck", name)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cfgcheck
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/goplus/llcppg/_xtool/llcppsymg/config/cfgparse"
+	"github.com/goplus/llcppg/types"
+	"golang.org/x/mod/module"
+)
+
+// Options configures the checks of Check.
+type Options struct {
+	IncPaths  []string // system include paths, searched for headers after the -I paths
+	LibPaths  []string // system library paths, searched for libraries after the -L paths
+	CheckLibs bool     // check the libraries of libs
+}
+
+// Check checks the values of conf, whose cflags and libs, and the ones of its
+// platform configs, are expanded: the package name, the headers of include,
+// the libraries of libs if opts.CheckLibs, the import paths of deps and the
+// patterns of filters. The platform
+// configs merged for the types.TargetPlatform and the tags of types.TagsEnv
+// are checked before conf is merged, at their own paths like
+// $.platforms[0].include[1].
+func Check(conf *types.Config, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	merged := *conf
+	merged.Include = append([]string(nil), conf.Include...)
+	merged.TrimPrefixes = append([]string(nil), conf.TrimPrefixes...)
+	merged.ResolvePlatform()
+	srcs := sources(conf)
+
+	var errs Errors
+	checkName(&errs, conf.Name)
+	checkHeaders(&errs, &merged, srcs, opts.IncPaths)
+	if opts.CheckLibs {
+		checkLibs(&errs, merged.Libs, srcs, opts.LibPaths)
+	}
+	// deps are resolved by gogensig, which reports the ones it can't import
+	for i, dep := range conf.Deps {
+		if err := module.CheckImportPath(dep); err != nil {
+			errs.add(fmt.Sprintf("$.deps[%d]", i), "can't import %q: %v", dep, err)
+		}
+	}
+	checkFilters(&errs, conf.Filters)
+	return errs.Err()
+}
+
+// source is a part of the config merged for the target platform: the base
+// config at $, or a platform config at $.platforms[i].
+type source struct {
+	path    string
+	include []string
+	libs    string
+}
+
+// sources returns the base config of conf and its platform configs merged for
+// the target platform, in the order they are merged.
+func sources(conf *types.Config) []source {
+	srcs := []source{{path: "$", include: conf.Include, libs: conf.Libs}}
+	goos, goarch := types.TargetPlatform()
+	tags := types.EnvTags()
+	for i, platform := range conf.Platforms {
+		if platform != nil && platform.Match(goos, goarch, tags) {
+			srcs = append(srcs, source{path: fmt.Sprintf("$.platforms[%d]", i), include: platform.Include, libs: platform.Libs})
+		}
+	}
+	return srcs
+}
+
+func checkName(errs *Errors, name string) {
+	switch {
+	case name == "":
+		errs.add("$.name", "name is empty, it's the name of the generated Go package")
+	case !token.IsIdentifier(name):
+		errs.add("$.name", "%q is not a valid Go package name", name)
+	}
+}
+
+// checkHeaders checks the headers of include of srcs, which are merged into
+// conf.
+func checkHeaders(errs *Errors, conf *types.Config, srcs []source, incPaths []string) {
+	if len(conf.Include) == 0 && !conf.IncludeAll {
+		errs.add("$.include", "no header file is included")
+		return
+	}
+	// an invalid pattern isn't reported as not found again
+	invalid := make(map[string]bool)
+	for _, src := range srcs {
+		for i, file := range src.include {
+			if cfgparse.IsGlobPattern(file) {
+				if _, err := path.Match(file, ""); err != nil {
+					errs.add(fmt.Sprintf("%s.include[%d]", src.path, i), "invalid glob pattern %q: %v", file, err)
+					invalid[file] = true
+				}
+			}
+		}
+	}
+	for i, pattern := range conf.IncludeExclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs.add(fmt.Sprintf("$.includeExclude[%d]", i), "invalid glob pattern %q: %v", pattern, err)
+		}
+	}
+	cflags := cfgparse.ParseCFlags(conf.CFlags)
+	if conf.IncludeAll {
+		for _, dir := range cflags.Paths {
+			if isSystemIncPath(dir, incPaths) {
+				errs.add("$.includeAll", "-I%s is a system include path, all of its headers would be headers of the package", dir)
+			}
+		}
+	}
+	_, notFounds, _ := cflags.GenPkgHeaderFilePaths(conf, incPaths)
+	if len(notFounds) == 0 {
+		return
+	}
+	notFound := make(map[string]bool, len(notFounds))
+	for _, file := range notFounds {
+		notFound[file] = true
+	}
+	where := searchPaths("-I", cflags.Paths)
+	for _, src := range srcs {
+		for i, file := range src.include {
+			if !notFound[file] || invalid[file] {
+				continue
+			}
+			if cfgparse.IsGlobPattern(file) {
+				// a glob pattern is only expanded in the -I paths
+				errs.add(fmt.Sprintf("%s.include[%d]", src.path, i), "no header matches %q in %s", file, globPaths(cflags.Paths))
+			} else {
+				errs.add(fmt.Sprintf("%s.include[%d]", src.path, i), "header %q not found in %s", file, where)
+			}
+		}
+	}
+}
+
+// broadIncPaths are include paths shared by many libraries, like the ones of a
+// package manager, which may not be system include paths of the compiler.
+var broadIncPaths = []string{"/usr/include", "/usr/local/include", "/opt/homebrew/include", "/opt/local/include"}
+
+// isSystemIncPath reports whether the include path dir is a system include
+// path or a broad include path.
+func isSystemIncPath(dir string, incPaths []string) bool {
+	dir = filepath.Clean(dir)
+	for _, paths := range [][]string{incPaths, broadIncPaths} {
+		for _, inc := range paths {
+			if filepath.Clean(inc) == dir {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func globPaths(paths []string) string {
+	if len(paths) == 0 {
+		return "the -I paths, which are missing"
+	}
+	return "-I paths " + strings.Join(paths, ", ")
+}
+
+// checkLibs checks the libraries of libs of srcs, which are merged into libs.
+func checkLibs(errs *Errors, libs string, srcs []source, libPaths []string) {
+	lbs := cfgparse.ParseLibs(libs)
+	if len(lbs.Names) == 0 {
+		errs.add("$.libs", "no library is linked by -l")
+		return
+	}
+	_, notFounds, _ := lbs.GenDylibPaths(libPaths)
+	notFound := make(map[string]bool, len(notFounds))
+	for _, name := range notFounds {
+		notFound[name] = true
+	}
+	where := searchPaths("-L", lbs.Paths)
+	for _, src := range srcs {
+		for _, name := range cfgparse.ParseLibs(src.libs).Names {
+			if notFound[name] {
+				errs.add(src.path+".libs", "library -l%s not found in %s", name, where)
+				// a library linked again isn't reported again
+				delete(notFound, name)
+			}
+		}
+	}
+}
+
+func searchPaths(flag string, paths []string) string {
+	if len(paths) == 0 {
+		return "the system paths, " + flag + " paths may be missing"
+	}
+	return flag + " paths " + strings.Join(paths, ", ") + " and the system paths"
+}
+
+func checkFilters(errs *Errors, filters *types.Filters) {
+	if filters == nil {
+		return
+	}
+	checkFilter(errs, "$.filters.symbols", filters.Symbols)
+	checkFilter(errs, "$.filters.types", filters.Types)
+	checkFilter(errs, "$.filters.macros", filters.Macros)
+	checkFilter(errs, "$.filters.headers", filters.Headers)
+}
+
+func checkFilter(errs *Errors, at string, filter *types.Filter) {
+	if filter == nil {
+		return
+	}
+	check := func(key string, patterns []string) {
+		for i, pattern := range patterns {
+			if _, err := types.CompilePattern(pattern); err != nil {
+				errs.add(fmt.Sprintf("%s.%s[%d]", at, key, i), "%v", err)
+			}
+		}
+	}
+	check("include", filter.Include)
+	check("exclude", filter.Exclude)
+}
@@ -0,0 +1,355 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cfgcheck validates llcppg.cfg, reporting each problem at its JSON
+// path like $.include[1], and generates the JSON Schemas of llcppg.cfg and
+// llcppg.symb.json from their Go types.
+package cfgcheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goplus/llcppg/types"
+)
+
+// Error is a problem of a config file at the JSON path like $.include[1].
+type Error struct {
+	Path string
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return e.Path + ": " + e.Msg
+}
+
+// Errors are the problems of a config file, in the order they are found.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Err returns e as an error, or nil if there is no problem.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *Errors) add(path, format string, args ...any) {
+	*e = append(*e, &Error{Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Decode decodes the llcppg.cfg data strictly: an unknown key, which is matched
+// case-sensitively like llcppsymg and llcppsigfetch do, or a value of a wrong
+// type is an error, reported as Errors. The $schema key, which refers an
+// editor to the JSON Schema of llcppg.cfg, is allowed.
+func Decode(data []byte) (*types.Config, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, Errors{syntaxError(data, err)}
+	}
+	if obj, ok := v.(map[string]any); ok {
+		if _, ok := obj["$schema"].(string); ok {
+			delete(obj, "$schema")
+		}
+	}
+	var errs Errors
+	checkValue(&errs, "$", v, reflect.TypeOf(types.Config{}))
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	// the patterns of the filters are compiled by decoding, which stops at the
+	// first invalid one, so they're all checked at their paths before
+	var raw struct {
+		Filters *struct {
+			Symbols, Types, Macros, Headers *struct{ Include, Exclude []string }
+		}
+	}
+	if err := json.Unmarshal(data, &raw); err == nil && raw.Filters != nil {
+		filter := func(f *struct{ Include, Exclude []string }) *types.Filter {
+			if f == nil {
+				return nil
+			}
+			return &types.Filter{Include: f.Include, Exclude: f.Exclude}
+		}
+		checkFilters(&errs, &types.Filters{
+			Symbols: filter(raw.Filters.Symbols),
+			Types:   filter(raw.Filters.Types),
+			Macros:  filter(raw.Filters.Macros),
+			Headers: filter(raw.Filters.Headers),
+		})
+		if len(errs) > 0 {
+			return nil, errs
+		}
+	}
+	conf := new(types.Config)
+	if err := json.Unmarshal(data, conf); err != nil {
+		return nil, Errors{{Path: "$", Msg: err.Error()}}
+	}
+	for i, platform := range conf.Platforms {
+		if platform != nil {
+			checkCondition(&errs, fmt.Sprintf("$.platforms[%d].when", i), platform.When)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return conf, nil
+}
+
+// checkCondition checks the condition of a types.PlatformConfig.
+func checkCondition(errs *Errors, path, when string) {
+	if strings.TrimSpace(when) == "" {
+		errs.add(path, "empty condition, expected GOOS, GOARCH or tags like linux,amd64")
+		return
+	}
+	for _, term := range strings.Split(when, ",") {
+		name := strings.TrimPrefix(strings.TrimSpace(term), "!")
+		if name == "" || strings.IndexFunc(name, func(r rune) bool {
+			return !(r == '_' || r == '.' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		}) >= 0 {
+			errs.add(path, "invalid term %q in condition %q", strings.TrimSpace(term), when)
+		}
+	}
+}
+
+func syntaxError(data []byte, err error) *Error {
+	var serr *json.SyntaxError
+	if !errors.As(err, &serr) {
+		return &Error{Path: "$", Msg: err.Error()}
+	}
+	// the offending byte is the last one read
+	line, col := 1, 1
+	for _, b := range data[:serr.Offset-1] {
+		if b == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return &Error{Path: "$", Msg: fmt.Sprintf("invalid JSON at line %d, column %d: %v", line, col, err)}
+}
+
+// checkValue checks that the JSON value v at path can be decoded as a value of t.
+func checkValue(errs *Errors, path string, v any, t reflect.Type) {
+	if v == nil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		default:
+			errs.add(path, "expected %s, got null", jsonKind(t))
+		}
+		return
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			errs.add(path, "expected object, got %s", valueKind(v))
+			return
+		}
+		fields := jsonFields(t)
+		for _, key := range sortedKeys(obj) {
+			field, ok := fields[key]
+			if !ok {
+				errs.add(keyPath(path, key), "unknown key%s", suggest(key, fields))
+				continue
+			}
+			checkValue(errs, keyPath(path, key), obj[key], field.Type)
+		}
+	case reflect.Map:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			errs.add(path, "expected object, got %s", valueKind(v))
+			return
+		}
+		for _, key := range sortedKeys(obj) {
+			checkValue(errs, keyPath(path, key), obj[key], t.Elem())
+		}
+	case reflect.Slice:
+		arr, ok := v.([]any)
+		if !ok {
+			errs.add(path, "expected array, got %s", valueKind(v))
+			return
+		}
+		for i, elem := range arr {
+			checkValue(errs, fmt.Sprintf("%s[%d]", path, i), elem, t.Elem())
+		}
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			errs.add(path, "expected string, got %s", valueKind(v))
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			errs.add(path, "expected boolean, got %s", valueKind(v))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := v.(json.Number)
+		if !ok {
+			errs.add(path, "expected integer, got %s", valueKind(v))
+		} else if _, err := n.Int64(); err != nil {
+			errs.add(path, "expected integer, got %s", n)
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := v.(json.Number); !ok {
+			errs.add(path, "expected number, got %s", valueKind(v))
+		}
+	}
+}
+
+// jsonFields returns the fields of the struct type t by their JSON names.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name, ok := jsonName(field); ok {
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+func jsonName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// suggest returns a hint of the known key which key is likely a typo of.
+func suggest(key string, fields map[string]reflect.StructField) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		dist := editDistance(strings.ToLower(key), strings.ToLower(name))
+		if dist < bestDist || dist == bestDist && name < best {
+			best, bestDist = name, dist
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if n := prev[j] + 1; n < cur[j] {
+				cur[j] = n
+			}
+			if n := cur[j-1] + 1; n < cur[j] {
+				cur[j] = n
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func keyPath(path, key string) string {
+	for i, c := range key {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			b, _ := json.Marshal(key)
+			return path + "[" + string(b) + "]"
+		}
+	}
+	if key == "" {
+		return path + `[""]`
+	}
+	return path + "." + key
+}
+
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// valueKind returns the JSON kind of a decoded value.
+func valueKind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// jsonKind returns the JSON kind of the values of t.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		return "array"
+	default:
+		return "object"
+	}
+}
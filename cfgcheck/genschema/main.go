@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command genschema writes the JSON Schemas of llcppg.cfg and llcppg.symb.json
+// to a directory.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/goplus/llcppg/cfgcheck"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: genschema dir")
+		os.Exit(2)
+	}
+	dir := os.Args[1]
+	for name, gen := range map[string]func() ([]byte, error){
+		"llcppg.cfg.schema.json":       cfgcheck.CfgSchema,
+		"llcppg.symb.json.schema.json": cfgcheck.SymbSchema,
+	} {
+		data, err := gen()
+		check(err)
+		check(os.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+}
+
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
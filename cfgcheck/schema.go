@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cfgcheck
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goplus/llcppg/types"
+)
+
+//go:generate go run ./genschema ../schema
+
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// CfgSchema returns the JSON Schema of llcppg.cfg, generated from types.Config.
+func CfgSchema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(types.Config{}))
+	schema["properties"].(map[string]any)["$schema"] = map[string]any{"type": "string"}
+	schema["$schema"] = schemaDraft
+	schema["title"] = "llcppg.cfg"
+	schema["required"] = []string{"name"}
+	return marshalSchema(schema)
+}
+
+// SymbSchema returns the JSON Schema of llcppg.symb.json, generated from
+// types.SymbolInfo.
+func SymbSchema() ([]byte, error) {
+	item := typeSchema(reflect.TypeOf(types.SymbolInfo{}))
+	item["required"] = requiredFields(reflect.TypeOf(types.SymbolInfo{}))
+	return marshalSchema(map[string]any{
+		"$schema": schemaDraft,
+		"title":   "llcppg.symb.json",
+		"type":    "array",
+		"items":   item,
+	})
+}
+
+func marshalSchema(schema map[string]any) ([]byte, error) {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// typeSchema returns the schema of the JSON values of t. The values of a
+// pointer, slice or map can also be null.
+func typeSchema(t reflect.Type) map[string]any {
+	nullable := false
+	switch t.Kind() {
+	case reflect.Pointer:
+		t, nullable = t.Elem(), true
+	case reflect.Slice, reflect.Map:
+		nullable = true
+	}
+	var schema map[string]any
+	switch t.Kind() {
+	case reflect.Struct:
+		props := make(map[string]any)
+		for name, field := range jsonFields(t) {
+			props[name] = typeSchema(field.Type)
+		}
+		schema = map[string]any{
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+	case reflect.Map:
+		schema = map[string]any{
+			"type":                 "object",
+			"additionalProperties": typeSchema(t.Elem()),
+		}
+	case reflect.Slice:
+		schema = map[string]any{
+			"type":  "array",
+			"items": typeSchema(t.Elem()),
+		}
+	default:
+		schema = map[string]any{"type": jsonKind(t)}
+	}
+	if nullable {
+		schema["type"] = []string{schema["type"].(string), "null"}
+	}
+	return schema
+}
+
+// requiredFields returns the JSON names of the fields of the struct type t
+// which aren't omitted if empty.
+func requiredFields(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		if tag := field.Tag.Get("json"); !hasOption(tag, "omitempty") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func hasOption(tag, option string) bool {
+	for _, opt := range strings.Split(tag, ",")[1:] {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
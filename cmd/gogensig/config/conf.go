@@ -16,7 +16,7 @@ import (
 	cppgtypes "github.com/goplus/llcppg/types"
 )
 
-// llcppg.cfg
+// llcppg.cfg, whose platform configs are merged for the current platform
 func GetCppgCfgFromPath(filePath string) (*cppgtypes.Config, error) {
 	bytes, err := ReadFile(filePath)
 	if err != nil {
@@ -27,6 +27,7 @@ func GetCppgCfgFromPath(filePath string) (*cppgtypes.Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	conf.ResolvePlatform()
 	return conf, nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -55,6 +56,28 @@ func TestLookupSymbolError(t *testing.T) {
 	}
 }
 
+func TestLookupCppSymbol(t *testing.T) {
+	table := config.CreateSymbolTable([]config.SymbolEntry{
+		{MangleName: "_ZN7Counter5countE", CppName: "Counter::count", GoName: "Counter_Count"},
+	})
+	entry, err := table.LookupCppSymbol("Counter::count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.MangleName != "_ZN7Counter5countE" || entry.GoName != "Counter_Count" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	for _, name := range []string{"Counter::total", ""} {
+		if _, err := table.LookupCppSymbol(name); err == nil {
+			t.Errorf("%q: expect error", name)
+		}
+	}
+	var nilTable *config.SymbolTable
+	if _, err := nilTable.LookupCppSymbol("Counter::count"); err == nil {
+		t.Error("expect error")
+	}
+}
+
 func TestSigfetch(t *testing.T) {
 	testCases := []struct {
 		name   string
@@ -167,6 +190,38 @@ func TestGetCppgCfgFromPath(t *testing.T) {
 		}
 	})
 
+	t.Run("Merge platform configs", func(t *testing.T) {
+		t.Setenv(cppgtypes.TagsEnv, "threads")
+		platformConfigPath := filepath.Join(tempDir, "platform_config.cfg")
+		err := os.WriteFile(platformConfigPath, []byte(`{
+		"name": "foo",
+		"cflags": "-I/opt/foo/include",
+		"include": ["foo.h"],
+		"platforms": [
+			{"when": "threads", "cflags": "-DFOO_USE_THREADS", "include": ["foo_threads.h"]},
+			{"when": "!threads", "include": ["foo_single.h"]},
+			{"when": "`+runtime.GOOS+`,`+runtime.GOARCH+`", "libs": "-lfoo", "trimPrefixes": ["foo_"]}
+		]
+	}`), 0644)
+		if err != nil {
+			t.Fatalf("Failed to create platform config file: %v", err)
+		}
+		cfg, err := config.GetCppgCfgFromPath(platformConfigPath)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		expectedConfig := &cppgtypes.Config{
+			Name:         "foo",
+			CFlags:       "-I/opt/foo/include -DFOO_USE_THREADS",
+			Include:      []string{"foo.h", "foo_threads.h"},
+			Libs:         "-lfoo",
+			TrimPrefixes: []string{"foo_"},
+		}
+		if !reflect.DeepEqual(cfg, expectedConfig) {
+			t.Errorf("Merged config does not match expected config.\nGot: %+v\nWant: %+v", cfg, expectedConfig)
+		}
+	})
+
 	t.Run("File not found", func(t *testing.T) {
 		_, err := config.GetCppgCfgFromPath(filepath.Join(tempDir, "nonexistent_file.cfg"))
 		if err == nil {
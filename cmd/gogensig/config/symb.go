@@ -19,7 +19,8 @@ type SymbolEntry struct {
 }
 
 type SymbolTable struct {
-	t map[MangleNameType]SymbolEntry
+	t   map[MangleNameType]SymbolEntry
+	cpp map[CppNameType]SymbolEntry
 }
 
 // llcppg.symb.json
@@ -37,10 +38,12 @@ func NewSymbolTable(filePath string) (*SymbolTable, error) {
 }
 func CreateSymbolTable(symbs []SymbolEntry) *SymbolTable {
 	symbolTable := &SymbolTable{
-		t: make(map[MangleNameType]SymbolEntry),
+		t:   make(map[MangleNameType]SymbolEntry),
+		cpp: make(map[CppNameType]SymbolEntry),
 	}
 	for _, symb := range symbs {
 		symbolTable.t[symb.MangleName] = symb
+		symbolTable.cpp[symb.CppName] = symb
 	}
 	return symbolTable
 }
@@ -58,3 +61,16 @@ func (t *SymbolTable) LookupSymbol(name MangleNameType) (*SymbolEntry, error) {
 	}
 	return nil, errs.NewSymbolNotFoudError(name)
 }
+
+// LookupCppSymbol looks up a symbol by its C++ name, like Foo::count of a
+// static data member, whose declaration has no mangled name.
+func (t *SymbolTable) LookupCppSymbol(name CppNameType) (*SymbolEntry, error) {
+	if t == nil || t.cpp == nil {
+		return nil, errs.NewSymbolTableNotInitializedError()
+	}
+	symbol, ok := t.cpp[name]
+	if ok && len(name) > 0 {
+		return &symbol, nil
+	}
+	return nil, errs.NewSymbolNotFoudError(name)
+}
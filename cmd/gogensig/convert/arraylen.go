@@ -0,0 +1,104 @@
+/*
+This file keeps the spelled lengths of arrays, like [NAME_MAX]int8 of
+char name[NAME_MAX], when the length names a constant of the package. gogen
+writes the evaluated lengths of array types, so the lengths are replaced in the
+AST of the written Go files.
+*/
+package convert
+
+import (
+	goast "go/ast"
+	"go/constant"
+	"go/types"
+)
+
+// addConst records the Go name of the integer constant named cname in C, which
+// may be spelled as an array length.
+func (p *Package) addConst(cname, name string) {
+	if p.consts == nil {
+		p.consts = make(map[string]string)
+	}
+	p.consts[cname] = name
+}
+
+// addArrayLen records the C name spelled as the length of the array type arr.
+func (p *Package) addArrayLen(arr *types.Array, cname string) {
+	if p.arrayLens == nil {
+		p.arrayLens = make(map[*types.Array]string)
+	}
+	p.arrayLens[arr] = cname
+}
+
+// arrayLenName returns the Go name of the constant spelled as the length of arr,
+// or "" if the length is not spelled as a constant of the package with the same
+// value.
+func (p *Package) arrayLenName(arr *types.Array) string {
+	cname, ok := p.arrayLens[arr]
+	if !ok {
+		return ""
+	}
+	name, ok := p.consts[cname]
+	if !ok {
+		return ""
+	}
+	obj, ok := p.p.Types.Scope().Lookup(name).(*types.Const)
+	if !ok {
+		return ""
+	}
+	if n, exact := constant.Int64Val(constant.ToInt(obj.Val())); !exact || n != arr.Len() {
+		return ""
+	}
+	return name
+}
+
+// attachArrayLens replaces the evaluated lengths of the recorded arrays in the
+// type declarations of the Go file genFName by the names of their constants.
+func (p *Package) attachArrayLens(genFName string) {
+	if len(p.arrayLens) == 0 {
+		return
+	}
+	file := p.p.ASTFile(genFName)
+	if file == nil {
+		return
+	}
+	scope := p.p.Types.Scope()
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*goast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			if spec, ok := spec.(*goast.TypeSpec); ok {
+				if obj := scope.Lookup(spec.Name.Name); obj != nil {
+					p.attachArrayLen(spec.Type, obj.Type().Underlying())
+				}
+			}
+		}
+	}
+}
+
+// attachArrayLen replaces the lengths of the arrays in expr of type typ,
+// including the arrays in fields, pointers and array elements.
+func (p *Package) attachArrayLen(expr goast.Expr, typ types.Type) {
+	switch t := typ.(type) {
+	case *types.Array:
+		if arr, ok := expr.(*goast.ArrayType); ok {
+			if name := p.arrayLenName(t); name != "" {
+				arr.Len = goast.NewIdent(name)
+			}
+			p.attachArrayLen(arr.Elt, t.Elem())
+		}
+	case *types.Pointer:
+		if star, ok := expr.(*goast.StarExpr); ok {
+			p.attachArrayLen(star.X, t.Elem())
+		}
+	case *types.Struct:
+		structExpr, ok := expr.(*goast.StructType)
+		if !ok || t.NumFields() != len(structExpr.Fields.List) {
+			return
+		}
+		for i, field := range structExpr.Fields.List {
+			p.attachArrayLen(field.Type, t.Field(i).Type())
+		}
+	}
+}
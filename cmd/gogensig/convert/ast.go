@@ -28,17 +28,29 @@ type ConvertCommentGroup struct {
 	*goast.CommentGroup
 }
 
+// CommentGroup converts a C comment group to a Go doc comment,
+// translating its Doxygen markup (see doxygenToGoDoc).
 func CommentGroup(doc *ast.CommentGroup) *ConvertCommentGroup {
 	goDoc := &goast.CommentGroup{}
 	goDoc.List = make([]*goast.Comment, 0)
 	if doc != nil && doc.List != nil {
-		for _, comment := range doc.List {
-			goDoc.List = append(goDoc.List, Comment(comment).Comment)
-		}
+		goDoc.List = append(goDoc.List, goDocComments(doxygenToGoDoc(docLines(doc)))...)
 	}
 	return &ConvertCommentGroup{CommentGroup: goDoc}
 }
 
+// DeclCommentGroup converts the doc comment of a C declaration to a Go doc comment
+// like CommentGroup, adding the notes and the doc lines of its attributes (see attrDocLines).
+func DeclCommentGroup(doc *ast.CommentGroup, attrs []*ast.Attr, cname string, notes ...string) *ConvertCommentGroup {
+	var lines []string
+	if doc != nil && doc.List != nil {
+		lines = doxygenToGoDoc(docLines(doc))
+	}
+	goDoc := &goast.CommentGroup{}
+	goDoc.List = append(make([]*goast.Comment, 0), goDocComments(attrDocLines(lines, notes, attrs, cname))...)
+	return &ConvertCommentGroup{CommentGroup: goDoc}
+}
+
 func (p *ConvertCommentGroup) AddComment(comment *goast.Comment) error {
 	if comment == nil || len(comment.Text) <= 0 {
 		return fmt.Errorf("%s", "add nil or empty comment")
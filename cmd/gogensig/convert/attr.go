@@ -0,0 +1,132 @@
+/*
+This file applies the attributes of C declarations, like deprecated and noreturn,
+and the nullability of pointers to the generated Go code.
+*/
+package convert
+
+import (
+	"go/types"
+	"strings"
+
+	"github.com/goplus/llcppg/ast"
+)
+
+// skippedByAttrs returns why a declaration is not converted because of its
+// attributes, or "" if it is converted. An unavailable declaration can't be
+// used, and a declaration with hidden visibility is not exported by the library.
+func skippedByAttrs(decl *ast.DeclBase) string {
+	if decl.Attr(ast.AttrUnavailable) != nil {
+		return "unavailable"
+	}
+	if attr := decl.Attr(ast.AttrVisibility); attr != nil && len(attr.Args) > 0 {
+		if vis := attr.Args[0]; vis == "hidden" || vis == "internal" {
+			return "visibility " + vis
+		}
+	}
+	return ""
+}
+
+// attrDocLines appends the notes and the doc lines of attrs to the lines of a
+// doc comment. The notes are a paragraph. A deprecated attribute starts a
+// Deprecated paragraph, unless the doc comment already has one, and the other
+// attributes are listed in an Attributes line:
+//
+//	buf must not be NULL.
+//
+//	Attributes: noreturn, format(printf, 1, 2).
+//
+//	Deprecated: use bar instead
+func attrDocLines(lines []string, notes []string, attrs []*ast.Attr, cname string) []string {
+	if len(notes) > 0 {
+		lines = appendParagraph(lines, notes[0])
+		lines = append(lines, notes[1:]...)
+	}
+	var deprecated *ast.Attr
+	var names []string
+	for _, attr := range attrs {
+		switch attr.Name {
+		case ast.AttrDeprecated:
+			deprecated = attr
+		case ast.AttrUnavailable, ast.AttrVisibility:
+		default:
+			names = append(names, attrString(attr))
+		}
+	}
+	if len(names) > 0 {
+		lines = appendParagraph(lines, "Attributes: "+strings.Join(names, ", ")+".")
+	}
+	if deprecated != nil && !hasDeprecatedParagraph(lines) {
+		msg := cname + " is deprecated."
+		if len(deprecated.Args) > 0 && deprecated.Args[0] != "" {
+			msg = deprecated.Args[0]
+		}
+		lines = appendParagraph(lines, "Deprecated: "+msg)
+	}
+	return lines
+}
+
+// attrString returns attr in the C form, eg. format(printf, 1, 2).
+func attrString(attr *ast.Attr) string {
+	if len(attr.Args) == 0 {
+		return attr.Name
+	}
+	return attr.Name + "(" + strings.Join(attr.Args, ", ") + ")"
+}
+
+// nullabilityNotes returns the doc lines of the nullability of the pointer
+// parameters and result of a function, eg. "buf must not be NULL.".
+// The receiver (if any) is skipped.
+func nullabilityNotes(funcType *ast.FuncType, sig *types.Signature) []string {
+	var notes []string
+	if funcType.Params != nil {
+		first := 0
+		if sig.Recv() != nil {
+			first = 1
+		}
+		for i, field := range funcType.Params.List[first:] {
+			if i >= sig.Params().Len() {
+				break
+			}
+			switch nullability(field.Type) {
+			case ast.Nonnull:
+				notes = append(notes, sig.Params().At(i).Name()+" must not be NULL.")
+			case ast.Nullable:
+				notes = append(notes, sig.Params().At(i).Name()+" may be NULL.")
+			}
+		}
+	}
+	switch nullability(funcType.Ret) {
+	case ast.Nonnull:
+		notes = append(notes, "The result is never NULL.")
+	case ast.Nullable:
+		notes = append(notes, "The result may be NULL.")
+	}
+	return notes
+}
+
+// nullability returns the nullability qualifier of a pointer type, or 0 if it has none.
+func nullability(typ ast.Expr) ast.Qualifier {
+	if q, ok := typ.(*ast.QualifiedType); ok {
+		return q.Qualifiers & (ast.Nonnull | ast.Nullable | ast.NullUnspecified)
+	}
+	return 0
+}
+
+// isConstPointer reports whether typ is a pointer to a const type, like const char *.
+func isConstPointer(typ ast.Expr) bool {
+	ptr, ok := ast.Unqualified(typ).(*ast.PointerType)
+	if !ok {
+		return false
+	}
+	q, ok := ptr.X.(*ast.QualifiedType)
+	return ok && q.Qualifiers&ast.Const != 0
+}
+
+func hasDeprecatedParagraph(lines []string) bool {
+	for _, line := range lines {
+		if strings.HasPrefix(line, "Deprecated: ") {
+			return true
+		}
+	}
+	return false
+}
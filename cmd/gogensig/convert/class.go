@@ -0,0 +1,62 @@
+/*
+This file converts C++ classes and their member functions. A class is converted
+like a struct, and a non-static member function becomes a method of the Go type
+of its class, whose receiver is the implicit this parameter. A reference is
+passed as a pointer, and a function of a C++ package is linked by its mangled
+name, as C++ functions are scoped and overloaded.
+*/
+package convert
+
+import (
+	"go/types"
+
+	"github.com/goplus/llcppg/ast"
+)
+
+func (p *AstConvert) VisitClass(className *ast.Ident, fields *ast.FieldList, typeDecl *ast.TypeDecl) {
+	p.VisitStruct(className, fields, typeDecl)
+}
+
+func (p *AstConvert) VisitMethod(className *ast.Ident, method *ast.FuncDecl, typeDecl *ast.TypeDecl) {
+	if className == nil || p.typeExcluded(typeDecl.Parent, typeDecl.Name) {
+		return
+	}
+	p.VisitFuncDecl(method)
+}
+
+// isMemberFunc reports whether funcDecl is a non-static member function of a
+// record, which is called with the object as its implicit this parameter.
+func (p *Package) isMemberFunc(funcDecl *ast.FuncDecl) bool {
+	return !funcDecl.IsStatic && p.outerRecord(funcDecl.Parent) != ""
+}
+
+// withThis returns a copy of the member function funcDecl with its implicit this
+// parameter prepended, which becomes the receiver of the Go method.
+func withThis(funcDecl *ast.FuncDecl) *ast.FuncDecl {
+	this := &ast.Field{
+		Names: []*ast.Ident{{Name: "this"}},
+		Type:  &ast.PointerType{X: funcDecl.Parent},
+	}
+	params := []*ast.Field{this}
+	if funcDecl.Type.Params != nil {
+		params = append(params, funcDecl.Type.Params.List...)
+	}
+	fn := *funcDecl
+	fn.Type = &ast.FuncType{Params: &ast.FieldList{List: params}, Ret: funcDecl.Type.Ret}
+	return &fn
+}
+
+// linkName returns the symbol funcDecl is linked to, which is its mangled name
+// in a C++ package and its name in a C package.
+func (p *Package) linkName(funcDecl *ast.FuncDecl) string {
+	if p.CppgConf != nil && p.CppgConf.Cplusplus && funcDecl.MangledName != "" {
+		return funcDecl.MangledName
+	}
+	return funcDecl.Name.Name
+}
+
+// handleRefType converts a lvalue or rvalue reference to elem, which is passed
+// as a pointer.
+func (p *TypeConv) handleRefType(elem ast.Expr) (types.Type, error) {
+	return p.handlePointerType(&ast.PointerType{X: elem})
+}
@@ -0,0 +1,324 @@
+/*
+This file translates C comments into Go doc comments,
+including the Doxygen and Javadoc markup commonly used in C headers.
+*/
+package convert
+
+import (
+	goast "go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/goplus/llcppg/ast"
+)
+
+// docLines returns the text lines of a C comment group with the comment
+// markers (//, ///, //!, /*, /**, /*!, the trailing < of member comments,
+// the leading * of block comment lines and */) removed.
+func docLines(doc *ast.CommentGroup) []string {
+	var lines []string
+	inBlock := false
+	for _, comment := range doc.List {
+		for _, line := range strings.Split(strings.TrimRight(comment.Text, "\r\n"), "\n") {
+			text := strings.TrimLeft(line, " \t")
+			switch {
+			case inBlock:
+				if strings.HasPrefix(text, "*") && !strings.HasPrefix(text, "*/") {
+					text = text[1:]
+				}
+			case strings.HasPrefix(text, "//"):
+				text = trimMarker(text[2:], "/!")
+			case strings.HasPrefix(text, "/*"):
+				text = trimMarker(text[2:], "*!")
+				inBlock = true
+			default:
+				text = line
+			}
+			if inBlock {
+				if end := strings.Index(text, "*/"); end >= 0 {
+					text = text[:end]
+					inBlock = false
+				}
+			}
+			text = strings.TrimRight(strings.TrimPrefix(text, " "), " \t\r")
+			if strings.Trim(text, "*/") == "" {
+				// decoration lines like /*****
+				text = ""
+			}
+			lines = append(lines, text)
+		}
+	}
+	return lines
+}
+
+// trimMarker removes the Doxygen marker that follows // or /*, eg. the ! of //!
+// and the < of a member comment like ///<.
+func trimMarker(text, marker string) string {
+	if strings.HasPrefix(text, "*/") {
+		return text
+	}
+	if len(text) > 0 && strings.IndexByte(marker, text[0]) >= 0 {
+		text = text[1:]
+	}
+	return strings.TrimPrefix(text, "<")
+}
+
+// docSections holds the sections of a doc comment parsed from Doxygen markup.
+type docSections struct {
+	desc       []string
+	params     []string
+	returns    []string
+	deprecated []string
+}
+
+// doxygenToGoDoc translates Doxygen and Javadoc markup into Go doc comment text.
+// Both the @cmd and \cmd forms of the following commands are translated:
+//
+//   - @brief, @short and @details are removed, keeping their text as description.
+//   - @param is listed under a Parameters section.
+//   - @return, @returns and @result start a Returns paragraph.
+//   - @deprecated starts a Deprecated paragraph, recognized by Go tools.
+//   - @code ... @endcode becomes an indented code block.
+//
+// Other text is kept as is.
+func doxygenToGoDoc(lines []string) []string {
+	var sec docSections
+	target := &sec.desc
+	inCode := false
+	for _, line := range lines {
+		cmd, rest := doxygenCommand(line)
+		if inCode {
+			if cmd == "endcode" {
+				inCode = false
+				sec.desc = append(sec.desc, "")
+			} else {
+				sec.desc = append(sec.desc, "\t"+line)
+			}
+			continue
+		}
+		switch cmd {
+		case "brief", "short", "details":
+			target = &sec.desc
+		case "param":
+			name, desc, _ := strings.Cut(strings.TrimSpace(rest), " ")
+			sec.params = append(sec.params, strings.TrimSpace(name+": "+strings.TrimSpace(desc)))
+			target = &sec.params
+			continue
+		case "return", "returns", "result":
+			target = &sec.returns
+		case "deprecated":
+			target = &sec.deprecated
+		case "code":
+			inCode = true
+			target = &sec.desc
+			sec.desc = append(sec.desc, "")
+			continue
+		case "endcode":
+			continue
+		default:
+			rest = line
+		}
+		rest = strings.TrimSpace(rest)
+		switch {
+		case rest == "":
+			// a blank line ends the paragraph of a command
+			sec.desc = append(sec.desc, "")
+			target = &sec.desc
+		case target == &sec.params:
+			sec.params[len(sec.params)-1] += " " + rest
+		default:
+			*target = append(*target, rest)
+		}
+	}
+
+	out := compactLines(sec.desc)
+	if len(sec.params) > 0 {
+		out = appendParagraph(out, "Parameters:")
+		for _, param := range sec.params {
+			out = append(out, "  - "+param)
+		}
+	}
+	if len(sec.returns) > 0 {
+		out = appendParagraph(out, "Returns: "+sec.returns[0])
+		out = append(out, sec.returns[1:]...)
+	}
+	if len(sec.deprecated) > 0 {
+		out = appendParagraph(out, "Deprecated: "+sec.deprecated[0])
+		out = append(out, sec.deprecated[1:]...)
+	}
+	return out
+}
+
+// doxygenCommand returns the Doxygen command at the beginning of line
+// without its @ or \ prefix and any [in,out] direction, and the rest of line.
+func doxygenCommand(line string) (cmd, rest string) {
+	text := strings.TrimSpace(line)
+	if len(text) < 2 || (text[0] != '@' && text[0] != '\\') {
+		return "", line
+	}
+	end := 1
+	for end < len(text) && isCommandChar(text[end]) {
+		end++
+	}
+	cmd, rest = text[1:end], text[end:]
+	if strings.HasPrefix(rest, "[") {
+		if i := strings.IndexByte(rest, ']'); i >= 0 {
+			rest = rest[i+1:]
+		}
+	} else if strings.HasPrefix(rest, "{") { // \code{.c}
+		if i := strings.IndexByte(rest, '}'); i >= 0 {
+			rest = rest[i+1:]
+		}
+	}
+	return cmd, rest
+}
+
+func isCommandChar(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+}
+
+// compactLines removes the leading and trailing blank lines and merges
+// consecutive blank lines.
+func compactLines(lines []string) []string {
+	var out []string
+	for _, line := range lines {
+		if line == "" && (len(out) == 0 || out[len(out)-1] == "") {
+			continue
+		}
+		out = append(out, line)
+	}
+	if len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+func appendParagraph(lines []string, line string) []string {
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	return append(lines, line)
+}
+
+// goDocComments converts the text lines of a doc comment to Go comments.
+func goDocComments(lines []string) []*goast.Comment {
+	comments := make([]*goast.Comment, 0, len(lines))
+	for _, line := range lines {
+		if line == "" || strings.HasPrefix(line, "\t") {
+			comments = append(comments, &goast.Comment{Text: "//" + line})
+		} else {
+			comments = append(comments, &goast.Comment{Text: "// " + line})
+		}
+	}
+	return comments
+}
+
+// lineComments returns the doc comments of the generated code, a comment per line.
+func lineComments(lines ...string) *goast.CommentGroup {
+	doc := &goast.CommentGroup{}
+	for _, line := range lines {
+		doc.List = append(doc.List, &goast.Comment{Text: "// " + line})
+	}
+	return doc
+}
+
+// objComments holds the comments of a struct field or an enum constant,
+// which are attached to the generated Go source when it is written.
+type objComments struct {
+	doc     *goast.CommentGroup
+	comment *goast.CommentGroup
+}
+
+// setObjComments records the doc and line comments of a struct field or an enum constant.
+func (p *Package) setObjComments(obj types.Object, doc, comment *ast.CommentGroup) {
+	c := &objComments{doc: goCommentGroup(doc), comment: goCommentGroup(comment)}
+	if c.doc == nil && c.comment == nil {
+		return
+	}
+	if c.doc != nil {
+		// gogen prints the comments of nodes without positions on the line of
+		// the previous token, so a doc comment starts with its own line break.
+		// The indentation is fixed by formatting the written source.
+		list := append([]*goast.Comment{}, c.doc.List...)
+		list[0] = &goast.Comment{Text: "\n" + list[0].Text}
+		c.doc = &goast.CommentGroup{List: list}
+	}
+	if p.objComments == nil {
+		p.objComments = make(map[types.Object]*objComments)
+	}
+	p.objComments[obj] = c
+}
+
+// goCommentGroup returns the Go comment group of doc, or nil if it has no text.
+func goCommentGroup(doc *ast.CommentGroup) *goast.CommentGroup {
+	if doc == nil {
+		return nil
+	}
+	if goDoc := CommentGroup(doc).CommentGroup; len(goDoc.List) > 0 {
+		return goDoc
+	}
+	return nil
+}
+
+// attachObjComments attaches the recorded comments of struct fields and enum
+// constants to the AST of the Go file genFName, which gogen generates without them.
+// It reports whether any comment is attached.
+func (p *Package) attachObjComments(genFName string) bool {
+	if len(p.objComments) == 0 {
+		return false
+	}
+	file := p.p.ASTFile(genFName)
+	if file == nil {
+		return false
+	}
+	attached := false
+	scope := p.p.Types.Scope()
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*goast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			switch spec := spec.(type) {
+			case *goast.TypeSpec:
+				if obj := scope.Lookup(spec.Name.Name); obj != nil {
+					attached = p.attachFieldComments(spec.Type, obj.Type().Underlying()) || attached
+				}
+			case *goast.ValueSpec:
+				if len(spec.Names) != 1 {
+					continue
+				}
+				if c, ok := p.objComments[scope.Lookup(spec.Names[0].Name)]; ok {
+					spec.Doc, spec.Comment = c.doc, c.comment
+					attached = true
+				}
+			}
+		}
+	}
+	return attached
+}
+
+// attachFieldComments attaches the comments of the fields of typ to expr,
+// including the fields of nested anonymous structs.
+func (p *Package) attachFieldComments(expr goast.Expr, typ types.Type) bool {
+	structExpr, ok := expr.(*goast.StructType)
+	if !ok {
+		return false
+	}
+	structType, ok := typ.(*types.Struct)
+	if !ok || structType.NumFields() != len(structExpr.Fields.List) {
+		return false
+	}
+	attached := false
+	for i, field := range structExpr.Fields.List {
+		v := structType.Field(i)
+		if c, ok := p.objComments[v]; ok {
+			field.Doc, field.Comment = c.doc, c.comment
+			attached = true
+		}
+		if _, named := v.Type().(*types.Named); !named {
+			attached = p.attachFieldComments(field.Type, v.Type().Underlying()) || attached
+		}
+	}
+	return attached
+}
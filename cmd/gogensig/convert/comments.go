@@ -22,6 +22,13 @@ func NewFuncDocComments(funcName string, goFuncName string) *goast.CommentGroup
 	return &commentGroup
 }
 
+// NewVarDocComments links the Go variable goVarName to the C symbol varName, a
+// variable is linked by the bare symbol name, without the C. prefix of functions.
+func NewVarDocComments(varName string, goVarName string) *goast.CommentGroup {
+	comment := goast.Comment{Text: "//go:linkname " + goVarName + " " + varName}
+	return &goast.CommentGroup{List: []*goast.Comment{&comment}}
+}
+
 func NewTypecDocComments() *goast.CommentGroup {
 	return &goast.CommentGroup{
 		List: []*goast.Comment{
@@ -16,6 +16,7 @@ type AstConvert struct {
 	*visitor.BaseDocVisitor
 	Pkg       *Package
 	visitDone func(pkg *Package, incPath string)
+	skipFile  bool // the current header is excluded by the header filter
 }
 
 type AstConvertConfig struct {
@@ -80,32 +81,27 @@ func (p *AstConvert) WritePubFile() {
 }
 
 func (p *AstConvert) VisitFuncDecl(funcDecl *ast.FuncDecl) {
+	if p.symbolExcluded(funcDecl.Parent, funcDecl.Name) {
+		return
+	}
 	err := p.Pkg.NewFuncDecl(funcDecl)
 	if err != nil {
 		if dbg.GetDebugError() {
-			log.Printf("NewFuncDecl %s Fail: %s\n", funcDecl.Name.Name, err.Error())
+			log.Printf("%s: NewFuncDecl %s Fail: %s\n", funcDecl.Loc, funcDecl.Name.Name, err.Error())
 		}
 	}
 }
 
 func (p *AstConvert) VisitMacro(macro *ast.Macro) {
+	if p.macroExcluded(macro.Name) {
+		return
+	}
 	err := p.Pkg.NewMacro(macro)
 	if err != nil {
 		log.Printf("NewMacro %s Fail: %s\n", macro.Name, err.Error())
 	}
 }
 
-/*
-//TODO
-func (p *AstConvert) VisitClass(className *ast.Ident, fields *ast.FieldList, typeDecl *ast.TypeDecl) {
-	fmt.Printf("visit class %s\n", className.Name)
-	p.pkg.NewTypeDecl(typeDecl)
-}
-
-func (p *AstConvert) VisitMethod(className *ast.Ident, method *ast.FuncDecl, typeDecl *ast.TypeDecl) {
-	fmt.Printf("visit method %s of %s\n", method.Name.Name, className.Name)
-}*/
-
 func (p *AstConvert) VisitStruct(structName *ast.Ident, fields *ast.FieldList, typeDecl *ast.TypeDecl) {
 	// https://github.com/goplus/llcppg/issues/66 ignore unexpected struct name
 	// Union (unnamed at /usr/local/Cellar/msgpack/6.0.2/include/msgpack/object.h:75:9)
@@ -115,13 +111,16 @@ func (p *AstConvert) VisitStruct(structName *ast.Ident, fields *ast.FieldList, t
 		}
 		return
 	}
+	if p.typeExcluded(typeDecl.Parent, typeDecl.Name) {
+		return
+	}
 	err := p.Pkg.NewTypeDecl(typeDecl)
 	if typeDecl.Name == nil {
-		log.Printf("NewTypeDecl anonymous struct skipped")
+		log.Printf("%s: NewTypeDecl anonymous struct skipped\n", typeDecl.Loc)
 	}
 	if err != nil {
 		if name := typeDecl.Name; name != nil {
-			log.Printf("NewTypeDecl %s Fail: %s\n", name.Name, err.Error())
+			log.Printf("%s: NewTypeDecl %s Fail: %s\n", typeDecl.Loc, name.Name, err.Error())
 		}
 	}
 }
@@ -131,20 +130,36 @@ func (p *AstConvert) VisitUnion(unionName *ast.Ident, fields *ast.FieldList, typ
 }
 
 func (p *AstConvert) VisitEnumTypeDecl(enumTypeDecl *ast.EnumTypeDecl) {
+	if p.typeExcluded(enumTypeDecl.Parent, enumTypeDecl.Name) {
+		return
+	}
 	err := p.Pkg.NewEnumTypeDecl(enumTypeDecl)
 	if err != nil {
 		if name := enumTypeDecl.Name; name != nil {
-			log.Printf("NewEnumTypeDecl %s Fail: %s\n", name.Name, err.Error())
+			log.Printf("%s: NewEnumTypeDecl %s Fail: %s\n", enumTypeDecl.Loc, name.Name, err.Error())
 		} else {
-			log.Printf("NewEnumTypeDecl anonymous Fail: %s\n", err.Error())
+			log.Printf("%s: NewEnumTypeDecl anonymous Fail: %s\n", enumTypeDecl.Loc, err.Error())
 		}
 	}
 }
 
 func (p *AstConvert) VisitTypedefDecl(typedefDecl *ast.TypedefDecl) {
+	if p.typeExcluded(typedefDecl.Parent, typedefDecl.Name) {
+		return
+	}
 	err := p.Pkg.NewTypedefDecl(typedefDecl)
 	if err != nil {
-		log.Printf("NewTypedefDecl %s Fail: %s\n", typedefDecl.Name.Name, err.Error())
+		log.Printf("%s: NewTypedefDecl %s Fail: %s\n", typedefDecl.Loc, typedefDecl.Name.Name, err.Error())
+	}
+}
+
+func (p *AstConvert) VisitVarDecl(varDecl *ast.VarDecl) {
+	if p.symbolExcluded(varDecl.Parent, varDecl.Name) {
+		return
+	}
+	err := p.Pkg.NewVarDecl(varDecl)
+	if err != nil {
+		log.Printf("%s: NewVarDecl %s Fail: %s\n", varDecl.Loc, varDecl.Name.Name, err.Error())
 	}
 }
 
@@ -163,6 +178,15 @@ func (p *AstConvert) VisitStart(path string, incPath string, isSys bool) {
 			break
 		}
 	}
+	// the declarations of an excluded header of the package are not converted,
+	// and it's not a Go file of the package
+	p.skipFile = inPkgIncPath && !isSys && !p.filters().Header(path)
+	if p.skipFile {
+		if dbg.GetDebugLog() {
+			log.Printf("header %s is filtered out\n", path)
+		}
+		return
+	}
 	p.Pkg.SetCurFile(&HeaderFile{
 		File:         path,
 		IncPath:      incPath,
@@ -402,7 +402,7 @@ import (
 
 type NormalType c.Int
 type Foo struct {
-	Unused [8]uint8
+	_ [0]byte
 }
 `
 	if strings.TrimSpace(expectedOutput) != strings.TrimSpace(buf.String()) {
@@ -410,6 +410,126 @@ type Foo struct {
 	}
 }
 
+// The members of a C++ class are visited by VisitClass and VisitMethod: a member
+// function becomes a method linked to its mangled name, a reference is a pointer.
+func TestVisitClass(t *testing.T) {
+	symbPath, err := config.CreateTmpJSONFile("llcppg.symb.json", []config.SymbolEntry{
+		{MangleName: "_ZNK3Foo3getERKi", CppName: "Foo::get(const int &) const", GoName: "(*Foo).Get"},
+		{MangleName: "_ZN3Foo3setEOi", CppName: "Foo::set(int &&)", GoName: "(*Foo).Set"},
+		{MangleName: "_ZN3Bar5resetEv", CppName: "Bar::reset()", GoName: "(*Bar).Reset"},
+		{MangleName: "foo_count", CppName: "foo_count", GoName: "FooCount"},
+	})
+	defer os.Remove(symbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	converter, err := convert.NewAstConvert(&convert.AstConvertConfig{
+		PkgName:  "test",
+		SymbFile: symbPath,
+		CfgFile:  "",
+	})
+	if err != nil {
+		t.Fatal("NewAstConvert Fail")
+	}
+	converter.Pkg.CppgConf.Cplusplus = true
+	foo := &ast.Ident{Name: "Foo"}
+	integer := &ast.BuiltinType{Kind: ast.Int}
+	method := func(name, mangled string, param *ast.Field, ret ast.Expr) *ast.FuncDecl {
+		return &ast.FuncDecl{
+			DeclBase:    ast.DeclBase{Parent: foo},
+			Name:        &ast.Ident{Name: name},
+			MangledName: mangled,
+			Type:        &ast.FuncType{Params: &ast.FieldList{List: []*ast.Field{param}}, Ret: ret},
+		}
+	}
+	// class Foo {
+	// public:
+	//     int x;
+	//     int get(const int &i) const;
+	//     void set(int &&v);
+	// };
+	// struct Bar {
+	//     void reset();
+	// };
+	// int foo_count(void);
+	bar := &ast.Ident{Name: "Bar"}
+	converter.Visit(&ast.File{Decls: []ast.Decl{
+		&ast.TypeDecl{
+			Name: foo,
+			Type: &ast.RecordType{
+				Tag: ast.Class,
+				Fields: &ast.FieldList{List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "x"}}, Type: integer, Access: ast.Public},
+				}},
+				Methods: []*ast.FuncDecl{
+					method("get", "_ZNK3Foo3getERKi", &ast.Field{
+						Names: []*ast.Ident{{Name: "i"}},
+						Type:  &ast.LvalueRefType{X: &ast.QualifiedType{X: integer, Qualifiers: ast.Const}},
+					}, integer),
+					method("set", "_ZN3Foo3setEOi", &ast.Field{
+						Names: []*ast.Ident{{Name: "v"}},
+						Type:  &ast.RvalueRefType{X: integer},
+					}, &ast.BuiltinType{Kind: ast.Void}),
+				},
+			},
+		},
+		&ast.TypeDecl{
+			Name: bar,
+			Type: &ast.RecordType{
+				Tag:    ast.Struct,
+				Fields: &ast.FieldList{},
+				Methods: []*ast.FuncDecl{{
+					DeclBase:    ast.DeclBase{Parent: bar},
+					Name:        &ast.Ident{Name: "reset"},
+					MangledName: "_ZN3Bar5resetEv",
+					Type:        &ast.FuncType{Params: &ast.FieldList{}, Ret: &ast.BuiltinType{Kind: ast.Void}},
+				}},
+			},
+		},
+		&ast.FuncDecl{
+			Name:        &ast.Ident{Name: "foo_count"},
+			MangledName: "foo_count",
+			Type:        &ast.FuncType{Params: &ast.FieldList{}, Ret: integer},
+		},
+	}})
+
+	buf, err := converter.Pkg.WriteDefaultFileToBuffer()
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	expectedOutput :=
+		`
+package test
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	X c.Int
+}
+// llgo:link (*Foo).Get C._ZNK3Foo3getERKi
+func (recv_ *Foo) Get(i *c.Int) c.Int {
+	return 0
+}
+// llgo:link (*Foo).Set C._ZN3Foo3setEOi
+func (recv_ *Foo) Set(v *c.Int) {
+}
+
+type Bar struct {
+}
+// llgo:link (*Bar).Reset C._ZN3Bar5resetEv
+func (recv_ *Bar) Reset() {
+}
+//go:linkname FooCount C.foo_count
+func FooCount() c.Int
+`
+	if strings.TrimSpace(expectedOutput) != strings.TrimSpace(buf.String()) {
+		t.Errorf("does not match expected.\nExpected:\n%s\nGot:\n%s", expectedOutput, buf.String())
+	}
+}
+
 func TestWritePkgFilesFail(t *testing.T) {
 	tempDir, err := os.MkdirTemp(dir, "test_package_write_unwritable")
 	if err != nil {
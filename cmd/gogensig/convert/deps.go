@@ -72,7 +72,7 @@ func (pm *PkgDepLoader) Imports(pkgPaths []string) (pkgs []*PkgInfo, err error)
 	for i, pkgPath := range pkgPaths {
 		pkgs[i], err = pm.Import(pkgPath)
 		if err != nil {
-			return nil, err
+			return nil, errs.NewDepImportError(i, pkgPath, err)
 		}
 	}
 	return
@@ -165,7 +165,7 @@ func (p *PkgInfo) GetIncPaths() ([]string, []string, error) {
 	}
 	expandedIncFlags := env.ExpandEnv(p.CppgConf.CFlags)
 	cflags := cfgparse.ParseCFlags(expandedIncFlags)
-	incPaths, notFounds, err := cflags.GenHeaderFilePaths(p.CppgConf.Include, syspath.GetIncludePaths())
+	incPaths, notFounds, err := cflags.GenPkgHeaderFilePaths(p.CppgConf, syspath.GetIncludePaths())
 	p.includes = incPaths
 	return incPaths, notFounds, err
 }
@@ -0,0 +1,226 @@
+/*
+This file maps the error conventions of llcppg.cfg to Go error types.
+*/
+package convert
+
+import (
+	goast "go/ast"
+	"go/token"
+	"go/types"
+	"log"
+	"path"
+	"sort"
+
+	"github.com/goplus/gogen"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+	cppgtypes "github.com/goplus/llcppg/types"
+)
+
+// errorType is a Go error type generated for an error convention.
+type errorType struct {
+	conv     *cppgtypes.ErrorConvention
+	decl     *gogen.TypeDecl
+	method   *gogen.Func // the Error method, its body is generated once the message function is known
+	file     *HeaderFile // the file where the type declaration is located
+	complete bool        // whether the body of the Error method is generated
+}
+
+// errorConvention returns the error convention of llcppg.cfg that matches the
+// C function name and fits the result of its signature.
+func (p *Package) errorConvention(name string, sig *types.Signature) *cppgtypes.ErrorConvention {
+	if p.CppgConf == nil || sig.Results().Len() == 0 {
+		return nil
+	}
+	for _, conv := range p.CppgConf.ErrorConventions {
+		if conv.Message == name || !matchName(conv.Funcs, name) {
+			continue
+		}
+		if !fitFailure(conv.Failure, sig.Results().At(0).Type()) {
+			if dbg.GetDebugError() {
+				log.Printf("errorConvention: result of %s doesn't fit failure %q\n", name, conv.Failure)
+			}
+			continue
+		}
+		return conv
+	}
+	return nil
+}
+
+func matchName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func fitFailure(failure string, ret types.Type) bool {
+	switch failure {
+	case cppgtypes.FailureNonZero, cppgtypes.FailureNegative:
+		basic, ok := ret.Underlying().(*types.Basic)
+		return ok && basic.Info()&types.IsInteger != 0
+	case cppgtypes.FailureNull:
+		if _, ok := ret.Underlying().(*types.Pointer); ok {
+			return true
+		}
+		basic, ok := ret.Underlying().(*types.Basic)
+		return ok && basic.Kind() == types.UnsafePointer
+	}
+	return false
+}
+
+func errorTypeName(conv *cppgtypes.ErrorConvention) string {
+	if conv.Type != "" {
+		return conv.Type
+	}
+	if conv.Failure == cppgtypes.FailureNull {
+		return "NullError"
+	}
+	return "Error"
+}
+
+// errorType returns the Go error type of conv, creating it in the current file
+// on first use. An error code type has the result type of the raw binding as
+// its underlying type, while a NULL failure is reported with a string type.
+func (p *Package) errorType(conv *cppgtypes.ErrorConvention, ret types.Type) types.Type {
+	name := errorTypeName(conv)
+	if p.errTypes == nil {
+		p.errTypes = make(map[string]*errorType)
+	}
+	if et, ok := p.errTypes[name]; ok {
+		if (et.conv.Failure == cppgtypes.FailureNull) != (conv.Failure == cppgtypes.FailureNull) {
+			log.Printf("errorType: %s is used by incompatible error conventions\n", name)
+			return nil
+		}
+		return et.decl.Type()
+	}
+	if obj := p.p.Types.Scope().Lookup(name); obj != nil {
+		log.Printf("errorType: %s already defined\n", name)
+		return nil
+	}
+	underlying := ret
+	doc := "// " + name + " is an error code reported by a function of " + p.conf.Name + "."
+	if conv.Failure == cppgtypes.FailureNull {
+		underlying = types.Typ[types.String]
+		doc = "// " + name + " reports a function of " + p.conf.Name + " returning NULL."
+	}
+	typeBlock := p.p.NewTypeDefs()
+	typeBlock.SetComments(&goast.CommentGroup{List: []*goast.Comment{{Text: doc}}})
+	decl := typeBlock.NewType(name)
+	decl.InitType(p.p, underlying)
+	recv := p.p.NewParam(token.NoPos, "e", decl.Type())
+	results := types.NewTuple(p.p.NewParam(token.NoPos, "", types.Typ[types.String]))
+	method := p.p.NewFunc(recv, "Error", nil, results, false)
+	et := &errorType{conv: conv, decl: decl, method: method, file: p.curFile}
+	p.errTypes[name] = et
+	if conv.Failure == cppgtypes.FailureNull {
+		p.genErrorMethod(et, nil)
+	} else if fn := p.messageFunc(conv, 1); fn != nil {
+		p.genErrorMethod(et, fn)
+	}
+	return decl.Type()
+}
+
+// messageFunc returns the declared Go function of the message function of conv,
+// if it takes nparams parameters and returns a C string.
+func (p *Package) messageFunc(conv *cppgtypes.ErrorConvention, nparams int) *types.Func {
+	if conv.Message == "" {
+		return nil
+	}
+	fnSpec, err := p.cvt.LookupSymbol(conv.Message)
+	if err != nil || fnSpec.IsMethod {
+		return nil
+	}
+	fn, ok := p.p.Types.Scope().Lookup(fnSpec.FnName).(*types.Func)
+	if !ok {
+		return nil
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Params().Len() != nparams || sig.Results().Len() != 1 || !isCString(sig.Results().At(0).Type()) {
+		if dbg.GetDebugError() {
+			log.Printf("messageFunc: %s is not a message function\n", conv.Message)
+		}
+		return nil
+	}
+	return fn
+}
+
+func isCString(typ types.Type) bool {
+	ptr, ok := typ.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	basic, ok := ptr.Elem().Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.Int8
+}
+
+// finishErrorTypes generates the Error method of error codes without a
+// declared message function, formatting the error code instead.
+func (p *Package) finishErrorTypes() {
+	for _, name := range p.pendingErrorTypes() {
+		p.genErrorMethod(p.errTypes[name], nil)
+	}
+}
+
+func (p *Package) pendingErrorTypes() []string {
+	var names []string
+	for name, et := range p.errTypes {
+		if !et.complete {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// genErrorMethod generates the body of the Error method of et:
+//
+//	func (e Error) Error() string {
+//		return c.GoString(Errstr(c.Int(e)))
+//	}
+func (p *Package) genErrorMethod(et *errorType, msgFn *types.Func) {
+	defer p.SetCurFile(p.curFile)
+	p.SetCurFile(et.file)
+	pkg := p.p
+	recv := et.method.Type().(*types.Signature).Recv()
+	cb := et.method.BodyStart(pkg)
+	switch {
+	case et.conv.Failure == cppgtypes.FailureNull:
+		cb.Typ(types.Typ[types.String]).Val(recv).Call(1)
+	case msgFn != nil:
+		paramType := msgFn.Type().(*types.Signature).Params().At(0).Type()
+		cb.Val(pkg.Import(cLibPath).Ref("GoString")).Val(msgFn).Typ(paramType).Val(recv).Call(1).Call(1).Call(1)
+	default:
+		itoa := pkg.Import("strconv").Ref("Itoa")
+		cb.Val(p.conf.Name + " error ").Val(itoa).Typ(types.Typ[types.Int]).Val(recv).Call(1).Call(1).BinaryOp(token.ADD)
+	}
+	cb.Return(1).End()
+	et.complete = true
+}
+
+// checkFailure generates the failure check of conv for the result of the raw binding:
+//
+//	if ret != 0 {
+//		err = Error(ret)
+//	}
+func (p *Package) checkFailure(cb *gogen.CodeBuilder, conv *cppgtypes.ErrorConvention, cname string, ret, errVar *types.Var, errType types.Type) {
+	cb.If().Val(ret)
+	switch conv.Failure {
+	case cppgtypes.FailureNonZero:
+		cb.Val(0).BinaryOp(token.NEQ)
+	case cppgtypes.FailureNegative:
+		cb.Val(0).BinaryOp(token.LSS)
+	case cppgtypes.FailureNull:
+		cb.CompareNil(token.EQL)
+	}
+	cb.Then().VarRef(errVar).Typ(errType)
+	if conv.Failure != cppgtypes.FailureNull {
+		cb.Val(ret)
+	} else if msgFn := p.messageFunc(conv, 0); msgFn != nil {
+		cb.Val(p.p.Import(cLibPath).Ref("GoString")).Val(msgFn).Call(0).Call(1)
+	} else {
+		cb.Val(cname + " returned NULL")
+	}
+	cb.Call(1).Assign(1).End()
+}
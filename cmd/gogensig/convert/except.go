@@ -0,0 +1,478 @@
+/*
+This file wraps the C++ functions listed in the exceptions of llcppg.cfg with
+extern "C" shims catching their exceptions, and generates Go wrappers returning
+a caught exception as an error. Calling a throwing C++ function through its raw
+binding aborts the process.
+*/
+package convert
+
+import (
+	"fmt"
+	goast "go/ast"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/gogen"
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+)
+
+const (
+	shimPrefix = "llcppg_exc_" // prefix of the C symbols of the shims, followed by the mangled name
+	shimDir    = "_wrap"       // directory of the shim source, ignored by the go tool
+)
+
+// cppShim is an extern "C" shim calling a C++ function.
+type cppShim struct {
+	header string // include path of the header declaring the function
+	src    string // C++ source of the shim
+	std    bool   // whether the shim uses the std:: marshaling helpers
+}
+
+// catchesExceptions reports whether the C++ function funcDecl is listed in the
+// exceptions of llcppg.cfg.
+func (p *Package) catchesExceptions(funcDecl *ast.FuncDecl) bool {
+	if p.CppgConf == nil || !p.CppgConf.Cplusplus {
+		return false
+	}
+	return matchName(p.CppgConf.Exceptions, cppName(funcDecl.Parent, funcDecl.Name.Name))
+}
+
+// cppName returns the qualified C++ name of name in parent, like ns::Foo::bar.
+func cppName(parent ast.Expr, name string) string {
+	if scope := cppScope(parent); scope != "" {
+		return scope + "::" + name
+	}
+	return name
+}
+
+func cppScope(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.ScopingExpr:
+		return cppName(expr.Parent, cppScope(expr.X))
+	}
+	return ""
+}
+
+// genExceptionWrapper generates a wrapper calling the shim of funcDecl, which
+// returns the what() text of a caught exception through its last parameter:
+//
+//	//go:linkname exc_Foo_Get C.llcppg_exc__ZN3Foo3getEi
+//	func exc_Foo_Get(self *Foo, i c.Int, exc **int8) c.Int
+//
+//	// GetErr wraps Get and returns a C++ exception thrown by it as an error.
+//	func (recv_ *Foo) GetErr(i c.Int) (ret c.Int, err error) {
+//		var exc *int8
+//		ret = exc_Foo_Get(recv_, i, &exc)
+//		if exc != nil {
+//			err = errors.New(c.GoString(exc))
+//			c.Free(unsafe.Pointer(exc))
+//		}
+//		return
+//	}
+func (p *Package) genExceptionWrapper(fnSpec *GoFuncSpec, sig *types.Signature, funcDecl *ast.FuncDecl) {
+	pkg := p.p
+	name := fnSpec.FnName + errWrapperSuffix
+	if p.wrapperIsDefined(sig, name) {
+		if dbg.GetDebugError() {
+			log.Printf("genExceptionWrapper: %s already defined\n", name)
+		}
+		return
+	}
+	shim := shimPrefix + funcDecl.MangledName
+	src, err := shimSource(funcDecl, shim, p.isMemberFunc(funcDecl))
+	if err != nil {
+		log.Printf("genExceptionWrapper: can't wrap %s: %s\n", funcDecl.Name.Name, err.Error())
+		return
+	}
+	binding := p.declareShim(shim, fnSpec, sig)
+	if binding == nil {
+		return
+	}
+	p.shims = append(p.shims, &cppShim{header: p.curFile.IncPath, src: src})
+
+	var ret *types.Var
+	var results []*types.Var
+	if sig.Results().Len() > 0 {
+		ret = pkg.NewParam(token.NoPos, uniqueParamName("ret", sig.Params()), sig.Results().At(0).Type())
+		results = append(results, ret)
+	}
+	errVar := pkg.NewParam(token.NoPos, uniqueParamName("err", sig.Params()), types.Universe.Lookup("error").Type())
+	results = append(results, errVar)
+	args := namedParams(pkg, sig.Params())
+	var recv *gogen.Param
+	if sig.Recv() != nil {
+		recv = pkg.NewParam(token.NoPos, sig.Recv().Name(), sig.Recv().Type())
+	}
+	fn := pkg.NewFunc(recv, name, types.NewTuple(args...), types.NewTuple(results...), false)
+	fn.SetComments(pkg, &goast.CommentGroup{List: []*goast.Comment{
+		{Text: "// " + name + " wraps " + fnSpec.FnName + " and returns a C++ exception thrown by it as an error."},
+	}})
+
+	cb := fn.BodyStart(pkg)
+	excName := uniqueParamName("exc", sig.Params())
+	cb.NewVar(types.NewPointer(types.Typ[types.Int8]), excName)
+	exc := cb.Scope().Lookup(excName)
+	if ret != nil {
+		cb.VarRef(ret)
+	}
+	cb.Val(binding)
+	nargs := len(args) + 1
+	if recv != nil {
+		cb.Val(recv)
+		nargs++
+	}
+	for _, arg := range args {
+		cb.Val(arg)
+	}
+	cb.VarRef(exc).UnaryOp(token.AND).Call(nargs)
+	if ret != nil {
+		cb.Assign(1)
+	} else {
+		cb.EndStmt()
+	}
+	clib := pkg.Import(cLibPath)
+	cb.If().Val(exc).CompareNil(token.NEQ).Then().
+		VarRef(errVar).Val(pkg.Import("errors").Ref("New")).Val(clib.Ref("GoString")).Val(exc).Call(1).Call(1).Assign(1).
+		Val(clib.Ref("Free")).Typ(types.Typ[types.UnsafePointer]).Val(exc).Call(1).Call(1).EndStmt().
+		End()
+	cb.Return(0).End()
+}
+
+// declareShim declares the raw binding of the shim of a C++ function. It takes
+// the receiver of sig, if any, as its first parameter and the address of the
+// exception text as its last one.
+func (p *Package) declareShim(shim string, fnSpec *GoFuncSpec, sig *types.Signature) *types.Func {
+	pkg := p.p
+	name := "exc_" + fnSpec.FnName
+	var params []*types.Var
+	if recv := sig.Recv(); recv != nil {
+		name = "exc_" + getNamedType(recv.Type()).Obj().Name() + "_" + fnSpec.FnName
+		params = append(params, pkg.NewParam(token.NoPos, "self", recv.Type()))
+	}
+	if obj := pkg.Types.Scope().Lookup(name); obj != nil {
+		log.Printf("declareShim: %s already defined\n", name)
+		return nil
+	}
+	for _, param := range namedParams(pkg, sig.Params()) {
+		params = append(params, pkg.NewParam(token.NoPos, param.Name(), param.Type()))
+	}
+	params = append(params, pkg.NewParam(token.NoPos, uniqueParamName("exc", sig.Params()), types.NewPointer(types.NewPointer(types.Typ[types.Int8]))))
+	decl := pkg.NewFuncDecl(token.NoPos, name, types.NewSignatureType(nil, nil, nil, types.NewTuple(params...), sig.Results(), false))
+	decl.SetComments(pkg, NewFuncDocComments(shim, name))
+	return decl.Func
+}
+
+// shimSource returns the C++ source of the shim of funcDecl, whose first
+// parameter is this if member is true:
+//
+//	extern "C" int llcppg_exc__ZN3Foo3getEi(Foo *self, int a0, char **exc) {
+//		try {
+//			return self->get(a0);
+//		} catch (const std::exception &e) {
+//			*exc = strdup(e.what());
+//		} catch (...) {
+//			*exc = strdup("unknown C++ exception");
+//		}
+//		return {};
+//	}
+//
+// A reference result is returned as a pointer. A result of a named type, like a
+// class, is returned by value, which requires it to be trivially copyable to
+// pass through extern "C", and default constructible to be returned after a
+// caught exception. Both are checked by a static_assert in the shim.
+func shimSource(funcDecl *ast.FuncDecl, shim string, member bool) (string, error) {
+	var params, args []string
+	for i, field := range funcDecl.Type.Params.List {
+		if _, ok := field.Type.(*ast.Variadic); ok {
+			return "", fmt.Errorf("variadic parameters can't be forwarded")
+		}
+		name := "self"
+		if !member || i > 0 {
+			name = "a" + strconv.Itoa(len(args))
+			arg := name
+			if _, ok := field.Type.(*ast.RvalueRefType); ok {
+				arg = "std::move(" + name + ")"
+			}
+			args = append(args, arg)
+		}
+		param, err := cppDecl(field.Type, name)
+		if err != nil {
+			return "", err
+		}
+		params = append(params, param)
+	}
+	params = append(params, "char **exc")
+
+	call, ret := shimCall(funcDecl, member, args)
+	switch t := ret.(type) {
+	case *ast.LvalueRefType:
+		ret, call = &ast.PointerType{X: t.X}, "&"+call
+	case *ast.RvalueRefType:
+		return "", fmt.Errorf("rvalue reference result is not supported")
+	}
+	void := ret == nil || Expr(ret).IsVoid()
+	if void {
+		ret = &ast.BuiltinType{Kind: ast.Void}
+	}
+	decl, err := cppDecl(ret, shim+"("+strings.Join(params, ", ")+")")
+	if err != nil {
+		return "", err
+	}
+	stmt := call + ";"
+	if void {
+		return shimBody(decl, []string{stmt}, true, nil)
+	}
+	return shimBody(decl, []string{"return " + stmt}, true, ret)
+}
+
+// shimCall returns the call of funcDecl with args in its shim, whose first
+// parameter is self if member is true, and the type of the result. A
+// constructor constructs the object in place and has no result.
+func shimCall(funcDecl *ast.FuncDecl, member bool, args []string) (call string, ret ast.Expr) {
+	ret = funcDecl.Type.Ret
+	call = cppName(funcDecl.Parent, funcDecl.Name.Name)
+	switch {
+	case funcDecl.IsConstructor:
+		ret = nil
+		call = "::new (self) " + cppScope(funcDecl.Parent)
+	case member:
+		call = "self->" + funcDecl.Name.Name
+	}
+	return call + "(" + strings.Join(args, ", ") + ")", ret
+}
+
+// shimBody returns the definition of the shim declared by decl running stmts.
+// If catch is true, they run in a try block storing the what() text of a
+// caught exception to *exc, and the shim returns a value-initialized result of
+// the type ret after the block if ret is not nil.
+func shimBody(decl string, stmts []string, catch bool, ret ast.Expr) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "extern \"C\" %s {\n", decl)
+	if catch && ret != nil && !isScalar(ret) {
+		typ, err := cppDecl(ret, "")
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "\tstatic_assert(std::is_trivially_copyable<%s>::value && std::is_default_constructible<%s>::value,\n", typ, typ)
+		fmt.Fprintf(&b, "\t\t%s);\n", strconv.Quote(typ+" can't be returned by value from the shim"))
+	}
+	indent := "\t"
+	if catch {
+		b.WriteString("\ttry {\n")
+		indent = "\t\t"
+	}
+	for _, stmt := range stmts {
+		b.WriteString(indent + stmt + "\n")
+	}
+	if catch {
+		b.WriteString("\t} catch (const std::exception &e) {\n\t\t*exc = strdup(e.what());\n")
+		b.WriteString("\t} catch (...) {\n\t\t*exc = strdup(\"unknown C++ exception\");\n\t}\n")
+		if ret != nil {
+			b.WriteString("\treturn {};\n")
+		}
+	}
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// isScalar reports whether a value of the C++ type typ is a pointer or of a
+// builtin type, which can always be value-initialized and passed through
+// extern "C".
+func isScalar(typ ast.Expr) bool {
+	if t, ok := typ.(*ast.QualifiedType); ok {
+		typ = t.X
+	}
+	switch typ.(type) {
+	case *ast.PointerType, *ast.BuiltinType:
+		return true
+	}
+	return false
+}
+
+// cppDecl returns the C++ declaration of name with the type typ, like
+// int (*name)(int). If name is empty, it returns the spelling of typ.
+func cppDecl(typ ast.Expr, name string) (string, error) {
+	join := func(base string) (string, error) {
+		if name == "" {
+			return base, nil
+		}
+		return base + " " + name, nil
+	}
+	switch t := typ.(type) {
+	case *ast.BuiltinType:
+		base, err := cppBuiltin(t)
+		if err != nil {
+			return "", err
+		}
+		return join(base)
+	case *ast.Ident, *ast.ScopingExpr:
+		return join(cppScope(t))
+	case *ast.TagExpr:
+		return join(cppScope(t.Name))
+	case *ast.InstantiationType:
+		var args []string
+		for _, field := range t.Args.List {
+			arg, err := cppDecl(field.Type, "")
+			if err != nil {
+				return "", err
+			}
+			args = append(args, arg)
+		}
+		base, err := cppDecl(t.Template, "")
+		if err != nil {
+			return "", err
+		}
+		return join(base + "<" + strings.Join(args, ", ") + ">")
+	case *ast.QualifiedType:
+		if t.Qualifiers&ast.Volatile != 0 {
+			name = "volatile " + name
+		}
+		if t.Qualifiers&ast.Const != 0 {
+			name = "const " + name
+		}
+		return cppDecl(t.X, strings.TrimSuffix(name, " "))
+	case *ast.PointerType:
+		return cppDecl(t.X, declarator(t.X, "*"+name))
+	case *ast.LvalueRefType:
+		return cppDecl(t.X, declarator(t.X, "&"+name))
+	case *ast.RvalueRefType:
+		return cppDecl(t.X, declarator(t.X, "&&"+name))
+	case *ast.ArrayType:
+		n := ""
+		if t.Len != nil {
+			lit, ok := t.Len.(*ast.BasicLit)
+			if !ok {
+				return "", fmt.Errorf("unsupported array length %T", t.Len)
+			}
+			n = lit.Value
+		}
+		return cppDecl(t.Elt, name+"["+n+"]")
+	case *ast.FuncType:
+		var params []string
+		if t.Params != nil {
+			for _, field := range t.Params.List {
+				if _, ok := field.Type.(*ast.Variadic); ok {
+					params = append(params, "...")
+					continue
+				}
+				param, err := cppDecl(field.Type, "")
+				if err != nil {
+					return "", err
+				}
+				params = append(params, param)
+			}
+		}
+		return cppDecl(t.Ret, name+"("+strings.Join(params, ", ")+")")
+	}
+	return "", fmt.Errorf("unsupported type %T", typ)
+}
+
+// declarator parenthesizes the declarator of a pointer or reference to an
+// array or a function, like (*name) of int (*name)[4].
+func declarator(elem ast.Expr, name string) string {
+	switch elem.(type) {
+	case *ast.ArrayType, *ast.FuncType:
+		return "(" + name + ")"
+	}
+	return name
+}
+
+func cppBuiltin(t *ast.BuiltinType) (string, error) {
+	unsigned := ""
+	if t.Flags&ast.Unsigned != 0 {
+		unsigned = "unsigned "
+	}
+	switch t.Kind {
+	case ast.Void:
+		return "void", nil
+	case ast.Bool:
+		return "bool", nil
+	case ast.Char:
+		return unsigned + "char", nil
+	case ast.Char16:
+		return "char16_t", nil
+	case ast.Char32:
+		return "char32_t", nil
+	case ast.WChar:
+		return "wchar_t", nil
+	case ast.Int:
+		switch {
+		case t.Flags&ast.Short != 0:
+			return unsigned + "short", nil
+		case t.Flags&ast.LongLong != 0:
+			return unsigned + "long long", nil
+		case t.Flags&ast.Long != 0:
+			return unsigned + "long", nil
+		}
+		return unsigned + "int", nil
+	case ast.Int128:
+		return unsigned + "__int128", nil
+	case ast.Float:
+		switch {
+		case t.Flags&ast.Long != 0:
+			return "long double", nil
+		case t.Flags&ast.Double != 0:
+			return "double", nil
+		}
+		return "float", nil
+	case ast.Float16:
+		return "_Float16", nil
+	case ast.Float128:
+		return "__float128", nil
+	}
+	return "", fmt.Errorf("unsupported builtin type %d", t.Kind)
+}
+
+// linkShims writes the source of the shims to _wrap/{name}_autogen.cpp and
+// lists it in LLGoFiles, so that it's compiled with the package using the cflags
+// of llcppg.cfg.
+func (p *Package) linkShims() error {
+	if len(p.shims) == 0 {
+		return nil
+	}
+	fileName := path.Join(shimDir, p.conf.Name+"_autogen.cpp")
+	if err := os.MkdirAll(filepath.Join(p.GetOutputDir(), shimDir), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(p.GetOutputDir(), fileName), []byte(p.shimFile()), 0644); err != nil {
+		return err
+	}
+	files := fileName
+	if p.CppgConf.CFlags != "" {
+		files = p.CppgConf.CFlags + ": " + fileName
+	}
+	p.p.CB().NewConstStart(types.Typ[types.String], "LLGoFiles").Val(files).EndInit(1)
+	return nil
+}
+
+// shimFile returns the C++ source file of the shims.
+func (p *Package) shimFile() string {
+	var b strings.Builder
+	b.WriteString("// Code generated by gogensig. DO NOT EDIT.\n\n")
+	b.WriteString("#include <cstring>\n#include <exception>\n#include <new>\n#include <type_traits>\n#include <utility>\n")
+	var headers []string
+	std := false
+	for _, shim := range p.shims {
+		if !contains(headers, shim.header) {
+			headers = append(headers, shim.header)
+			fmt.Fprintf(&b, "#include <%s>\n", shim.header)
+		}
+		std = std || shim.std
+	}
+	if std {
+		b.WriteString("\n" + stdRuntime)
+	}
+	for _, shim := range p.shims {
+		b.WriteString("\n" + shim.src)
+	}
+	return b.String()
+}
@@ -57,7 +57,7 @@ func (p *ExprWrap) ToChar() (int8, error) {
 }
 
 func (p *ExprWrap) IsVoid() bool {
-	retType, ok := p.e.(*ast.BuiltinType)
+	retType, ok := ast.Unqualified(p.e).(*ast.BuiltinType)
 	if ok && retType.Kind == ast.Void {
 		return true
 	}
@@ -0,0 +1,54 @@
+package convert
+
+import (
+	"log"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+	cppgtypes "github.com/goplus/llcppg/types"
+)
+
+// filters returns the filters of llcppg.cfg, which select the declarations
+// converted like llcppsymg and llcppsigfetch do.
+func (p *AstConvert) filters() *cppgtypes.Filters {
+	if p.Pkg.CppgConf == nil {
+		return nil
+	}
+	return p.Pkg.CppgConf.Filters
+}
+
+// inPkgFile reports whether the current file is a file of the package, whose
+// declarations are filtered. The declarations of system and dependency headers
+// are always visited, so the types they declare, like size_t and FILE, are
+// known when the declarations of the package refer to them.
+func (p *AstConvert) inPkgFile() bool {
+	file := p.Pkg.curFile
+	return !file.IsSys && (file.InCurPkg || !file.IsHeaderFile)
+}
+
+// symbolExcluded reports whether the function or variable name in the scope
+// parent is excluded by the symbol filter.
+func (p *AstConvert) symbolExcluded(parent ast.Expr, name *ast.Ident) bool {
+	return p.skipFile || p.inPkgFile() && name != nil && p.excluded("symbol", cppName(parent, name.Name), p.filters().Symbol)
+}
+
+// typeExcluded reports whether the type name in the scope parent is excluded
+// by the type filter. Anonymous types are never excluded.
+func (p *AstConvert) typeExcluded(parent ast.Expr, name *ast.Ident) bool {
+	return p.skipFile || p.inPkgFile() && name != nil && p.excluded("type", cppName(parent, name.Name), p.filters().Type)
+}
+
+// macroExcluded reports whether the macro name is excluded by the macro filter.
+func (p *AstConvert) macroExcluded(name string) bool {
+	return p.skipFile || p.inPkgFile() && p.excluded("macro", name, p.filters().Macro)
+}
+
+func (p *AstConvert) excluded(kind, name string, selected func(string) bool) bool {
+	if selected(name) {
+		return false
+	}
+	if dbg.GetDebugLog() {
+		log.Printf("%s %s is filtered out\n", kind, name)
+	}
+	return true
+}
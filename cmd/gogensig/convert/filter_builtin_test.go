@@ -0,0 +1,136 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goplus/llcppg/ast"
+	ctoken "github.com/goplus/llcppg/token"
+	cppgtypes "github.com/goplus/llcppg/types"
+)
+
+// newFilterTestConvert returns a converter of the package headers pkgFiles,
+// which are set without searching the include paths.
+func newFilterTestConvert(t *testing.T, filters *cppgtypes.Filters, pkgFiles ...string) *AstConvert {
+	converter, err := NewAstConvert(&AstConvertConfig{
+		PkgName:  "test",
+		SymbFile: "",
+		CfgFile:  "",
+	})
+	if err != nil {
+		t.Fatal("NewAstConvert Fail")
+	}
+	converter.Pkg.CppgConf.Filters = filters
+	converter.Pkg.includes = pkgFiles
+	return converter
+}
+
+func TestVisitFilters(t *testing.T) {
+	converter := newFilterTestConvert(t, &cppgtypes.Filters{
+		Symbols: &cppgtypes.Filter{Exclude: []string{"*_internal", "/^ns::Foo::/"}},
+		Types:   &cppgtypes.Filter{Include: []string{"Keep*", "ns::*"}},
+		Macros:  &cppgtypes.Filter{Exclude: []string{"/^_/"}},
+		Headers: &cppgtypes.Filter{Exclude: []string{"foo/internal.h"}},
+	}, "/usr/include/foo/internal.h")
+	integer := &ast.BuiltinType{Kind: ast.Int}
+	typedef := func(name string, parent ast.Expr) {
+		converter.VisitTypedefDecl(&ast.TypedefDecl{
+			DeclBase: ast.DeclBase{Parent: parent},
+			Name:     &ast.Ident{Name: name},
+			Type:     integer,
+		})
+	}
+	varDecl := func(name string, parent ast.Expr) {
+		converter.VisitVarDecl(&ast.VarDecl{
+			DeclBase: ast.DeclBase{Parent: parent},
+			Name:     &ast.Ident{Name: name},
+			Type:     integer,
+			Value:    &ast.BasicLit{Kind: ast.IntLit, Value: "1"},
+		})
+	}
+	macro := func(name string) {
+		converter.VisitMacro(&ast.Macro{
+			Name: name,
+			Tokens: []*ast.Token{
+				{Token: ctoken.IDENT, Lit: name},
+				{Token: ctoken.LITERAL, Lit: "1"},
+			},
+		})
+	}
+	ns := &ast.Ident{Name: "ns"}
+	typedef("KeepInt", nil)
+	typedef("DropInt", nil)
+	typedef("Size", ns)
+	varDecl("version", nil)
+	varDecl("state_internal", nil)
+	varDecl("count", &ast.ScopingExpr{Parent: ns, X: &ast.Ident{Name: "Foo"}})
+	macro("VERSION")
+	macro("_GUARD_H")
+
+	// declarations of an excluded header are not converted
+	converter.VisitStart("/usr/include/foo/internal.h", "foo/internal.h", false)
+	typedef("KeepHidden", nil)
+	macro("HIDDEN")
+
+	buf, err := converter.Pkg.WriteDefaultFileToBuffer()
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	for _, name := range []string{"KeepInt", "Size", "Version", "VERSION"} {
+		if !strings.Contains(buf.String(), name) {
+			t.Errorf("%s is filtered out:\n%s", name, buf.String())
+		}
+	}
+	for _, name := range []string{"DropInt", "State_internal", "Count", "_GUARD_H", "KeepHidden", "HIDDEN"} {
+		if strings.Contains(buf.String(), name) {
+			t.Errorf("%s is not filtered out:\n%s", name, buf.String())
+		}
+	}
+}
+
+// The filters select the declarations of the package headers only, the types
+// of system headers are still known when the package refers to them.
+func TestVisitFiltersSysTypes(t *testing.T) {
+	converter := newFilterTestConvert(t, &cppgtypes.Filters{
+		Types:   &cppgtypes.Filter{Include: []string{"foo_*"}},
+		Headers: &cppgtypes.Filter{Exclude: []string{"stddef.h"}},
+	}, "/path/to/foo.h")
+
+	// typedef unsigned long size_t;
+	converter.VisitStart("/usr/include/stddef.h", "stddef.h", true)
+	converter.VisitTypedefDecl(&ast.TypedefDecl{
+		DeclBase: ast.DeclBase{Loc: &ast.Location{File: "/usr/include/stddef.h"}},
+		Name:     &ast.Ident{Name: "size_t"},
+		Type:     &ast.BuiltinType{Kind: ast.Int, Flags: ast.Unsigned | ast.Long},
+	})
+
+	// struct foo_buf { size_t len; };
+	converter.VisitStart("/path/to/foo.h", "foo.h", false)
+	converter.VisitStruct(&ast.Ident{Name: "foo_buf"}, nil, &ast.TypeDecl{
+		DeclBase: ast.DeclBase{Loc: &ast.Location{File: "/path/to/foo.h"}},
+		Name:     &ast.Ident{Name: "foo_buf"},
+		Type: &ast.RecordType{
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{List: []*ast.Field{
+				{Names: []*ast.Ident{{Name: "len"}}, Type: &ast.Ident{Name: "size_t"}},
+			}},
+		},
+	})
+	buf, err := converter.Pkg.WriteToBuffer("foo.go")
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	expectedOutput :=
+		`
+package test
+
+import _ "unsafe"
+
+type FooBuf struct {
+	Len uintptr
+}
+`
+	if strings.TrimSpace(expectedOutput) != strings.TrimSpace(buf.String()) {
+		t.Errorf("does not match expected.\nExpected:\n%s\nGot:\n%s", expectedOutput, buf.String())
+	}
+}
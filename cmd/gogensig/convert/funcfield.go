@@ -0,0 +1,158 @@
+/*
+This file generates typed accessors of the function pointer fields of structs,
+which are converted to pointer-sized c.Pointer fields.
+*/
+package convert
+
+import (
+	goast "go/ast"
+	"go/token"
+	"go/types"
+	"log"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+)
+
+const funcFieldSuffix = "Func"
+
+// funcField is a function pointer field of a struct.
+type funcField struct {
+	named *types.Named // the struct type
+	field *types.Var   // the c.Pointer field
+	typ   types.Type   // the Go func type of the field, a signature or a named C func type
+	file  *HeaderFile
+}
+
+// addFuncFields records the function pointer fields of the struct type named,
+// which is converted from the fields of the C struct cname, if the struct is
+// listed in the funcFields of llcppg.cfg.
+func (p *Package) addFuncFields(cname string, named *types.Named, fields *ast.FieldList) {
+	if p.CppgConf == nil || !matchName(p.CppgConf.FuncFields, cname) {
+		return
+	}
+	structType, ok := named.Underlying().(*types.Struct)
+	if !ok || fields == nil {
+		return
+	}
+	// the fields are matched by name, as the Go struct may also have the fields
+	// of the bases and the padding of the layout, and has no static field
+	goFields := make(map[string]*types.Var, structType.NumFields())
+	for i := 0; i < structType.NumFields(); i++ {
+		v := structType.Field(i)
+		goFields[v.Name()] = v
+	}
+	for _, field := range fields.List {
+		if len(field.Names) == 0 || field.IsStatic {
+			continue
+		}
+		v := goFields[getFieldName(field.Names[0].Name)]
+		if v == nil {
+			if dbg.GetDebugLog() {
+				log.Printf("addFuncFields: field %s of %s not found in the Go struct\n", field.Names[0].Name, cname)
+			}
+			continue
+		}
+		if basic, ok := v.Type().Underlying().(*types.Basic); !ok || basic.Kind() != types.UnsafePointer {
+			continue
+		}
+		if typ := p.cvt.funcFieldType(field); typ != nil {
+			p.funcFields = append(p.funcFields, &funcField{named: named, field: v, typ: typ, file: p.curFile})
+		}
+	}
+}
+
+// genFuncFieldAccessors generates the accessors of the recorded function pointer
+// fields. A field of an anonymous function pointer type gets a named C func type
+// first. The accessors are generated after all functions are declared, so they
+// don't take the names of the raw bindings:
+//
+//	// OpsReadFunc is the C function type of the Read field of Ops.
+//	// llgo:type C
+//	type OpsReadFunc func(unsafe.Pointer, *int8, c.Int) c.Int
+//
+//	// ReadFunc returns the Read field of p.
+//	func (p *Ops) ReadFunc() OpsReadFunc {
+//		return *(*OpsReadFunc)(unsafe.Pointer(&p.Read))
+//	}
+//
+//	// SetReadFunc sets the Read field of p to fn.
+//	func (p *Ops) SetReadFunc(fn OpsReadFunc) {
+//		p.Read = *(*unsafe.Pointer)(unsafe.Pointer(&fn))
+//	}
+func (p *Package) genFuncFieldAccessors() {
+	defer p.SetCurFile(p.curFile)
+	for _, ff := range p.funcFields {
+		typeName := ff.named.Obj().Name()
+		getter := ff.field.Name() + funcFieldSuffix
+		setter := "Set" + getter
+		if p.isFieldOrMethod(ff.named, getter) || p.isFieldOrMethod(ff.named, setter) {
+			log.Printf("genFuncFieldAccessors: %s.%s or %s.%s already defined\n", typeName, getter, typeName, setter)
+			continue
+		}
+		p.SetCurFile(ff.file)
+		fnType := ff.typ
+		if sig, ok := fnType.(*types.Signature); ok {
+			fnType = p.funcFieldTypeDecl(typeName, ff.field.Name(), sig)
+			if fnType == nil {
+				continue
+			}
+		}
+		p.genFuncFieldGetter(ff, getter, fnType)
+		p.genFuncFieldSetter(ff, setter, fnType)
+	}
+}
+
+// funcFieldTypeDecl declares the named C func type of a field of an anonymous
+// function pointer type.
+func (p *Package) funcFieldTypeDecl(typeName, fieldName string, sig *types.Signature) types.Type {
+	name := typeName + fieldName + funcFieldSuffix
+	if obj := p.p.Types.Scope().Lookup(name); obj != nil {
+		log.Printf("genFuncFieldAccessors: %s already defined\n", name)
+		return nil
+	}
+	typeBlock := p.p.NewTypeDefs()
+	doc := &goast.CommentGroup{List: []*goast.Comment{
+		{Text: "// " + name + " is the C function type of the " + fieldName + " field of " + typeName + "."},
+		{Text: TYPEC},
+	}}
+	typeBlock.SetComments(doc)
+	decl := typeBlock.NewType(name)
+	decl.InitType(p.p, sig)
+	return decl.Type()
+}
+
+func (p *Package) genFuncFieldGetter(ff *funcField, name string, fnType types.Type) {
+	pkg := p.p
+	recv := pkg.NewParam(token.NoPos, "p", types.NewPointer(ff.named))
+	results := types.NewTuple(pkg.NewParam(token.NoPos, "", fnType))
+	fn := pkg.NewFunc(recv, name, nil, results, false)
+	fn.SetComments(pkg, lineComments(
+		name+" returns the "+ff.field.Name()+" field of p.",
+	))
+	unsafePtr := types.Typ[types.UnsafePointer]
+	fn.BodyStart(pkg).
+		Typ(types.NewPointer(fnType)).Typ(unsafePtr).Val(recv).MemberVal(ff.field.Name()).UnaryOp(token.AND).Call(1).Call(1).Elem().
+		Return(1).End()
+}
+
+func (p *Package) genFuncFieldSetter(ff *funcField, name string, fnType types.Type) {
+	pkg := p.p
+	recv := pkg.NewParam(token.NoPos, "p", types.NewPointer(ff.named))
+	param := pkg.NewParam(token.NoPos, "fn", fnType)
+	fn := pkg.NewFunc(recv, name, types.NewTuple(param), nil, false)
+	fn.SetComments(pkg, lineComments(
+		name+" sets the "+ff.field.Name()+" field of p to fn.",
+	))
+	unsafePtr := types.Typ[types.UnsafePointer]
+	fn.BodyStart(pkg).
+		Val(recv).MemberRef(ff.field.Name()).
+		Typ(types.NewPointer(unsafePtr)).Typ(unsafePtr).Val(param).UnaryOp(token.AND).Call(1).Call(1).Elem().
+		Assign(1).End()
+}
+
+// isFieldOrMethod reports whether name is a field or method of *named.
+func (p *Package) isFieldOrMethod(named *types.Named, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(named), true, p.p.Types, name)
+	return obj != nil
+}
@@ -0,0 +1,394 @@
+/*
+This file converts the base classes of C++ records. A single public non-virtual
+base is embedded in the Go struct of the derived record, so that its fields and
+methods are promoted. The other bases are stored in the gaps of the layout of the
+record and reached through upcasts, and their methods are forwarded by methods of
+the derived record.
+*/
+package convert
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"log"
+	"strings"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/convert/sizes"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+)
+
+const castPrefix = "llcppg_cast_" // prefix of the C symbols of the cast shims
+
+// derivedRecord is a C++ record with base classes.
+type derivedRecord struct {
+	named *types.Named // the Go type of the record
+	cname ast.Expr     // the C++ name of the record
+	bases []*baseClass
+	file  *HeaderFile
+}
+
+// baseClass is a base class of a derived record.
+type baseClass struct {
+	spec  *ast.BaseSpec
+	named *types.Named // the Go type of the base
+}
+
+// embeddedBase returns the base of a C++ record which is embedded in its Go
+// struct, or nil if there is none.
+func embeddedBase(recordType *ast.RecordType) *ast.BaseSpec {
+	if len(recordType.Bases) != 1 {
+		return nil
+	}
+	base := recordType.Bases[0]
+	if base.Virtual || base.Access != ast.Public || base.Offset < 0 {
+		return nil
+	}
+	return base
+}
+
+// baseFields returns the fields of the Go struct of a C++ record storing its
+// bases, followed by the fields of the record, and their byte offsets if the
+// record has a layout. A record with a layout only stores its embedded base, the
+// other bases are left to the blank fields filling the gaps of the layout. A
+// record with the natural layout of its fields stores its non-virtual bases in
+// declaration order.
+func (p *TypeConv) baseFields(recordType *ast.RecordType, fields []*types.Var) ([]*types.Var, []int64, error) {
+	layout := recordType.Layout
+	embed := embeddedBase(recordType)
+	var result []*types.Var
+	var offsets []int64
+	for _, base := range recordType.Bases {
+		if layout != nil && base != embed {
+			continue
+		}
+		if base.Virtual {
+			return nil, nil, fmt.Errorf("%w: virtual base of a record without layout", ErrLayout)
+		}
+		typ, err := p.ToType(base.Type)
+		if err != nil {
+			if layout != nil {
+				// the base is left to a blank field, and has no upcast
+				continue
+			}
+			return nil, nil, err
+		}
+		named, ok := typ.(*types.Named)
+		embedded := base == embed && ok && !hasField(fields, named.Obj().Name())
+		if layout != nil && (!embedded || !fitsLayout(typ, base.Offset, layout)) {
+			continue
+		}
+		name := "_"
+		if embedded {
+			name = named.Obj().Name()
+		}
+		result = append(result, types.NewField(token.NoPos, p.Types, name, typ, embedded))
+		offsets = append(offsets, base.Offset)
+	}
+	return result, offsets, nil
+}
+
+// fitsLayout reports whether the Go type typ can be stored at the byte offset of
+// layout, which isn't the case of a base whose tail padding is reused by the
+// fields of the derived record.
+func fitsLayout(typ types.Type, offset int64, layout *ast.RecordLayout) bool {
+	align := sizes.Alignof(typ)
+	if offset%align != 0 || align > layout.Align {
+		return false
+	}
+	end := offset + sizes.Sizeof(typ)
+	for _, off := range layout.Offsets {
+		if off >= 0 && off < end {
+			return false
+		}
+	}
+	return end <= layout.Size
+}
+
+func hasField(fields []*types.Var, name string) bool {
+	for _, field := range fields {
+		if field.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cppRecordName returns the C++ name of the record of typeDecl, qualified by
+// its scope.
+func cppRecordName(typeDecl *ast.TypeDecl) ast.Expr {
+	if typeDecl.Parent != nil {
+		return &ast.ScopingExpr{Parent: typeDecl.Parent, X: typeDecl.Name}
+	}
+	return typeDecl.Name
+}
+
+// addDerivedRecord records the bases of the C++ record of typeDecl, whose Go
+// type is named, and whether it is polymorphic, that is it has a virtual method
+// or a polymorphic base.
+func (p *Package) addDerivedRecord(named *types.Named, typeDecl *ast.TypeDecl) {
+	record := typeDecl.Type
+	polymorphic := false
+	for _, method := range record.Methods {
+		polymorphic = polymorphic || method.IsVirtual
+	}
+	derived := &derivedRecord{named: named, cname: cppRecordName(typeDecl), file: p.curFile}
+	for _, spec := range record.Bases {
+		typ, err := p.cvt.paramType(spec.Type)
+		base := getNamedType(typ)
+		if err != nil || base == nil {
+			if dbg.GetDebugError() {
+				log.Printf("addDerivedRecord: can't convert a base of %s\n", named.Obj().Name())
+			}
+			continue
+		}
+		polymorphic = polymorphic || p.polymorphic[base]
+		derived.bases = append(derived.bases, &baseClass{spec: spec, named: base})
+	}
+	if polymorphic {
+		if p.polymorphic == nil {
+			p.polymorphic = make(map[*types.Named]bool)
+		}
+		p.polymorphic[named] = true
+	}
+	if len(derived.bases) > 0 {
+		p.derivedRecords = append(p.derivedRecords, derived)
+	}
+}
+
+// genBaseClasses generates the conversions between the recorded derived records
+// and their public bases, and the methods forwarding the methods of the bases
+// which are not embedded:
+//
+//	// class C : public A, public B { int c; };
+//	type C struct {
+//		_ [4]byte
+//		_ [4]byte
+//		C c.Int
+//	}
+//
+//	// AsA returns the A base of p.
+//	func (p *C) AsA() *A {
+//		return (*A)(unsafe.Pointer(p))
+//	}
+//
+//	// AsB returns the B base of p.
+//	func (p *C) AsB() *B {
+//		return (*B)(unsafe.Add(unsafe.Pointer(p), 4))
+//	}
+//
+//	// GetB calls GetB on the B base of p.
+//	func (p *C) GetB() c.Int {
+//		return p.AsB().GetB()
+//	}
+//
+// The upcast to a virtual base calls a shim, because its offset depends on the
+// dynamic type of the object. A polymorphic base gets a downcast calling a shim
+// using dynamic_cast, like CFromA.
+func (p *Package) genBaseClasses() {
+	defer p.SetCurFile(p.curFile)
+	for _, derived := range p.derivedRecords {
+		p.SetCurFile(derived.file)
+		typeName := derived.named.Obj().Name()
+		var forwarded []*baseClass
+		for _, base := range derived.bases {
+			if base.spec.Access != ast.Public {
+				continue
+			}
+			upcast := upcastName(base)
+			if p.isFieldOrMethod(derived.named, upcast) {
+				log.Printf("genBaseClasses: %s.%s already defined\n", typeName, upcast)
+				continue
+			}
+			embedded := p.isEmbedded(derived.named, base.named)
+			if !p.genUpcast(derived, base, upcast, embedded) {
+				continue
+			}
+			if !embedded {
+				forwarded = append(forwarded, base)
+			}
+			if p.polymorphic[base.named] {
+				p.genDowncast(derived, base)
+			}
+		}
+		p.genForwarders(derived, forwarded)
+	}
+}
+
+func upcastName(base *baseClass) string {
+	return "As" + base.named.Obj().Name()
+}
+
+// isEmbedded reports whether base is embedded in the Go struct of named.
+func (p *Package) isEmbedded(named, base *types.Named) bool {
+	structType, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < structType.NumFields(); i++ {
+		field := structType.Field(i)
+		if field.Embedded() && types.Identical(field.Type(), base) {
+			return true
+		}
+	}
+	return false
+}
+
+// genUpcast generates the method converting a pointer to the derived record to a
+// pointer to its base.
+func (p *Package) genUpcast(derived *derivedRecord, base *baseClass, name string, embedded bool) bool {
+	pkg := p.p
+	recv := pkg.NewParam(token.NoPos, "p", types.NewPointer(derived.named))
+	ptr := types.NewPointer(base.named)
+	results := types.NewTuple(pkg.NewParam(token.NoPos, "", ptr))
+	doc := lineComments(name + " returns the " + base.named.Obj().Name() + " base of p.")
+	spec := base.spec
+	if !embedded && (spec.Virtual || spec.Offset < 0) {
+		shim := p.castShim(derived.cname, spec.Type, "return self;")
+		if shim == "" {
+			return false
+		}
+		sig := types.NewSignatureType(recv, nil, nil, nil, results, false)
+		decl := pkg.NewFuncDecl(token.NoPos, name, sig)
+		decl.BodyStart(pkg).Val(nil).Return(1).End()
+		doc.List = append(doc.List, NewFuncDocComments(shim, pubMethodName(recv.Type(), &GoFuncSpec{FnName: name, IsMethod: true, PtrRecv: true})).List...)
+		decl.SetComments(pkg, doc)
+		return true
+	}
+	fn := pkg.NewFunc(recv, name, nil, results, false)
+	fn.SetComments(pkg, doc)
+	cb := fn.BodyStart(pkg)
+	unsafePtr := types.Typ[types.UnsafePointer]
+	switch {
+	case embedded:
+		cb.Val(recv).MemberVal(base.named.Obj().Name()).UnaryOp(token.AND)
+	case spec.Offset == 0:
+		cb.Typ(ptr).Typ(unsafePtr).Val(recv).Call(1).Call(1)
+	default:
+		cb.Typ(ptr).Val(pkg.Unsafe().Ref("Add")).Typ(unsafePtr).Val(recv).Call(1).Val(int(spec.Offset)).Call(2).Call(1)
+	}
+	cb.Return(1).End()
+	return true
+}
+
+// genDowncast generates the function converting a pointer to a polymorphic base
+// to a pointer to the derived record with dynamic_cast:
+//
+//	// CFromA returns the C whose A base is p, or nil if p is not the base of a C.
+//	//go:linkname CFromA C.llcppg_cast_pkg_A_C
+//	func CFromA(p *A) *C
+func (p *Package) genDowncast(derived *derivedRecord, base *baseClass) {
+	pkg := p.p
+	typeName, baseName := derived.named.Obj().Name(), base.named.Obj().Name()
+	name := typeName + "From" + baseName
+	if obj := pkg.Types.Scope().Lookup(name); obj != nil {
+		log.Printf("genDowncast: %s already defined\n", name)
+		return
+	}
+	ptr, err := cppDecl(&ast.PointerType{X: derived.cname}, "")
+	if err != nil {
+		log.Printf("genDowncast: can't cast to %s: %s\n", typeName, err.Error())
+		return
+	}
+	shim := p.castShim(base.spec.Type, derived.cname, "return dynamic_cast<"+ptr+">(self);")
+	if shim == "" {
+		return
+	}
+	params := types.NewTuple(pkg.NewParam(token.NoPos, "p", types.NewPointer(base.named)))
+	results := types.NewTuple(pkg.NewParam(token.NoPos, "", types.NewPointer(derived.named)))
+	decl := pkg.NewFuncDecl(token.NoPos, name, types.NewSignatureType(nil, nil, nil, params, results, false))
+	doc := lineComments(name + " returns the " + typeName + " whose " + baseName + " base is p, or nil if p is not the base of a " + typeName + ".")
+	doc.List = append(doc.List, NewFuncDocComments(shim, name).List...)
+	decl.SetComments(pkg, doc)
+}
+
+// castShim adds the shim converting a pointer to the C++ record from to a
+// pointer to the C++ record to with stmt, and returns its C symbol, or "" if the
+// records can't be spelled in C++:
+//
+//	extern "C" A *llcppg_cast_pkg_D_A(D *self) {
+//		return self;
+//	}
+func (p *Package) castShim(from, to ast.Expr, stmt string) string {
+	shim := castPrefix + p.conf.Name + "_" + shimName(from) + "_" + shimName(to)
+	param, err := cppDecl(&ast.PointerType{X: from}, "self")
+	if err != nil {
+		log.Printf("castShim: %s\n", err.Error())
+		return ""
+	}
+	decl, err := cppDecl(&ast.PointerType{X: to}, shim+"("+param+")")
+	if err != nil {
+		log.Printf("castShim: %s\n", err.Error())
+		return ""
+	}
+	src, err := shimBody(decl, []string{stmt}, false, nil)
+	if err != nil {
+		log.Printf("castShim: %s\n", err.Error())
+		return ""
+	}
+	p.shims = append(p.shims, &cppShim{header: p.curFile.IncPath, src: src})
+	return shim
+}
+
+// shimName returns the C++ name of a record as a part of a C symbol, like
+// ns_Foo for ns::Foo.
+func shimName(expr ast.Expr) string {
+	name, err := cppDecl(expr, "")
+	if err != nil {
+		return ""
+	}
+	return strings.NewReplacer("::", "_", "<", "_", ">", "_", ", ", "_", " ", "_", "*", "P").Replace(name)
+}
+
+// genForwarders generates the methods of the derived record calling the methods
+// of its public bases which are not embedded. A method of more than one base is
+// ambiguous and is not forwarded, like a method overridden by the derived record.
+func (p *Package) genForwarders(derived *derivedRecord, bases []*baseClass) {
+	count := make(map[string]int)
+	methods := make([]*types.MethodSet, len(bases))
+	for i, base := range bases {
+		methods[i] = types.NewMethodSet(types.NewPointer(base.named))
+		for j := 0; j < methods[i].Len(); j++ {
+			count[methods[i].At(j).Obj().Name()]++
+		}
+	}
+	for i, base := range bases {
+		for j := 0; j < methods[i].Len(); j++ {
+			method := methods[i].At(j).Obj().(*types.Func)
+			name := method.Name()
+			if count[name] > 1 || !method.Exported() && method.Pkg() != p.p.Types {
+				continue
+			}
+			if p.isFieldOrMethod(derived.named, name) {
+				if dbg.GetDebugLog() {
+					log.Printf("genForwarders: %s.%s already defined\n", derived.named.Obj().Name(), name)
+				}
+				continue
+			}
+			p.genForwarder(derived, base, method)
+		}
+	}
+}
+
+func (p *Package) genForwarder(derived *derivedRecord, base *baseClass, method *types.Func) {
+	pkg := p.p
+	sig := method.Type().(*types.Signature)
+	params := namedParams(pkg, sig.Params())
+	recv := pkg.NewParam(token.NoPos, uniqueParamName("p", sig.Params()), types.NewPointer(derived.named))
+	fn := pkg.NewFunc(recv, method.Name(), types.NewTuple(params...), sig.Results(), sig.Variadic())
+	fn.SetComments(pkg, lineComments(
+		method.Name()+" calls "+method.Name()+" on the "+base.named.Obj().Name()+" base of "+recv.Name()+".",
+	))
+	cb := fn.BodyStart(pkg).Val(recv).MemberVal(upcastName(base)).Call(0).MemberVal(method.Name())
+	for _, param := range params {
+		cb.Val(param)
+	}
+	cb.Call(len(params), sig.Variadic())
+	if sig.Results().Len() > 0 {
+		cb.Return(1)
+	} else {
+		cb.EndStmt()
+	}
+	cb.End()
+}
@@ -0,0 +1,198 @@
+/*
+This file converts records whose layout differs from the natural layout of their
+fields, like records changed by #pragma pack, a packed or aligned attribute, or
+alignas. Go can't declare packed structs, so a field which is misaligned in Go is
+stored in a byte array and reached through accessors, and the gaps and the
+alignment of the record are filled with blank fields.
+*/
+package convert
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"log"
+	"strings"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/convert/sizes"
+)
+
+// layoutField is a field of a record which is misaligned in Go, so it is stored
+// in a byte array of the Go struct of the record.
+type layoutField struct {
+	record  string     // the Go name of the record
+	field   *types.Var // the field as it would be declared in Go
+	storage *types.Var // the byte array storing the field
+	file    *HeaderFile
+}
+
+// alignTypes are the types of the blank fields raising the alignment of a struct.
+var alignTypes = map[int64]types.Type{
+	2: types.Typ[types.Uint16],
+	4: types.Typ[types.Uint32],
+	8: types.Typ[types.Uint64],
+}
+
+// layoutStruct returns the Go struct of the fields of a record at the byte
+// offsets of layout, with the size and alignment of layout:
+//
+//	// #pragma pack(1)
+//	// struct hdr { char kind; int len; char pad[3]; } __attribute__((aligned(4)));
+//	type Hdr struct {
+//		_    [0]uint32
+//		Kind int8
+//		len  [4]byte
+//		Pad  [3]int8
+//	}
+//
+// It reports an error if the layout can't be matched, like a record aligned to
+// more than a Go type can be, or overlapping fields.
+func (p *TypeConv) layoutStruct(fields []*types.Var, offsets []int64, layout *ast.RecordLayout) (*types.Struct, error) {
+	if len(fields) != len(offsets) {
+		return nil, fmt.Errorf("%w: %d fields with %d offsets", ErrLayout, len(fields), len(offsets))
+	}
+	var result []*types.Var
+	var stored []*layoutField
+	var want []int64
+	var end, align int64 = 0, 1
+	add := func(field *types.Var, offset int64) {
+		result = append(result, field)
+		want = append(want, offset)
+	}
+	for i, field := range fields {
+		offset := offsets[i]
+		if offset < 0 {
+			// a static field takes no storage
+			continue
+		}
+		if offset < end {
+			return nil, fmt.Errorf("%w: field %s at offset %d overlaps the previous field", ErrLayout, field.Name(), offset)
+		}
+		if offset > end {
+			add(p.blankBytes(offset-end), end)
+		}
+		size, fieldAlign := sizes.Sizeof(field.Type()), sizes.Alignof(field.Type())
+		if offset%fieldAlign == 0 && fieldAlign <= layout.Align {
+			add(field, offset)
+			if fieldAlign > align {
+				align = fieldAlign
+			}
+		} else {
+			if p.record == nil {
+				return nil, fmt.Errorf("%w: misaligned field %s of an anonymous record", ErrLayout, field.Name())
+			}
+			storage := types.NewField(token.NoPos, p.Types, storageName(field.Name()), types.NewArray(types.Universe.Lookup("byte").Type(), size), false)
+			add(storage, offset)
+			stored = append(stored, &layoutField{field: field, storage: storage})
+		}
+		end = offset + size
+	}
+	if end > layout.Size {
+		return nil, fmt.Errorf("%w: fields end at offset %d beyond size %d", ErrLayout, end, layout.Size)
+	}
+	if end < layout.Size {
+		add(p.blankBytes(layout.Size-end), end)
+	}
+	if layout.Align > align {
+		typ, ok := alignTypes[layout.Align]
+		if !ok || sizes.Alignof(typ) != layout.Align {
+			return nil, fmt.Errorf("%w: alignment %d is not supported", ErrLayout, layout.Align)
+		}
+		result = append([]*types.Var{types.NewField(token.NoPos, p.Types, "_", types.NewArray(typ, 0), false)}, result...)
+		want = append([]int64{0}, want...)
+	}
+	st := types.NewStruct(result, nil)
+	if sizes.Sizeof(st) != layout.Size || sizes.Alignof(st) != layout.Align {
+		return nil, fmt.Errorf("%w: Go struct has size %d and alignment %d, want %d and %d",
+			ErrLayout, sizes.Sizeof(st), sizes.Alignof(st), layout.Size, layout.Align)
+	}
+	for i, offset := range sizes.Offsetsof(result) {
+		if offset != want[i] {
+			return nil, fmt.Errorf("%w: field %s at offset %d, want %d", ErrLayout, result[i].Name(), offset, want[i])
+		}
+	}
+	if len(stored) > 0 && p.conf.Package != nil {
+		p.conf.Package.addLayoutFields(p.record.name, stored)
+	}
+	return st, nil
+}
+
+func (p *TypeConv) blankBytes(n int64) *types.Var {
+	return types.NewField(token.NoPos, p.Types, "_", types.NewArray(types.Universe.Lookup("byte").Type(), n), false)
+}
+
+// storageName returns the unexported name of the byte array storing the field
+// name.
+func storageName(name string) string {
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// addLayoutFields records the misaligned fields of the record.
+func (p *Package) addLayoutFields(record string, fields []*layoutField) {
+	for _, lf := range fields {
+		lf.record, lf.file = record, p.curFile
+		p.layoutFields = append(p.layoutFields, lf)
+	}
+}
+
+// genLayoutAccessors generates the accessors of the recorded misaligned fields,
+// which copy the field from and to its byte array:
+//
+//	// Len returns the Len field of p.
+//	func (p *Hdr) Len() c.Int {
+//		return *(*c.Int)(unsafe.Pointer(&p.len))
+//	}
+//
+//	// SetLen sets the Len field of p to v.
+//	func (p *Hdr) SetLen(v c.Int) {
+//		*(*c.Int)(unsafe.Pointer(&p.len)) = v
+//	}
+func (p *Package) genLayoutAccessors() {
+	defer p.SetCurFile(p.curFile)
+	for _, lf := range p.layoutFields {
+		obj, ok := p.p.Types.Scope().Lookup(lf.record).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		getter := lf.field.Name()
+		setter := "Set" + getter
+		if p.isFieldOrMethod(named, getter) || p.isFieldOrMethod(named, setter) {
+			log.Printf("genLayoutAccessors: %s.%s or %s.%s already defined\n", lf.record, getter, lf.record, setter)
+			continue
+		}
+		p.SetCurFile(lf.file)
+		p.genLayoutGetter(named, lf, getter)
+		p.genLayoutSetter(named, lf, setter)
+	}
+}
+
+func (p *Package) genLayoutGetter(named *types.Named, lf *layoutField, name string) {
+	pkg := p.p
+	recv := pkg.NewParam(token.NoPos, "p", types.NewPointer(named))
+	results := types.NewTuple(pkg.NewParam(token.NoPos, "", lf.field.Type()))
+	fn := pkg.NewFunc(recv, name, nil, results, false)
+	fn.SetComments(pkg, lineComments(
+		name+" returns the "+lf.field.Name()+" field of p.",
+	))
+	fn.BodyStart(pkg).
+		Typ(types.NewPointer(lf.field.Type())).Typ(types.Typ[types.UnsafePointer]).Val(recv).MemberVal(lf.storage.Name()).UnaryOp(token.AND).Call(1).Call(1).Elem().
+		Return(1).End()
+}
+
+func (p *Package) genLayoutSetter(named *types.Named, lf *layoutField, name string) {
+	pkg := p.p
+	recv := pkg.NewParam(token.NoPos, "p", types.NewPointer(named))
+	param := pkg.NewParam(token.NoPos, "v", lf.field.Type())
+	fn := pkg.NewFunc(recv, name, types.NewTuple(param), nil, false)
+	fn.SetComments(pkg, lineComments(
+		name+" sets the "+lf.field.Name()+" field of p to v.",
+	))
+	fn.BodyStart(pkg).
+		Typ(types.NewPointer(lf.field.Type())).Typ(types.Typ[types.UnsafePointer]).Val(recv).MemberVal(lf.storage.Name()).UnaryOp(token.AND).Call(1).Call(1).ElemRef().
+		Val(param).Assign(1).End()
+}
@@ -0,0 +1,303 @@
+/*
+This file pairs constructors with destructors and generates owned handle types for them.
+*/
+package convert
+
+import (
+	goast "go/ast"
+	"go/token"
+	"go/types"
+	"log"
+	"strings"
+
+	"github.com/goplus/gogen"
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+	cppgtypes "github.com/goplus/llcppg/types"
+)
+
+const handleSuffix = "Handle"
+
+// declaredFunc is a raw binding declared in the package.
+type declaredFunc struct {
+	cname    string
+	funcDecl *ast.FuncDecl
+	fnSpec   *GoFuncSpec
+	sig      *types.Signature
+	decl     *gogen.Func
+	doc      *goast.CommentGroup
+	file     *HeaderFile
+	ctor     bool // C++ constructor
+	dtor     bool // C++ destructor
+}
+
+// ownedHandle is a generated handle type owning a pointer to a C type.
+type ownedHandle struct {
+	typ  types.Type
+	dtor *declaredFunc
+}
+
+func (p *Package) addDeclaredFunc(funcDecl *ast.FuncDecl, fnSpec *GoFuncSpec, sig *types.Signature, decl *gogen.Func, doc *goast.CommentGroup) {
+	p.declFuncs = append(p.declFuncs, &declaredFunc{
+		cname:    funcDecl.Name.Name,
+		funcDecl: funcDecl,
+		fnSpec:   fnSpec,
+		sig:      sig,
+		decl:     decl,
+		doc:      doc,
+		file:     p.curFile,
+		ctor:     funcDecl.IsConstructor,
+		dtor:     funcDecl.IsDestructor,
+	})
+}
+
+func (p *Package) lookupDeclaredFunc(cname string) *declaredFunc {
+	for _, fn := range p.declFuncs {
+		if fn.cname == cname {
+			return fn
+		}
+	}
+	return nil
+}
+
+// genLifecycles pairs the declared constructors with their destructors by the
+// explicit pairs and the name patterns of the lifecycle of llcppg.cfg, and C++
+// constructors with the destructor of their class. The default patterns are
+// only used if the lifecycle is configured without patterns. An owned handle is
+// generated for each pair.
+func (p *Package) genLifecycles() {
+	var patterns, pairs []*cppgtypes.LifecyclePair
+	finalizer := false
+	if conf := p.CppgConf.Lifecycle; conf != nil {
+		patterns = conf.Patterns
+		if len(patterns) == 0 {
+			patterns = cppgtypes.DefaultLifecyclePatterns
+		}
+		pairs = conf.Pairs
+		finalizer = conf.Finalizer
+	}
+	paired := make(map[*declaredFunc]bool)
+	for _, pair := range pairs {
+		ctor, dtor := p.lookupDeclaredFunc(pair.New), p.lookupDeclaredFunc(pair.Free)
+		if ctor == nil || dtor == nil {
+			log.Printf("genLifecycles: %s or %s is not declared\n", pair.New, pair.Free)
+			continue
+		}
+		p.genLifecycle(ctor, dtor, finalizer)
+		paired[ctor] = true
+	}
+	for _, ctor := range p.declFuncs {
+		if paired[ctor] {
+			continue
+		}
+		if ctor.ctor {
+			for _, dtor := range p.declFuncs {
+				if dtor.dtor && dtor.fnSpec.RecvName == ctor.fnSpec.RecvName {
+					p.genLifecycle(ctor, dtor, finalizer)
+				}
+			}
+			continue
+		}
+		for _, pattern := range patterns {
+			stem, ok := matchStem(pattern.New, ctor.cname)
+			if !ok {
+				continue
+			}
+			if dtor := p.lookupDeclaredFunc(strings.Replace(pattern.Free, "*", stem, 1)); dtor != nil {
+				p.genLifecycle(ctor, dtor, finalizer)
+				break
+			}
+		}
+	}
+}
+
+// matchStem matches name against a pattern with a single *, returning the part matched by *.
+func matchStem(pattern, name string) (string, bool) {
+	prefix, suffix, ok := strings.Cut(pattern, "*")
+	if !ok || len(name) <= len(prefix)+len(suffix) ||
+		!strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return "", false
+	}
+	return name[len(prefix) : len(name)-len(suffix)], true
+}
+
+// destroyedType returns the named type T of a destructor releasing a *T,
+// which is either its pointer receiver or its only parameter.
+func destroyedType(dtor *declaredFunc) *types.Named {
+	var typ types.Type
+	if recv := dtor.sig.Recv(); recv != nil && dtor.sig.Params().Len() == 0 {
+		typ = recv.Type()
+	} else if recv == nil && dtor.sig.Params().Len() == 1 {
+		typ = dtor.sig.Params().At(0).Type()
+	}
+	if ptr, ok := typ.(*types.Pointer); ok {
+		if named, ok := ptr.Elem().(*types.Named); ok {
+			return named
+		}
+	}
+	return nil
+}
+
+// genLifecycle generates the owned handle of the type released by dtor, and
+// the constructor of the handle with ctor:
+//
+//	// FooHandle owns a *Foo and releases it with foo_free on Close.
+//	type FooHandle struct {
+//		*Foo
+//	}
+//
+//	// NewFoo creates a Foo with foo_new.
+//	// The caller owns the returned handle and must release it with Close.
+//	func NewFoo() *FooHandle {
+//		ptr := FooNew()
+//		if ptr == nil {
+//			return nil
+//		}
+//		return &FooHandle{ptr}
+//	}
+func (p *Package) genLifecycle(ctor, dtor *declaredFunc, finalizer bool) {
+	named := destroyedType(dtor)
+	if named == nil {
+		if dbg.GetDebugError() {
+			log.Printf("genLifecycle: %s doesn't release a pointer\n", dtor.cname)
+		}
+		return
+	}
+	ptrType := types.NewPointer(named)
+	sig := ctor.sig
+	cstyle := sig.Recv() == nil && !ctor.ctor && sig.Results().Len() == 1 && types.Identical(sig.Results().At(0).Type(), ptrType)
+	cppstyle := ctor.ctor && sig.Recv() != nil && types.Identical(sig.Recv().Type(), ptrType)
+	if (!cstyle && !cppstyle) || sig.Variadic() {
+		if dbg.GetDebugError() {
+			log.Printf("genLifecycle: %s doesn't create a %s\n", ctor.cname, named.Obj().Name())
+		}
+		return
+	}
+	typeName := named.Obj().Name()
+	ctorName := "New" + typeName
+	if cppstyle {
+		// keep the suffix of overloaded constructors, eg. Init__1
+		ctorName += strings.TrimPrefix(ctor.fnSpec.FnName, "Init")
+	}
+	if obj := p.p.Types.Scope().Lookup(ctorName); obj != nil {
+		if dbg.GetDebugError() {
+			log.Printf("genLifecycle: %s already defined\n", ctorName)
+		}
+		return
+	}
+
+	defer p.SetCurFile(p.curFile)
+	p.SetCurFile(ctor.file)
+	handle := p.ownedHandle(named, dtor, finalizer)
+	if handle == nil {
+		return
+	}
+	pkg := p.p
+	params := namedParams(pkg, sig.Params())
+	handlePtr := types.NewPointer(handle.typ)
+	results := types.NewTuple(pkg.NewParam(token.NoPos, "", handlePtr))
+	fn := pkg.NewFunc(nil, ctorName, types.NewTuple(params...), results, false)
+	fn.SetComments(pkg, lineComments(
+		ctorName+" creates a "+typeName+" with "+ctor.cname+".",
+		"The caller owns the returned handle and must release it with Close.",
+	))
+	cb := fn.BodyStart(pkg)
+	if cstyle {
+		cb.DefineVarStart(token.NoPos, "ptr").Val(pkg.Types.Scope().Lookup(ctor.fnSpec.FnName))
+		for _, param := range params {
+			cb.Val(param)
+		}
+		cb.Call(len(params)).EndInit(1)
+		ptr := cb.Scope().Lookup("ptr")
+		cb.If().Val(ptr).CompareNil(token.EQL).Then().Val(nil).Return(1).End()
+		cb.DefineVarStart(token.NoPos, "h").Val(ptr).StructLit(handle.typ, 1, false).UnaryOp(token.AND).EndInit(1)
+	} else {
+		cb.DefineVarStart(token.NoPos, "h").Val(pkg.Builtin().Ref("new")).Typ(named).Call(1).StructLit(handle.typ, 1, false).UnaryOp(token.AND).EndInit(1)
+		h := cb.Scope().Lookup("h")
+		cb.Val(h).MemberVal(typeName).MemberVal(ctor.fnSpec.FnName)
+		for _, param := range params {
+			cb.Val(param)
+		}
+		cb.Call(len(params)).EndStmt()
+	}
+	h := cb.Scope().Lookup("h")
+	if finalizer {
+		cb.Val(pkg.Import("runtime").Ref("SetFinalizer")).Val(h).Typ(handlePtr).MemberVal("Close").Call(2).EndStmt()
+	}
+	cb.Val(h).Return(1).End()
+
+	p.addOwnershipDoc(ctor, "The result is owned by the caller and must be released with "+dtor.fnSpec.GoSymbName+".")
+}
+
+// ownedHandle returns the owned handle type of named, generating it and its
+// Close method in the current file on first use.
+func (p *Package) ownedHandle(named *types.Named, dtor *declaredFunc, finalizer bool) *ownedHandle {
+	typeName := named.Obj().Name()
+	if p.handles == nil {
+		p.handles = make(map[string]*ownedHandle)
+	}
+	if handle, ok := p.handles[typeName]; ok {
+		if handle.dtor != dtor {
+			log.Printf("genLifecycle: %s is released by both %s and %s\n", typeName, handle.dtor.cname, dtor.cname)
+			return nil
+		}
+		return handle
+	}
+	handleName := typeName + handleSuffix
+	if obj := p.p.Types.Scope().Lookup(handleName); obj != nil {
+		log.Printf("genLifecycle: %s already defined\n", handleName)
+		return nil
+	}
+	pkg := p.p
+	doc := handleName + " owns a *" + typeName + " and releases it with " + dtor.cname + " on Close"
+	if finalizer {
+		doc += " or when it becomes unreachable"
+	}
+	typeBlock := pkg.NewTypeDefs()
+	typeBlock.SetComments(lineComments(doc + "."))
+	decl := typeBlock.NewType(handleName)
+	ptrType := types.NewPointer(named)
+	decl.InitType(pkg, types.NewStruct([]*types.Var{
+		types.NewField(token.NoPos, pkg.Types, typeName, ptrType, true),
+	}, nil))
+	handle := &ownedHandle{typ: decl.Type(), dtor: dtor}
+	p.handles[typeName] = handle
+
+	// func (h *FooHandle) Close() {
+	// 	if h.Foo != nil {
+	// 		FooFree(h.Foo)
+	// 		h.Foo = nil
+	// 	}
+	// }
+	recv := pkg.NewParam(token.NoPos, "h", types.NewPointer(handle.typ))
+	fn := pkg.NewFunc(recv, "Close", nil, nil, false)
+	fn.SetComments(pkg, lineComments(
+		"Close releases the "+typeName+" with "+dtor.cname+".",
+		"It is safe to call Close more than once.",
+	))
+	cb := fn.BodyStart(pkg)
+	cb.If().Val(recv).MemberVal(typeName).CompareNil(token.NEQ).Then()
+	if dtor.sig.Recv() != nil {
+		cb.Val(recv).MemberVal(typeName).MemberVal(dtor.fnSpec.FnName).Call(0).EndStmt()
+	} else {
+		cb.Val(pkg.Types.Scope().Lookup(dtor.fnSpec.FnName)).Val(recv).MemberVal(typeName).Call(1).EndStmt()
+	}
+	cb.Val(recv).MemberRef(typeName).Val(nil).Assign(1)
+	if finalizer {
+		cb.Val(pkg.Import("runtime").Ref("SetFinalizer")).Val(recv).Val(nil).Call(2).EndStmt()
+	}
+	cb.End().End()
+
+	p.addOwnershipDoc(dtor, "It releases a "+typeName+" owned by a "+handleName+" on Close.")
+	return handle
+}
+
+// addOwnershipDoc adds the ownership information to the doc of a raw binding,
+// keeping the link directive as the last line.
+func (p *Package) addOwnershipDoc(fn *declaredFunc, txt string) {
+	list := fn.doc.List
+	directive := list[len(list)-1]
+	doc := &goast.CommentGroup{List: append(list[:len(list)-1:len(list)-1], &goast.Comment{Text: "// " + txt}, directive)}
+	fn.doc = doc
+	fn.decl.SetComments(p.p, doc)
+}
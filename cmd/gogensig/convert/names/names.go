@@ -49,6 +49,15 @@ func (m *NameMapper) GetGoName(name string, trimPrefixes []string) string {
 	return GoName(name, trimPrefixes)
 }
 
+// Lookup returns the Go name mapped for an original name, and whether it is mapped.
+func (m *NameMapper) Lookup(name string) (string, bool) {
+	goName, ok := m.mapping[name]
+	if ok && goName == "" {
+		goName = name
+	}
+	return goName, ok
+}
+
 func (m *NameMapper) SetMapping(originName, newName string) {
 	value := ""
 	if originName != newName {
@@ -3,9 +3,11 @@ package convert
 import (
 	"bytes"
 	"fmt"
+	"go/format"
 	"go/token"
 	"go/types"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 
@@ -49,8 +51,25 @@ type Package struct {
 	incompleteTypes *IncompleteTypes
 
 	nameMapper *names.NameMapper // handles name mapping and uniqueness
+
+	errTypes       map[string]*errorType         // error types of error conventions, keyed by Go name
+	declFuncs      []*declaredFunc               // declared raw bindings, in declaration order
+	handles        map[string]*ownedHandle       // owned handles, keyed by the Go name of the owned type
+	funcFields     []*funcField                  // function pointer fields of structs, in declaration order
+	unionMembers   []*unionMember                // anonymous members of unions not kept in their Go structs
+	layoutFields   []*layoutField                // misaligned fields of records stored in byte arrays
+	objComments    map[types.Object]*objComments // comments of struct fields and enum constants
+	records        map[string]string             // Go names of records, keyed by C names qualified by their outer records
+	consts         map[string]string             // Go names of integer constants, keyed by C names
+	arrayLens      map[*types.Array]string       // C names of the constants spelled as array lengths
+	shims          []*cppShim                    // extern "C" shims of C++ functions, in declaration order
+	std            *stdHelpers                   // Go helpers of the std:: shims, declared on first use
+	derivedRecords []*derivedRecord              // C++ records with base classes, in declaration order
+	polymorphic    map[*types.Named]bool         // C++ records with virtual methods
 }
 
+const cLibPath = "github.com/goplus/llgo/c"
+
 type PackageConfig struct {
 	PkgBase
 	Name        string // current package name
@@ -82,10 +101,11 @@ func NewPackage(config *PackageConfig) *Package {
 	pkgManager := NewPkgDepLoader(mod, p.p)
 	err = pkgManager.InitDeps(p.PkgInfo)
 	if err != nil {
-		log.Panicf("failed to init deps: %s", err.Error())
+		// an errs.DepImportError is reported by gogensig at its JSON path
+		panic(fmt.Errorf("failed to init deps: %w", err))
 	}
 
-	clib := p.p.Import("github.com/goplus/llgo/c")
+	clib := p.p.Import(cLibPath)
 	math := p.p.Import("math")
 	typeMap := NewBuiltinTypeMapWithPkgRefS(clib, math, p.p.Unsafe())
 	p.cvt = NewConv(&TypeConfig{
@@ -193,9 +213,10 @@ func (p *Package) handleFuncDecl(fnSpec *GoFuncSpec, sig *types.Signature, funcD
 		decl = p.p.NewFuncDecl(token.NoPos, fnPubName, sig)
 	}
 
-	doc := CommentGroup(funcDecl.Doc)
-	doc.AddCommentGroup(NewFuncDocComments(funcDecl.Name.Name, fnPubName))
+	doc := DeclCommentGroup(funcDecl.Doc, funcDecl.Attrs, funcDecl.Name.Name, nullabilityNotes(funcDecl.Type, sig)...)
+	doc.AddCommentGroup(NewFuncDocComments(p.linkName(funcDecl), fnPubName))
 	decl.SetComments(p.p, doc.CommentGroup)
+	p.addDeclaredFunc(funcDecl, fnSpec, sig, decl, doc.CommentGroup)
 	return nil
 }
 
@@ -237,33 +258,59 @@ func (p *Package) NewFuncDecl(funcDecl *ast.FuncDecl) error {
 	skip, anony, err := p.cvt.handleSysType(funcDecl.Name, funcDecl.Loc, p.curFile.IncPath)
 	if skip {
 		if dbg.GetDebugLog() {
-			log.Printf("NewFuncDecl: %v is a function of system header file\n", funcDecl.Name)
+			log.Printf("%s: NewFuncDecl: %v is a function of system header file\n", funcDecl.Loc, funcDecl.Name)
 		}
 		return err
 	}
 	if dbg.GetDebugLog() {
-		log.Printf("NewFuncDecl: %v\n", funcDecl.Name)
+		log.Printf("%s: NewFuncDecl: %v\n", funcDecl.Loc, funcDecl.Name)
 	}
 	if anony {
 		return errs.NewAnonymousFuncNotSupportError()
 	}
+	if why := skippedByAttrs(&funcDecl.DeclBase); why != "" {
+		if dbg.GetDebugLog() {
+			log.Printf("%s: NewFuncDecl: skip %v, %s\n", funcDecl.Loc, funcDecl.Name, why)
+		}
+		return nil
+	}
+
+	if p.isMemberFunc(funcDecl) {
+		funcDecl = withThis(funcDecl)
+	}
 
 	fnSpec, err := p.cvt.LookupSymbol(funcDecl.MangledName)
 	if err != nil {
 		// not gen the function not in the symbolmap
 		return err
 	}
+	if fnSpec.IsMethod && funcDecl.IsStatic && p.outerRecord(funcDecl.Parent) != "" {
+		// a static member function has no this to be the receiver
+		fnSpec = p.staticFuncSpec(fnSpec)
+	}
 
 	recv, err := p.funcIsDefined(fnSpec, funcDecl)
 	if err != nil {
 		return err
 	}
 
+	std, err := stdSignatureOf(funcDecl)
+	if err != nil {
+		return err
+	}
+	if std != nil {
+		return p.genStdFunc(fnSpec, funcDecl, recv, std)
+	}
+
 	sig, err := p.ToSigSignature(recv, funcDecl)
 	if err != nil {
 		return err
 	}
-	return p.handleFuncDecl(fnSpec, sig, funcDecl)
+	err = p.handleFuncDecl(fnSpec, sig, funcDecl)
+	if err != nil {
+		return err
+	}
+	return nil
 }
 
 func (p *Package) funcIsDefined(fnSpec *GoFuncSpec, funcDecl *ast.FuncDecl) (recv *types.Var, err error) {
@@ -294,12 +341,12 @@ func (p *Package) NewTypeDecl(typeDecl *ast.TypeDecl) error {
 	skip, anony, err := p.cvt.handleSysType(typeDecl.Name, typeDecl.Loc, p.curFile.IncPath)
 	if skip {
 		if dbg.GetDebugLog() {
-			log.Printf("NewTypeDecl: %s type of system header\n", typeDecl.Name)
+			log.Printf("%s: NewTypeDecl: %s type of system header\n", typeDecl.Loc, typeDecl.Name)
 		}
 		return err
 	}
 	if dbg.GetDebugLog() {
-		log.Printf("NewTypeDecl: %v\n", typeDecl.Name)
+		log.Printf("%s: NewTypeDecl: %v\n", typeDecl.Loc, typeDecl.Name)
 	}
 	if anony {
 		if dbg.GetDebugLog() {
@@ -307,8 +354,14 @@ func (p *Package) NewTypeDecl(typeDecl *ast.TypeDecl) error {
 		}
 		return nil
 	}
+	if why := skippedByAttrs(&typeDecl.DeclBase); why != "" {
+		if dbg.GetDebugLog() {
+			log.Printf("%s: NewTypeDecl: skip %v, %s\n", typeDecl.Loc, typeDecl.Name, why)
+		}
+		return nil
+	}
 
-	cname := typeDecl.Name.Name
+	cname := p.scopedName(typeDecl.Parent, typeDecl.Name.Name)
 	isForward := p.cvt.inComplete(typeDecl.Type)
 	name, changed, err := p.DeclName(cname)
 	if err != nil {
@@ -318,6 +371,10 @@ func (p *Package) NewTypeDecl(typeDecl *ast.TypeDecl) error {
 		return err
 	}
 	p.CollectNameMapping(cname, name)
+	if p.records == nil {
+		p.records = make(map[string]string)
+	}
+	p.records[cname] = name
 
 	incom := p.handleTypeDecl(name, cname, typeDecl)
 
@@ -329,6 +386,8 @@ func (p *Package) NewTypeDecl(typeDecl *ast.TypeDecl) error {
 		if err := p.handleCompleteType(incom, typeDecl.Type, cname); err != nil {
 			return err
 		}
+		p.addDerivedRecord(incom.decl.Type(), typeDecl)
+		p.genStaticVars(cppRecordName(typeDecl), typeDecl.Type)
 	}
 	return nil
 }
@@ -338,13 +397,13 @@ func (p *Package) handleTypeDecl(pubname string, cname string, typeDecl *ast.Typ
 	if existDecl, exists := p.incompleteTypes.Lookup(cname); exists {
 		return existDecl
 	}
-	decl := p.emptyTypeDecl(pubname, typeDecl.Doc)
+	decl := p.emptyTypeDecl(pubname, DeclCommentGroup(typeDecl.Doc, typeDecl.Attrs, cname).CommentGroup)
 	inc := &Incomplete{
 		cname: cname,
 		file:  p.curFile,
 		decl:  decl,
 		getType: func() (types.Type, error) {
-			return types.NewStruct(p.cvt.defaultRecordField(), nil), nil
+			return p.cvt.opaqueStruct(), nil
 		},
 	}
 	p.incompleteTypes.Add(inc)
@@ -358,16 +417,32 @@ func (p *Package) handleCompleteType(incom *Incomplete, typ *ast.RecordType, nam
 	defer p.incompleteTypes.Complete(name)
 	defer p.SetCurFile(p.curFile)
 	p.SetCurFile(incom.file)
-	structType, err := p.cvt.RecordTypeToStruct(typ)
+	structType, err := p.cvt.NamedRecordTypeToStruct(incom.decl.Type().Obj().Name(), typ)
 	if err != nil {
 		// For incomplete type's conerter error, we use default struct type
-		incom.decl.InitType(p.p, types.NewStruct(p.cvt.defaultRecordField(), nil))
+		incom.decl.InitType(p.p, p.cvt.opaqueStruct())
 		return err
 	}
 	incom.decl.InitType(p.p, structType)
+	if typ.Tag != ast.Union {
+		p.addFuncFields(name, incom.decl.Type(), typ.Fields)
+	}
 	return nil
 }
 
+// forwardDecl returns the type declaration created for the references to cname
+// before it is declared, and completes it, or nil if there is none. An enum or a
+// typedef nested in a C++ record is declared after the record, whose fields may
+// refer to it.
+func (p *Package) forwardDecl(cname string) *gogen.TypeDecl {
+	inc, ok := p.incompleteTypes.Lookup(cname)
+	if !ok {
+		return nil
+	}
+	p.incompleteTypes.Complete(cname)
+	return inc.decl
+}
+
 // handleImplicitForwardDecl handles type references that cannot be found in the current scope.
 // For such declarations, create a empty type decl and store it in the
 // incomplete map, but not in the public symbol table.
@@ -380,13 +455,13 @@ func (p *Package) handleImplicitForwardDecl(name string) *gogen.TypeDecl {
 	}
 
 	pubName := p.nameMapper.GetGoName(name, p.trimPrefixes())
-	decl := p.emptyTypeDecl(pubName, nil)
+	decl := p.emptyTypeDecl(pubName, CommentGroup(nil).CommentGroup)
 	inc := &Incomplete{
 		cname: name,
 		file:  p.curFile,
 		decl:  decl,
 		getType: func() (types.Type, error) {
-			return types.NewStruct(p.cvt.defaultRecordField(), nil), nil
+			return p.cvt.opaqueStruct(), nil
 		},
 	}
 	p.incompleteTypes.Add(inc)
@@ -396,9 +471,37 @@ func (p *Package) handleImplicitForwardDecl(name string) *gogen.TypeDecl {
 	return decl
 }
 
-func (p *Package) emptyTypeDecl(name string, doc *ast.CommentGroup) *gogen.TypeDecl {
+// nestedType declares the Go type name of an anonymous record or enum nested in
+// a field of a record, and registers it in llcppg.pub.
+func (p *Package) nestedType(name string, typ ast.Expr) (types.Type, error) {
+	if obj := p.p.Types.Scope().Lookup(name); obj != nil {
+		return nil, errs.NewTypeDefinedError(name, name)
+	}
+	p.CollectNameMapping(name, name)
+	switch t := typ.(type) {
+	case *ast.RecordType:
+		decl := p.emptyTypeDecl(name, nil)
+		structType, err := p.cvt.NamedRecordTypeToStruct(name, t)
+		if err != nil {
+			decl.InitType(p.p, p.cvt.opaqueStruct())
+			return nil, err
+		}
+		decl.InitType(p.p, structType)
+		return decl.Type(), nil
+	case *ast.EnumType:
+		decl := p.NewTypedefs(name, p.cvt.ToDefaultEnumType())
+		if err := p.createEnumItems(t.Items, decl.Type(), name); err != nil {
+			return nil, err
+		}
+		return decl.Type(), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported nested type %T", ErrTypeConv, typ)
+	}
+}
+
+func (p *Package) emptyTypeDecl(name string, doc *goast.CommentGroup) *gogen.TypeDecl {
 	typeBlock := p.p.NewTypeDefs()
-	typeBlock.SetComments(CommentGroup(doc).CommentGroup)
+	typeBlock.SetComments(doc)
 	return typeBlock.NewType(name)
 }
 
@@ -406,27 +509,41 @@ func (p *Package) NewTypedefDecl(typedefDecl *ast.TypedefDecl) error {
 	skip, _, err := p.cvt.handleSysType(typedefDecl.Name, typedefDecl.Loc, p.curFile.IncPath)
 	if skip {
 		if dbg.GetDebugLog() {
-			log.Printf("NewTypedefDecl: %v is a typedef of system header file\n", typedefDecl.Name)
+			log.Printf("%s: NewTypedefDecl: %v is a typedef of system header file\n", typedefDecl.Loc, typedefDecl.Name)
 		}
 		return err
 	}
 	if dbg.GetDebugLog() {
-		log.Printf("NewTypedefDecl: %v\n", typedefDecl.Name)
+		log.Printf("%s: NewTypedefDecl: %v\n", typedefDecl.Loc, typedefDecl.Name)
 	}
-	name, changed, err := p.DeclName(typedefDecl.Name.Name)
+	if why := skippedByAttrs(&typedefDecl.DeclBase); why != "" {
+		if dbg.GetDebugLog() {
+			log.Printf("%s: NewTypedefDecl: skip %v, %s\n", typedefDecl.Loc, typedefDecl.Name, why)
+		}
+		return nil
+	}
+	cname := p.scopedName(typedefDecl.Parent, typedefDecl.Name.Name)
+	name, changed, err := p.DeclName(cname)
 	if err != nil {
 		return err
 	}
-	p.CollectNameMapping(typedefDecl.Name.Name, name)
+	p.CollectNameMapping(cname, name)
 
-	genDecl := p.p.NewTypeDefs()
-	typeSpecdecl := genDecl.NewType(name)
+	var genDecl *gogen.TypeDefs
+	var typeSpecdecl *gogen.TypeDecl
+	if cname != typedefDecl.Name.Name {
+		typeSpecdecl = p.forwardDecl(cname)
+	}
+	if typeSpecdecl == nil {
+		genDecl = p.p.NewTypeDefs()
+		typeSpecdecl = genDecl.NewType(name)
+	}
 
 	if changed {
-		substObj(p.p.Types, p.p.Types.Scope(), typedefDecl.Name.Name, typeSpecdecl.Type().Obj())
+		substObj(p.p.Types, p.p.Types.Scope(), cname, typeSpecdecl.Type().Obj())
 	}
 
-	deferInit := p.handleTyperefIncomplete(typedefDecl.Type, typeSpecdecl, typedefDecl.Name.Name)
+	deferInit := p.handleTyperefIncomplete(typedefDecl.Type, typeSpecdecl, cname)
 	if deferInit {
 		if dbg.GetDebugLog() {
 			log.Printf("NewTypedefDecl: %s defer init\n", name)
@@ -434,15 +551,31 @@ func (p *Package) NewTypedefDecl(typedefDecl *ast.TypedefDecl) error {
 		return nil
 	}
 
-	typ, err := p.ToType(typedefDecl.Type)
+	var typ types.Type
+	if record, ok := ast.Unqualified(typedefDecl.Type).(*ast.RecordType); ok {
+		typ, err = p.cvt.NamedRecordTypeToStruct(name, record)
+	} else {
+		typ, err = p.ToType(typedefDecl.Type)
+	}
 	if err != nil {
-		typeSpecdecl.InitType(p.p, types.NewStruct(p.cvt.defaultRecordField(), nil))
+		typeSpecdecl.InitType(p.p, p.cvt.opaqueStruct())
 		return err
 	}
 
 	typeSpecdecl.InitType(p.p, typ)
+	if record, ok := ast.Unqualified(typedefDecl.Type).(*ast.RecordType); ok && record.Tag != ast.Union {
+		p.addFuncFields(cname, typeSpecdecl.Type(), record.Fields)
+	}
+	doc := DeclCommentGroup(nil, typedefDecl.Attrs, cname)
 	if _, ok := typ.(*types.Signature); ok {
-		genDecl.SetComments(NewTypecDocComments())
+		doc.AddCommentGroup(NewTypecDocComments())
+	}
+	if len(doc.List) > 0 {
+		if genDecl != nil {
+			genDecl.SetComments(doc.CommentGroup)
+		} else {
+			typeSpecdecl.SetComments(p.p, doc.CommentGroup)
+		}
 	}
 
 	return nil
@@ -450,13 +583,16 @@ func (p *Package) NewTypedefDecl(typedefDecl *ast.TypedefDecl) error {
 
 func (p *Package) handleTyperefIncomplete(typeRef ast.Expr, typeSpecdecl *gogen.TypeDecl, namedName string) bool {
 	var name string
-	switch expr := typeRef.(type) {
+	switch expr := ast.Unqualified(typeRef).(type) {
 	case *ast.TagExpr:
-		if n, ok := expr.Name.(*ast.Ident); ok {
+		switch n := expr.Name.(type) {
+		case *ast.Ident:
 			name = n.Name
-		} else {
-			panic("todo:scoping expr not supported")
+		case *ast.ScopingExpr:
+			name = p.scopingName(n)
 		}
+	case *ast.ScopingExpr:
+		name = p.scopingName(expr)
 	case *ast.Ident:
 		name = expr.Name
 	default:
@@ -502,14 +638,24 @@ func (p *Package) NewEnumTypeDecl(enumTypeDecl *ast.EnumTypeDecl) error {
 	skip, _, err := p.cvt.handleSysType(enumTypeDecl.Name, enumTypeDecl.Loc, p.curFile.IncPath)
 	if skip {
 		if dbg.GetDebugLog() {
-			log.Printf("NewEnumTypeDecl: %v is a enum type of system header file\n", enumTypeDecl.Name)
+			log.Printf("%s: NewEnumTypeDecl: %v is a enum type of system header file\n", enumTypeDecl.Loc, enumTypeDecl.Name)
 		}
 		return err
 	}
 	if dbg.GetDebugLog() {
-		log.Printf("NewEnumTypeDecl: %v\n", enumTypeDecl.Name)
+		log.Printf("%s: NewEnumTypeDecl: %v\n", enumTypeDecl.Loc, enumTypeDecl.Name)
+	}
+	if why := skippedByAttrs(&enumTypeDecl.DeclBase); why != "" {
+		if dbg.GetDebugLog() {
+			log.Printf("%s: NewEnumTypeDecl: skip %v, %s\n", enumTypeDecl.Loc, enumTypeDecl.Name, why)
+		}
+		return nil
 	}
-	enumType, enumTypeName, err := p.createEnumType(enumTypeDecl.Name)
+	enumName := enumTypeDecl.Name
+	if enumName != nil {
+		enumName = &ast.Ident{Name: p.scopedName(enumTypeDecl.Parent, enumName.Name)}
+	}
+	enumType, enumTypeName, err := p.createEnumType(enumName)
 	if err != nil {
 		return err
 	}
@@ -536,8 +682,12 @@ func (p *Package) createEnumType(enumName *ast.Ident) (types.Type, string, error
 	}
 	enumType := p.cvt.ToDefaultEnumType()
 	if name != "" {
-		t = p.NewTypedefs(name, enumType)
-		enumType = p.p.Types.Scope().Lookup(name).Type()
+		if t = p.forwardDecl(enumName.Name); t != nil {
+			t.InitType(p.p, enumType)
+		} else {
+			t = p.NewTypedefs(name, enumType)
+		}
+		enumType = t.Type()
 	}
 	if changed {
 		substObj(p.p.Types, p.p.Types.Scope(), enumName.Name, t.Type().Obj())
@@ -562,6 +712,8 @@ func (p *Package) createEnumItems(items []*ast.EnumItem, enumType types.Type, en
 			return err
 		}
 		defs.New(val, enumType, name)
+		p.addConst(item.Name.Name, name)
+		p.setObjComments(p.p.Types.Scope().Lookup(name), item.Doc, item.Comment)
 		if changed {
 			if obj := p.p.Types.Scope().Lookup(name); obj != nil {
 				substObj(p.p.Types, p.p.Types.Scope(), item.Name.Name, obj)
@@ -594,6 +746,7 @@ func (p *Package) NewMacro(macro *ast.Macro) error {
 				Kind:  token.INT,
 				Value: value,
 			}, nil, name)
+			p.addConst(macro.Name, name)
 		} else if _, err := litToFloat(value, 64); err == nil {
 			defs.New(&goast.BasicLit{
 				Kind:  token.FLOAT,
@@ -604,6 +757,74 @@ func (p *Package) NewMacro(macro *ast.Macro) error {
 	return nil
 }
 
+// NewVarDecl declares the constant of a const variable as a Go constant, named
+// like a macro and typed like the variable:
+//
+//	// static const int FOO_MAX = 64;
+//	const FOO_MAX c.Int = 64
+//
+// A string constant is untyped: a Go constant can't have the type of the
+// variable, a pointer to or an array of char, so it's a Go string where it's
+// used. A variable without a value, or with a floating value which is not finite,
+// like INFINITY, is an error, as Go has no constant for it.
+func (p *Package) NewVarDecl(varDecl *ast.VarDecl) error {
+	if p.curFile.IsSys {
+		return nil
+	}
+	if why := skippedByAttrs(&varDecl.DeclBase); why != "" {
+		if dbg.GetDebugLog() {
+			log.Printf("%s: NewVarDecl: skip %v, %s\n", varDecl.Loc, varDecl.Name, why)
+		}
+		return nil
+	}
+	name, _, err := p.DeclName(varDecl.Name.Name)
+	if err != nil {
+		return err
+	}
+	if varDecl.Value == nil {
+		return fmt.Errorf("%w: constant %s has no value", ErrTypeConv, varDecl.Name.Name)
+	}
+	if dbg.GetDebugLog() {
+		log.Printf("NewVarDecl: %s = %s\n", name, varDecl.Value.Value)
+	}
+	var val any
+	var typ types.Type
+	switch varDecl.Value.Kind {
+	case ast.StringLit:
+		val = &goast.BasicLit{Kind: token.STRING, Value: varDecl.Value.Value}
+	case ast.IntLit, ast.FloatLit:
+		typ, err = p.ToType(varDecl.Type)
+		if err != nil {
+			return err
+		}
+		basic, ok := typ.Underlying().(*types.Basic)
+		if !ok || basic.Info()&(types.IsBoolean|types.IsNumeric) == 0 {
+			return fmt.Errorf("%w: unsupported constant type %s", ErrTypeConv, typ)
+		}
+		kind := token.INT
+		if varDecl.Value.Kind == ast.FloatLit {
+			kind = token.FLOAT
+			f, err := litToFloat(varDecl.Value.Value, 64)
+			if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+				return fmt.Errorf("%w: unsupported constant value %s", ErrTypeConv, varDecl.Value.Value)
+			}
+		}
+		val = &goast.BasicLit{Kind: kind, Value: varDecl.Value.Value}
+		if basic.Info()&types.IsBoolean != 0 {
+			val = varDecl.Value.Value != "0"
+		}
+	default:
+		return fmt.Errorf("%w: unsupported constant value %s", ErrTypeConv, varDecl.Value.Value)
+	}
+	defs := p.NewConstGroup()
+	defs.SetComments(goCommentGroup(varDecl.Doc))
+	defs.New(val, typ, name)
+	if varDecl.Value.Kind == ast.IntLit {
+		p.addConst(varDecl.Name.Name, name)
+	}
+	return nil
+}
+
 func (p *Package) NewConstGroup() *ConstGroup {
 	return NewConstGroup(p.p, p.p.Types.Scope())
 }
@@ -618,6 +839,10 @@ func NewConstGroup(pkg *gogen.Package, scope *types.Scope) *ConstGroup {
 	}
 }
 
+func (p *ConstGroup) SetComments(doc *goast.CommentGroup) {
+	p.defs.SetComments(doc)
+}
+
 func (p *ConstGroup) New(val any, typ types.Type, name string) {
 	p.defs.New(func(cb *gogen.CodeBuilder) int {
 		cb.Val(val)
@@ -633,6 +858,13 @@ func (p *Package) WritePkgFiles() error {
 	if err != nil {
 		return err
 	}
+	p.genFuncWrappers()
+	p.finishErrorTypes()
+	p.genLifecycles()
+	p.genFuncFieldAccessors()
+	p.genUnionAccessors()
+	p.genLayoutAccessors()
+	p.genBaseClasses()
 	for _, file := range p.files {
 		if file.IsHeaderFile && !file.IsSys {
 			err := p.Write(file.File)
@@ -673,6 +905,9 @@ func (p *Package) WriteLinkFile() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to link lib: %w", err)
 	}
+	if err := p.linkShims(); err != nil {
+		return "", fmt.Errorf("failed to link shims: %w", err)
+	}
 	if err := p.writeToFile(fileName, filePath); err != nil {
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
@@ -700,11 +935,20 @@ func (p *Package) writeToFile(genFName string, filePath string) error {
 
 // Write the corresponding files in gogen package to the buffer
 func (p *Package) WriteToBuffer(genFName string) (*bytes.Buffer, error) {
+	p.attachArrayLens(genFName)
+	commented := p.attachObjComments(genFName)
 	buf := new(bytes.Buffer)
 	err := p.p.WriteTo(buf, genFName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to write to buffer: %w", err)
 	}
+	if commented {
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to format commented source: %w", err)
+		}
+		buf = bytes.NewBuffer(src)
+	}
 	return buf, nil
 }
 
@@ -740,6 +984,56 @@ func (p *Package) DeclName(name string) (pubName string, changed bool, err error
 	return pubName, changed, nil
 }
 
+// scopedName returns the C name of a declaration or a reference named name in
+// parent. A name nested in a C++ record is qualified by the record, like
+// Outer::Inner, and its Go name is prefixed by the Go name of the record, like
+// Outer_Inner. Namespaces don't qualify names.
+func (p *Package) scopedName(parent ast.Expr, name string) string {
+	outer := p.outerRecord(parent)
+	if outer == "" {
+		return name
+	}
+	cname := outer + "::" + name
+	if _, ok := p.nameMapper.Lookup(cname); !ok {
+		p.nameMapper.SetMapping(cname, p.records[outer]+"_"+names.PubName(name))
+	}
+	return cname
+}
+
+// scopingName returns the C name of a reference like Outer::Inner, or "" if it
+// is not a name.
+func (p *Package) scopingName(expr *ast.ScopingExpr) string {
+	if ident, ok := expr.X.(*ast.Ident); ok {
+		return p.scopedName(expr.Parent, ident.Name)
+	}
+	return ""
+}
+
+// outerRecord returns the qualified C name of the innermost record of parent,
+// like Outer or Outer::Inner, or "" if parent is nil or only has namespaces.
+func (p *Package) outerRecord(parent ast.Expr) string {
+	var outer, name string
+	switch expr := parent.(type) {
+	case *ast.Ident:
+		name = expr.Name
+	case *ast.ScopingExpr:
+		ident, ok := expr.X.(*ast.Ident)
+		if !ok {
+			return ""
+		}
+		outer, name = p.outerRecord(expr.Parent), ident.Name
+	default:
+		return ""
+	}
+	if outer != "" {
+		name = outer + "::" + name
+	}
+	if _, ok := p.records[name]; ok {
+		return name
+	}
+	return outer
+}
+
 func (p *Package) trimPrefixes() []string {
 	if p.curFile.InCurPkg {
 		return p.CppgConf.TrimPrefixes
@@ -842,8 +1136,8 @@ func (it *IncompleteTypes) Clear() {
 
 func (it *IncompleteTypes) IterateIncomplete(fn func(*Incomplete) error) error {
 	for _, inc := range it.types {
-		// skip the type that has been completed
-		if _, ok := it.typesMap[inc.cname]; !ok {
+		// skip the type that has been completed or redeclared
+		if cur, ok := it.typesMap[inc.cname]; !ok || cur != inc {
 			continue
 		}
 		if err := fn(inc); err != nil {
@@ -11,11 +11,6 @@ import (
 )
 
 func TestTypeRefIncompleteFail(t *testing.T) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Fatal("Expected panic, got nil")
-		}
-	}()
 	pkg := NewPackage(&PackageConfig{
 		PkgBase: PkgBase{
 			PkgPath:  ".",
@@ -48,12 +43,14 @@ func TestTypeRefIncompleteFail(t *testing.T) {
 		t.Fatal("Expected error, got nil")
 	}
 
-	pkg.handleTyperefIncomplete(&ast.TagExpr{
+	if pkg.handleTyperefIncomplete(&ast.TagExpr{
 		Tag: 0,
 		Name: &ast.ScopingExpr{
 			X: &ast.Ident{Name: "Bar"},
 		},
-	}, nil, "NewBar")
+	}, nil, "NewBar") {
+		t.Fatal("Expected Bar to be complete")
+	}
 }
 
 func TestPubMethodName(t *testing.T) {
@@ -98,3 +95,130 @@ func TestGetNameType(t *testing.T) {
 		t.Fatal("Expected nil, got", customRes)
 	}
 }
+
+func TestLinkName(t *testing.T) {
+	fn := &ast.FuncDecl{Name: &ast.Ident{Name: "foo_count"}, MangledName: "_Z9foo_countv"}
+	testCases := []struct {
+		cplusplus bool
+		mangled   string
+		expected  string
+	}{
+		{false, "_Z9foo_countv", "foo_count"},
+		{true, "_Z9foo_countv", "_Z9foo_countv"},
+		{true, "", "foo_count"},
+	}
+	for _, tc := range testCases {
+		pkg := &Package{PkgInfo: &PkgInfo{PkgBase: PkgBase{CppgConf: &cppgtypes.Config{Cplusplus: tc.cplusplus}}}}
+		fn.MangledName = tc.mangled
+		if got := pkg.linkName(fn); got != tc.expected {
+			t.Errorf("linkName(cplusplus=%v, %q) = %q, want %q", tc.cplusplus, tc.mangled, got, tc.expected)
+		}
+	}
+}
+
+func TestWithThis(t *testing.T) {
+	foo := &ast.ScopingExpr{Parent: &ast.Ident{Name: "ns"}, X: &ast.Ident{Name: "Foo"}}
+	params := &ast.FieldList{List: []*ast.Field{
+		{Names: []*ast.Ident{{Name: "i"}}, Type: &ast.LvalueRefType{X: &ast.BuiltinType{Kind: ast.Int}}},
+	}}
+	method := &ast.FuncDecl{
+		DeclBase: ast.DeclBase{Parent: foo},
+		Name:     &ast.Ident{Name: "get"},
+		Type:     &ast.FuncType{Params: params, Ret: &ast.BuiltinType{Kind: ast.Int}},
+	}
+	fn := withThis(method)
+	if len(fn.Type.Params.List) != 2 || fn.Type.Params.List[0].Names[0].Name != "this" {
+		t.Fatalf("unexpected params of %s: %+v", fn.Name.Name, fn.Type.Params.List)
+	}
+	if this, ok := fn.Type.Params.List[0].Type.(*ast.PointerType); !ok || this.X != foo {
+		t.Fatalf("this of %s is not a pointer to its class: %#v", fn.Name.Name, fn.Type.Params.List[0].Type)
+	}
+	if len(method.Type.Params.List) != 1 {
+		t.Fatal("withThis modified the params of the method")
+	}
+}
+
+func TestCppDecl(t *testing.T) {
+	char := &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}
+	testCases := []struct {
+		name     string
+		typ      ast.Expr
+		expected string
+	}{
+		{"cb", &ast.PointerType{X: &ast.FuncType{
+			Params: &ast.FieldList{List: []*ast.Field{{Type: &ast.BuiltinType{Kind: ast.Int}}, {Type: &ast.Variadic{}}}},
+			Ret:    &ast.BuiltinType{Kind: ast.Int},
+		}}, "int (*cb)(int, ...)"},
+		{"argv", &ast.PointerType{X: &ast.QualifiedType{
+			X:          &ast.PointerType{X: &ast.QualifiedType{X: char, Qualifiers: ast.Const}},
+			Qualifiers: ast.Const,
+		}}, "char const *const *argv"},
+		{"n", &ast.LvalueRefType{X: &ast.BuiltinType{Kind: ast.Int, Flags: ast.Unsigned | ast.LongLong}}, "unsigned long long &n"},
+		{"m", &ast.LvalueRefType{X: &ast.ArrayType{
+			Elt: &ast.BuiltinType{Kind: ast.Float, Flags: ast.Double},
+			Len: &ast.BasicLit{Kind: ast.IntLit, Value: "3"},
+		}}, "double (&m)[3]"},
+		{"f", &ast.RvalueRefType{X: &ast.ScopingExpr{Parent: &ast.Ident{Name: "ns"}, X: &ast.Ident{Name: "Foo"}}}, "ns::Foo &&f"},
+		{"", &ast.PointerType{X: &ast.QualifiedType{X: char, Qualifiers: ast.Const | ast.Volatile}}, "char const volatile *"},
+	}
+	for _, tc := range testCases {
+		decl, err := cppDecl(tc.typ, tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decl != tc.expected {
+			t.Errorf("cppDecl(%s) = %q, want %q", tc.name, decl, tc.expected)
+		}
+	}
+	if _, err := cppDecl(&ast.BuiltinType{Kind: ast.Complex}, "z"); err == nil {
+		t.Error("cppDecl of a complex type should fail")
+	}
+}
+
+func TestShimSourceResult(t *testing.T) {
+	foo := &ast.Ident{Name: "Foo"}
+	// Foo Foo::clone() const;
+	src, err := shimSource(&ast.FuncDecl{
+		DeclBase:    ast.DeclBase{Parent: foo},
+		Name:        &ast.Ident{Name: "clone"},
+		MangledName: "_ZNK3Foo5cloneEv",
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{List: []*ast.Field{{Type: &ast.PointerType{X: foo}}}},
+			Ret:    foo,
+		},
+	}, "llcppg_exc__ZNK3Foo5cloneEv", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `extern "C" Foo llcppg_exc__ZNK3Foo5cloneEv(Foo *self, char **exc) {
+	static_assert(std::is_trivially_copyable<Foo>::value && std::is_default_constructible<Foo>::value,
+		"Foo can't be returned by value from the shim");
+	try {
+		return self->clone();
+	} catch (const std::exception &e) {
+		*exc = strdup(e.what());
+	} catch (...) {
+		*exc = strdup("unknown C++ exception");
+	}
+	return {};
+}
+`
+	if src != expected {
+		t.Fatalf("unexpected shim:\n%s", src)
+	}
+}
+
+func TestIsLengthParamName(t *testing.T) {
+	lengths := []string{"n", "N", "len", "size", "count", "cap", "buf_len", "bufLen", "BUFLen", "buf_size", "item_count", "itemCount", "max_size"}
+	for _, name := range lengths {
+		if !isLengthParamName(name) {
+			t.Errorf("%s should be a length", name)
+		}
+	}
+	others := []string{"calendar", "account", "escape", "capture", "resize", "sizes", "n_items", "capacity_hint", "lens", "_", ""}
+	for _, name := range others {
+		if isLengthParamName(name) {
+			t.Errorf("%s should not be a length", name)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package convert_test
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,6 +15,8 @@ import (
 	"github.com/goplus/llcppg/cmd/gogensig/convert"
 	"github.com/goplus/llcppg/cmd/gogensig/convert/names"
 	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+	"github.com/goplus/llcppg/cmd/gogensig/errs"
+	ctoken "github.com/goplus/llcppg/token"
 	cppgtypes "github.com/goplus/llcppg/types"
 	"github.com/goplus/mod/gopmod"
 )
@@ -791,72 +794,101 @@ func Foo(a *c.Uint, b *float64) **int8
 	}
 }
 
-func TestStructDecl(t *testing.T) {
+func TestDoxygenComments(t *testing.T) {
+	comments := func(lines ...string) *ast.CommentGroup {
+		doc := &ast.CommentGroup{}
+		for _, line := range lines {
+			doc.List = append(doc.List, &ast.Comment{Text: line + "\n"})
+		}
+		return doc
+	}
 	testCases := []genDeclTestCase{
-		// struct Foo {}
 		{
-			name: "empty struct",
-			decl: &ast.TypeDecl{
-				Name: &ast.Ident{Name: "Foo"},
-				Type: &ast.RecordType{
-					Tag:    ast.Struct,
-					Fields: nil,
+			name: "function doc",
+			decl: &ast.FuncDecl{
+				DeclBase: ast.DeclBase{
+					Doc: comments(
+						"/**",
+						" * @brief Opens a database.",
+						" *",
+						" * Example:",
+						" * \\code",
+						" * sqlite3 *db;",
+						" * sqlite3_open(\"test.db\", &db);",
+						" * \\endcode",
+						" *",
+						" * @param[in] filename the database file,",
+						" *        in UTF-8",
+						" * @param ppDb the opened database",
+						" * @return SQLITE_OK on success",
+						" * @deprecated use sqlite3_open_v2 instead",
+						" */",
+					),
+				},
+				Name:        &ast.Ident{Name: "sqlite3_open"},
+				MangledName: "sqlite3_open",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{
+							{Names: []*ast.Ident{{Name: "filename"}}, Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}}},
+							{Names: []*ast.Ident{{Name: "ppDb"}}, Type: &ast.PointerType{X: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}}}},
+						},
+					},
+					Ret: &ast.BuiltinType{Kind: ast.Int},
+				},
+			},
+			symbs: []cfg.SymbolEntry{
+				{
+					CppName:    "sqlite3_open",
+					MangleName: "sqlite3_open",
+					GoName:     "Open",
 				},
 			},
 			expected: `
 package testpkg
 
-import _ "unsafe"
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
 
-type Foo struct {
-}`,
+// Opens a database.
+//
+// Example:
+//
+//	sqlite3 *db;
+//	sqlite3_open("test.db", &db);
+//
+// Parameters:
+//   - filename: the database file, in UTF-8
+//   - ppDb: the opened database
+//
+// Returns: SQLITE_OK on success
+//
+// Deprecated: use sqlite3_open_v2 instead
+//go:linkname Open C.sqlite3_open
+func Open(filename *int8, ppDb *unsafe.Pointer) c.Int`,
 		},
-		// invalid struct type
 		{
-			name: "invalid struct type",
+			name: "field comments",
 			decl: &ast.TypeDecl{
-				Name: &ast.Ident{Name: "InvalidStruct"},
-				Type: &ast.RecordType{
-					Tag: ast.Struct,
-					Fields: &ast.FieldList{
-						List: []*ast.Field{
-							{
-								Names: []*ast.Ident{{Name: "invalidField"}},
-								Type:  &ast.BuiltinType{Kind: ast.Bool, Flags: ast.Long},
-							},
-						},
-					},
+				DeclBase: ast.DeclBase{
+					Doc: comments("/// A point."),
 				},
-			},
-			expectedErr: "not found in type map",
-		},
-		// struct Foo { int a; double b; bool c; }
-		{
-			name: "struct field builtin type",
-			decl: &ast.TypeDecl{
-				Name: &ast.Ident{Name: "Foo"},
+				Name: &ast.Ident{Name: "Point"},
 				Type: &ast.RecordType{
 					Tag: ast.Struct,
 					Fields: &ast.FieldList{
 						List: []*ast.Field{
 							{
-								Names: []*ast.Ident{{Name: "a"}},
-								Type: &ast.BuiltinType{
-									Kind: ast.Int,
-								},
-							},
-							{
-								Names: []*ast.Ident{{Name: "b"}},
-								Type: &ast.BuiltinType{
-									Kind:  ast.Float,
-									Flags: ast.Double,
-								},
+								Doc:   comments("/** the x coordinate */"),
+								Names: []*ast.Ident{{Name: "x"}},
+								Type:  &ast.BuiltinType{Kind: ast.Int},
 							},
 							{
-								Names: []*ast.Ident{{Name: "c"}},
-								Type: &ast.BuiltinType{
-									Kind: ast.Bool,
-								},
+								Names:   []*ast.Ident{{Name: "y"}},
+								Type:    &ast.BuiltinType{Kind: ast.Int},
+								Comment: comments("///< the y coordinate"),
 							},
 						},
 					},
@@ -866,57 +898,32 @@ type Foo struct {
 package testpkg
 
 import (
-"github.com/goplus/llgo/c"
-_ "unsafe"
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
 )
 
-type Foo struct {
-	A c.Int
-	B float64
-	C bool
+// A point.
+type Point struct {
+	// the x coordinate
+	X c.Int
+	Y c.Int // the y coordinate
 }`,
 		},
-		// struct Foo { int* a; double* b; bool* c;void* d; }
 		{
-			name: "struct field pointer",
-			decl: &ast.TypeDecl{
-				Name: &ast.Ident{Name: "Foo"},
-				Type: &ast.RecordType{
-					Tag: ast.Struct,
-					Fields: &ast.FieldList{
-						List: []*ast.Field{
-							{
-								Names: []*ast.Ident{{Name: "a"}},
-								Type: &ast.PointerType{
-									X: &ast.BuiltinType{
-										Kind: ast.Int,
-									},
-								},
-							},
-							{
-								Names: []*ast.Ident{{Name: "b"}},
-								Type: &ast.PointerType{
-									X: &ast.BuiltinType{
-										Kind:  ast.Float,
-										Flags: ast.Double,
-									}},
-							},
-							{
-								Names: []*ast.Ident{{Name: "c"}},
-								Type: &ast.PointerType{
-									X: &ast.BuiltinType{
-										Kind: ast.Bool,
-									},
-								},
-							},
-							{
-								Names: []*ast.Ident{{Name: "d"}},
-								Type: &ast.PointerType{
-									X: &ast.BuiltinType{
-										Kind: ast.Void,
-									},
-								},
-							},
+			name: "enum item comments",
+			decl: &ast.EnumTypeDecl{
+				Name: &ast.Ident{Name: "Color"},
+				Type: &ast.EnumType{
+					Items: []*ast.EnumItem{
+						{
+							Doc:   comments("/// the red color"),
+							Name:  &ast.Ident{Name: "Red"},
+							Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"},
+						},
+						{
+							Name:    &ast.Ident{Name: "Green"},
+							Value:   &ast.BasicLit{Kind: ast.IntLit, Value: "1"},
+							Comment: comments("/*!< the green color */"),
 						},
 					},
 				},
@@ -926,98 +933,104 @@ package testpkg
 
 import (
 	"github.com/goplus/llgo/c"
-	"unsafe"
+	_ "unsafe"
 )
 
-type Foo struct {
-	A *c.Int
-	B *float64
-	C *bool
-	D unsafe.Pointer
-}`},
-		// struct Foo { char a[4]; int b[3][4]; }
+type Color c.Int
+const (
+	// the red color
+	ColorRed   Color = 0
+	ColorGreen Color = 1 // the green color
+)`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestDeclAttrs(t *testing.T) {
+	voidFunc := func(name string, attrs ...*ast.Attr) *ast.FuncDecl {
+		return &ast.FuncDecl{
+			DeclBase:    ast.DeclBase{Attrs: attrs},
+			Name:        &ast.Ident{Name: name},
+			MangledName: name,
+			Type:        &ast.FuncType{Ret: &ast.BuiltinType{Kind: ast.Void}},
+		}
+	}
+	testCases := []genDeclTestCase{
+		// void foo(void) __attribute__((deprecated("use bar instead"), noreturn));
 		{
-			name: "struct array field",
-			decl: &ast.TypeDecl{
-				Name: &ast.Ident{Name: "Foo"},
-				Type: &ast.RecordType{
-					Tag: ast.Struct,
-					Fields: &ast.FieldList{
+			name: "deprecated function",
+			decl: voidFunc("foo",
+				&ast.Attr{Name: ast.AttrDeprecated, Args: []string{"use bar instead"}},
+				&ast.Attr{Name: ast.AttrNoReturn},
+			),
+			symbs: []cfg.SymbolEntry{{CppName: "foo", MangleName: "foo", GoName: "Foo"}},
+			expected: `
+package testpkg
+
+import _ "unsafe"
+
+// Attributes: noreturn.
+//
+// Deprecated: use bar instead
+//go:linkname Foo C.foo
+func Foo()`,
+		},
+		// void *foo_alloc(const char *fmt, ...)
+		//     __attribute__((malloc, format(printf, 1, 2), nonnull(1), visibility("default")));
+		{
+			name: "attributes",
+			decl: &ast.FuncDecl{
+				DeclBase: ast.DeclBase{
+					Doc: &ast.CommentGroup{List: []*ast.Comment{{Text: "/// Allocates a formatted string.\n"}}},
+					Attrs: []*ast.Attr{
+						{Name: ast.AttrMalloc},
+						{Name: ast.AttrFormat, Args: []string{"printf", "1", "2"}},
+						{Name: ast.AttrNonNull, Args: []string{"1"}},
+						{Name: ast.AttrVisibility, Args: []string{"default"}},
+					},
+				},
+				Name:        &ast.Ident{Name: "foo_alloc"},
+				MangledName: "foo_alloc",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
 						List: []*ast.Field{
-							{
-								Names: []*ast.Ident{{Name: "a"}},
-								Type: &ast.ArrayType{
-									Elt: &ast.BuiltinType{
-										Kind:  ast.Char,
-										Flags: ast.Signed,
-									},
-									Len: &ast.BasicLit{
-										Kind:  ast.IntLit,
-										Value: "4",
-									},
-								},
-							},
-							{
-								Names: []*ast.Ident{{Name: "b"}},
-								Type: &ast.ArrayType{
-									Elt: &ast.ArrayType{
-										Elt: &ast.BuiltinType{
-											Kind: ast.Int,
-										},
-										Len: &ast.BasicLit{Kind: ast.IntLit, Value: "4"},
-									},
-									Len: &ast.BasicLit{Kind: ast.IntLit, Value: "3"},
-								},
-							},
+							{Names: []*ast.Ident{{Name: "fmt"}}, Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}}},
+							{Type: &ast.Variadic{}},
 						},
 					},
+					Ret: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}},
 				},
 			},
+			symbs: []cfg.SymbolEntry{{CppName: "foo_alloc", MangleName: "foo_alloc", GoName: "FooAlloc"}},
 			expected: `
 package testpkg
 
-import (
-"github.com/goplus/llgo/c"
-_ "unsafe"
-)
+import "unsafe"
 
-type Foo struct {
-	A [4]int8
-	B [3][4]c.Int
-}`},
+// Allocates a formatted string.
+//
+// Attributes: malloc, format(printf, 1, 2), nonnull(1).
+//go:linkname FooAlloc C.foo_alloc
+func FooAlloc(fmt *int8, __llgo_va_list ...interface{}) unsafe.Pointer`,
+		},
+		// struct __attribute__((deprecated)) Old { int x; };
 		{
-			name: "struct array field",
+			name: "deprecated type",
 			decl: &ast.TypeDecl{
-				Name: &ast.Ident{Name: "Foo"},
+				DeclBase: ast.DeclBase{
+					Attrs: []*ast.Attr{{Name: ast.AttrDeprecated}},
+				},
+				Name: &ast.Ident{Name: "Old"},
 				Type: &ast.RecordType{
 					Tag: ast.Struct,
 					Fields: &ast.FieldList{
 						List: []*ast.Field{
-							{
-								Names: []*ast.Ident{{Name: "a"}},
-								Type: &ast.ArrayType{
-									Elt: &ast.BuiltinType{
-										Kind:  ast.Char,
-										Flags: ast.Signed,
-									},
-									Len: &ast.BasicLit{
-										Kind:  ast.IntLit,
-										Value: "4",
-									},
-								},
-							},
-							{
-								Names: []*ast.Ident{{Name: "b"}},
-								Type: &ast.ArrayType{
-									Elt: &ast.ArrayType{
-										Elt: &ast.BuiltinType{
-											Kind: ast.Int,
-										},
-										Len: &ast.BasicLit{Kind: ast.IntLit, Value: "4"},
-									},
-									Len: &ast.BasicLit{Kind: ast.IntLit, Value: "3"},
-								},
-							},
+							{Names: []*ast.Ident{{Name: "x"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
 						},
 					},
 				},
@@ -1026,76 +1039,128 @@ type Foo struct {
 package testpkg
 
 import (
-"github.com/goplus/llgo/c"
-_ "unsafe"
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
 )
 
-type Foo struct {
-	A [4]int8
-	B [3][4]c.Int
-}`},
+// Deprecated: Old is deprecated.
+type Old struct {
+	X c.Int
+}`,
+		},
+		// void gone(void) __attribute__((unavailable("removed")));
 		{
-			name: "anonymous struct",
-			decl: &ast.TypeDecl{
-				Name: nil,
-				Type: &ast.RecordType{
-					Tag:    ast.Struct,
-					Fields: &ast.FieldList{},
-				},
-			},
+			name:  "unavailable function",
+			decl:  voidFunc("gone", &ast.Attr{Name: ast.AttrUnavailable, Args: []string{"removed"}}),
+			symbs: []cfg.SymbolEntry{{CppName: "gone", MangleName: "gone", GoName: "Gone"}},
 			expected: `
 package testpkg
-import _ "unsafe"
-			`},
+
+import _ "unsafe"`,
+		},
+		// __attribute__((visibility("hidden"))) void internal_helper(void);
 		{
-			name: "struct array field without len",
-			decl: &ast.TypeDecl{
-				Name: &ast.Ident{Name: "Foo"},
-				Type: &ast.RecordType{
-					Tag: ast.Struct,
-					Fields: &ast.FieldList{
-						List: []*ast.Field{
-							{
-								Names: []*ast.Ident{{Name: "a"}},
-								Type: &ast.ArrayType{
-									Elt: &ast.BuiltinType{
-										Kind:  ast.Char,
-										Flags: ast.Signed,
-									},
-								},
-							},
+			name:  "hidden function",
+			decl:  voidFunc("internal_helper", &ast.Attr{Name: ast.AttrVisibility, Args: []string{"hidden"}}),
+			symbs: []cfg.SymbolEntry{{CppName: "internal_helper", MangleName: "internal_helper", GoName: "InternalHelper"}},
+			expected: `
+package testpkg
+
+import _ "unsafe"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestTypeQualifiers(t *testing.T) {
+	qualified := func(x ast.Expr, quals ast.Qualifier) ast.Expr {
+		return &ast.QualifiedType{X: x, Qualifiers: quals}
+	}
+	constChar := qualified(&ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, ast.Const)
+	testCases := []genDeclTestCase{
+		// const char *_Nonnull foo_name(const char *_Nonnull s, void *_Nullable data);
+		{
+			name: "nullability",
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "foo_name"},
+				MangledName: "foo_name",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{
+							{Names: []*ast.Ident{{Name: "s"}}, Type: qualified(&ast.PointerType{X: constChar}, ast.Nonnull)},
+							{Names: []*ast.Ident{{Name: "data"}}, Type: qualified(&ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}}, ast.Nullable)},
 						},
 					},
+					Ret: qualified(&ast.PointerType{X: constChar}, ast.Nonnull),
 				},
 			},
-			expectedErr: "unsupport field with array without length",
+			symbs: []cfg.SymbolEntry{{CppName: "foo_name", MangleName: "foo_name", GoName: "FooName"}},
+			expected: `
+package testpkg
+
+import "unsafe"
+
+// s must not be NULL.
+// data may be NULL.
+// The result is never NULL.
+//go:linkname FooName C.foo_name
+func FooName(s *int8, data unsafe.Pointer) *int8`,
 		},
+		// int foo_copy(const int *out_src, int *volatile dst_out);
 		{
-			name: "struct array field without len",
-			decl: &ast.TypeDecl{
-				Name: &ast.Ident{Name: "Foo"},
-				Type: &ast.RecordType{
-					Tag: ast.Struct,
-					Fields: &ast.FieldList{
+			name: "const pointer is not an out param",
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "foo_copy"},
+				MangledName: "foo_copy",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
 						List: []*ast.Field{
-							{
-								Names: []*ast.Ident{{Name: "a"}},
-								Type: &ast.ArrayType{
-									Elt: &ast.BuiltinType{
-										Kind:  ast.Char,
-										Flags: ast.Signed,
-									},
-									Len: &ast.BuiltinType{Kind: ast.TypeKind(ast.Signed)}, //invalid
-								},
-							},
+							{Names: []*ast.Ident{{Name: "out_src"}}, Type: &ast.PointerType{X: qualified(&ast.BuiltinType{Kind: ast.Int}, ast.Const)}},
+							{Names: []*ast.Ident{{Name: "dst_out"}}, Type: qualified(&ast.PointerType{X: &ast.BuiltinType{Kind: ast.Int}}, ast.Volatile)},
 						},
 					},
+					Ret: &ast.BuiltinType{Kind: ast.Int},
 				},
 			},
-			expectedErr: "can't determine the array length",
+			symbs: []cfg.SymbolEntry{{CppName: "foo_copy", MangleName: "foo_copy", GoName: "FooCopy"}},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+//go:linkname FooCopy C.foo_copy
+func FooCopy(out_src *c.Int, dst_out *c.Int) c.Int
+// FooCopyOut wraps FooCopy and returns its out parameters as results.
+func FooCopyOut(out_src *c.Int) (ret c.Int, dst_out c.Int) {
+	ret = FooCopy(out_src, &dst_out)
+	return
+}`,
 		},
-	}
+		// typedef const volatile int CInt;
+		{
+			name: "qualified typedef",
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "CInt"},
+				Type: qualified(&ast.BuiltinType{Kind: ast.Int}, ast.Const|ast.Volatile),
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
 
+type CInt c.Int`,
+		},
+	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			testGenDecl(t, tc)
@@ -1103,46 +1168,148 @@ import _ "unsafe"
 	}
 }
 
-func TestTypedefFunc(t *testing.T) {
+func TestOutParamWrapper(t *testing.T) {
+	intPtr := func(name string) *ast.Field {
+		return &ast.Field{
+			Names: []*ast.Ident{{Name: name}},
+			Type:  &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Int}},
+		}
+	}
 	testCases := []genDeclTestCase{
-		// typedef int (*Foo) (int a, int b);
+		// int foo_get_size(int *out_width, int *height_out);
 		{
-			name: "typedef func",
-			decl: &ast.TypedefDecl{
-				Name: &ast.Ident{Name: "Foo"},
-				Type: &ast.PointerType{
-					X: &ast.FuncType{
-						Params: &ast.FieldList{
-							List: []*ast.Field{
-								{
-									Type: &ast.BuiltinType{
-										Kind: ast.Int,
-									},
-									Names: []*ast.Ident{{Name: "a"}},
-								},
-								{
-									Type: &ast.BuiltinType{
-										Kind: ast.Int,
-									},
-									Names: []*ast.Ident{{Name: "b"}},
-								},
+			name: "inferred out params",
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "foo_get_size"},
+				MangledName: "foo_get_size",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{intPtr("out_width"), intPtr("height_out")},
+					},
+					Ret: &ast.BuiltinType{Kind: ast.Int},
+				},
+			},
+			symbs: []cfg.SymbolEntry{
+				{
+					CppName:    "foo_get_size",
+					MangleName: "foo_get_size",
+					GoName:     "FooGetSize",
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+//go:linkname FooGetSize C.foo_get_size
+func FooGetSize(out_width *c.Int, height_out *c.Int) c.Int
+// FooGetSizeOut wraps FooGetSize and returns its out parameters as results.
+func FooGetSizeOut() (ret c.Int, out_width c.Int, height_out c.Int) {
+	ret = FooGetSize(&out_width, &height_out)
+	return
+}`,
+		},
+		// int foo_read(char *name_out, unsigned char *buf_out, int *vals_out, size_t vals_len, int *count_out);
+		{
+			name: "buffer params",
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "foo_read"},
+				MangledName: "foo_read",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names: []*ast.Ident{{Name: "name_out"}},
+								Type:  &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}},
+							},
+							{
+								Names: []*ast.Ident{{Name: "buf_out"}},
+								Type:  &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Unsigned}},
+							},
+							intPtr("vals_out"),
+							{
+								Names: []*ast.Ident{{Name: "vals_len"}},
+								Type:  &ast.BuiltinType{Kind: ast.Int, Flags: ast.Long | ast.Unsigned},
 							},
+							intPtr("count_out"),
 						},
-						Ret: &ast.BuiltinType{
-							Kind: ast.Int,
+					},
+					Ret: &ast.BuiltinType{Kind: ast.Int},
+				},
+			},
+			symbs: []cfg.SymbolEntry{
+				{
+					CppName:    "foo_read",
+					MangleName: "foo_read",
+					GoName:     "FooRead",
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+//go:linkname FooRead C.foo_read
+func FooRead(name_out *int8, buf_out *int8, vals_out *c.Int, vals_len c.Ulong, count_out *c.Int) c.Int
+// FooReadOut wraps FooRead and returns its out parameters as results.
+func FooReadOut(name_out *int8, buf_out *int8, vals_out *c.Int, vals_len c.Ulong) (ret c.Int, count_out c.Int) {
+	ret = FooRead(name_out, buf_out, vals_out, vals_len, &count_out)
+	return
+}`,
+		},
+		// void foo_get_size(int *width, int *height, void *out_data);
+		{
+			name: "configured out params",
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "foo_get_size"},
+				MangledName: "foo_get_size",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{
+							intPtr("width"),
+							intPtr("height"),
+							{
+								Names: []*ast.Ident{{Name: "out_data"}},
+								Type:  &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}},
+							},
 						},
 					},
+					Ret: &ast.BuiltinType{Kind: ast.Void},
+				},
+			},
+			symbs: []cfg.SymbolEntry{
+				{
+					CppName:    "foo_get_size",
+					MangleName: "foo_get_size",
+					GoName:     "FooGetSize",
+				},
+			},
+			cppgconf: &cppgtypes.Config{
+				OutParams: map[string][]string{
+					"foo_get_size": {"width", "height"},
 				},
 			},
 			expected: `
 package testpkg
 
 import (
-"github.com/goplus/llgo/c"
-_ "unsafe"
+	"github.com/goplus/llgo/c"
+	"unsafe"
 )
-// llgo:type C
-type Foo func(a c.Int, b c.Int) c.Int`,
+
+//go:linkname FooGetSize C.foo_get_size
+func FooGetSize(width *c.Int, height *c.Int, out_data unsafe.Pointer)
+// FooGetSizeOut wraps FooGetSize and returns its out parameters as results.
+func FooGetSizeOut(out_data unsafe.Pointer) (width c.Int, height c.Int) {
+	FooGetSize(&width, &height, out_data)
+	return
+}`,
 		},
 	}
 	for _, tc := range testCases {
@@ -1152,99 +1319,50 @@ type Foo func(a c.Int, b c.Int) c.Int`,
 	}
 }
 
-// Test Redefine error
-func TestRedef(t *testing.T) {
+func TestOutParamMethodWrapper(t *testing.T) {
 	pkg := createTestPkg(t, &convert.PackageConfig{
-		OutputDir: "",
 		SymbolTable: cfg.CreateSymbolTable(
 			[]cfg.SymbolEntry{
-				{CppName: "Bar", MangleName: "Bar", GoName: "Bar"},
+				{CppName: "foo_get_size", MangleName: "foo_get_size", GoName: "(*Foo).GetSize"},
 			},
 		),
 	})
-
-	flds := &ast.FieldList{
-		List: []*ast.Field{
-			{
-				Type: &ast.BuiltinType{Kind: ast.Int},
-			},
-		},
-	}
-	pkg.NewTypeDecl(&ast.TypeDecl{
-		Name: &ast.Ident{Name: "Foo"},
-		Type: &ast.RecordType{
-			Tag:    ast.Struct,
-			Fields: flds,
-		},
-	})
-
 	err := pkg.NewTypeDecl(&ast.TypeDecl{
 		Name: &ast.Ident{Name: "Foo"},
 		Type: &ast.RecordType{
-			Tag:    ast.Struct,
-			Fields: flds,
-		},
-	})
-	if err == nil {
-		t.Fatal("Expect a redefine err")
-	}
-
-	pkg.NewTypedefDecl(&ast.TypedefDecl{
-		Name: &ast.Ident{Name: "Foo"},
-		Type: &ast.Ident{Name: "Foo"},
-	})
-
-	err = pkg.NewFuncDecl(&ast.FuncDecl{
-		Name:        &ast.Ident{Name: "Bar"},
-		MangledName: "Bar",
-		Type: &ast.FuncType{
-			Ret: &ast.BuiltinType{
-				Kind: ast.Void,
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "a"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+				},
 			},
 		},
 	})
 	if err != nil {
-		t.Fatal("NewFuncDecl failed", err)
+		t.Fatal(err)
 	}
-
+	// int foo_get_size(Foo *f, int *out_ret);
 	err = pkg.NewFuncDecl(&ast.FuncDecl{
-		Name:        &ast.Ident{Name: "Bar"},
-		MangledName: "Bar",
-		Type:        &ast.FuncType{},
-	})
-	if err == nil {
-		t.Fatal("Expect a redefine err")
-	}
-
-	err = pkg.NewEnumTypeDecl(&ast.EnumTypeDecl{
-		Name: &ast.Ident{Name: "Foo"},
-		Type: &ast.EnumType{},
-	})
-
-	if err == nil {
-		t.Fatal("Expect a redefine err")
-	}
-
-	err = pkg.NewEnumTypeDecl(&ast.EnumTypeDecl{
-		Name: nil,
-		Type: &ast.EnumType{
-			Items: []*ast.EnumItem{
-				{Name: &ast.Ident{Name: "Foo"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+		Name:        &ast.Ident{Name: "foo_get_size"},
+		MangledName: "foo_get_size",
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "f"}}, Type: &ast.PointerType{X: &ast.Ident{Name: "Foo"}}},
+					{Names: []*ast.Ident{{Name: "ret"}}, Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Int}}},
+					{Names: []*ast.Ident{{Name: "size_out"}}, Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Int}}},
+				},
 			},
+			Ret: &ast.BuiltinType{Kind: ast.Int},
 		},
 	})
-
-	if err == nil {
-		t.Fatal("Expect a redefine err")
-	}
-
-	var buf bytes.Buffer
-	err = pkg.GetGenPackage().WriteTo(&buf)
 	if err != nil {
-		t.Fatalf("WriteTo failed: %v", err)
+		t.Fatal(err)
 	}
-
-	expect := `
+	if err := pkg.WritePkgFiles(); err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, pkg, `
 package testpkg
 
 import (
@@ -1253,157 +1371,282 @@ import (
 )
 
 type Foo struct {
-	c.Int
+	A c.Int
 }
-//go:linkname Bar C.Bar
-func Bar()
-`
-	comparePackageOutput(t, pkg, expect)
+// llgo:link (*Foo).GetSize C.foo_get_size
+func (recv_ *Foo) GetSize(ret *c.Int, size_out *c.Int) c.Int {
+	return 0
+}
+// GetSizeOut wraps GetSize and returns its out parameters as results.
+func (recv_ *Foo) GetSizeOut(ret *c.Int) (ret_ c.Int, size_out c.Int) {
+	ret_ = recv_.GetSize(ret, &size_out)
+	return
+}`)
 }
 
-func TestTypedef(t *testing.T) {
-	testCases := []genDeclTestCase{
-		// typedef double DOUBLE;
+func TestErrorConventionWrapper(t *testing.T) {
+	intParam := func(name string) *ast.Field {
+		return &ast.Field{Names: []*ast.Ident{{Name: name}}, Type: &ast.BuiltinType{Kind: ast.Int}}
+	}
+	testCases := []struct {
+		name     string
+		conv     *cppgtypes.ErrorConvention
+		decls    []*ast.FuncDecl
+		symbs    []cfg.SymbolEntry
+		expected string
+	}{
 		{
-			name: "typedef double",
-			decl: &ast.TypedefDecl{
-				Name: &ast.Ident{Name: "DOUBLE"},
-				Type: &ast.BuiltinType{
-					Kind:  ast.Float,
-					Flags: ast.Double,
+			name: "nonzero with message",
+			conv: &cppgtypes.ErrorConvention{
+				Funcs:   []string{"sqlite3_*"},
+				Failure: cppgtypes.FailureNonZero,
+				Message: "sqlite3_errstr",
+			},
+			decls: []*ast.FuncDecl{
+				// const char *sqlite3_errstr(int);
+				{
+					Name:        &ast.Ident{Name: "sqlite3_errstr"},
+					MangledName: "sqlite3_errstr",
+					Type: &ast.FuncType{
+						Params: &ast.FieldList{List: []*ast.Field{{Type: &ast.BuiltinType{Kind: ast.Int}}}},
+						Ret:    &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}},
+					},
+				},
+				// int sqlite3_sleep(int ms);
+				{
+					Name:        &ast.Ident{Name: "sqlite3_sleep"},
+					MangledName: "sqlite3_sleep",
+					Type: &ast.FuncType{
+						Params: &ast.FieldList{List: []*ast.Field{intParam("ms")}},
+						Ret:    &ast.BuiltinType{Kind: ast.Int},
+					},
 				},
 			},
+			symbs: []cfg.SymbolEntry{
+				{CppName: "sqlite3_errstr", MangleName: "sqlite3_errstr", GoName: "Errstr"},
+				{CppName: "sqlite3_sleep", MangleName: "sqlite3_sleep", GoName: "Sleep"},
+			},
 			expected: `
 package testpkg
 
-import _ "unsafe"
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
 
-type DOUBLE float64`,
+//go:linkname Errstr C.sqlite3_errstr
+func Errstr(c.Int) *int8
+//go:linkname Sleep C.sqlite3_sleep
+func Sleep(ms c.Int) c.Int
+// Error is an error code reported by a function of testpkg.
+type Error c.Int
+
+func (e Error) Error() string {
+	return c.GoString(Errstr(c.Int(e)))
+}
+// SleepErr wraps Sleep and reports failures as an error.
+func SleepErr(ms c.Int) (err error) {
+	ret := Sleep(ms)
+	if ret != 0 {
+		err = Error(ret)
+	}
+	return
+}`,
 		},
-		// invalid typedef
 		{
-			name: "invalid typedef",
-			decl: &ast.TypedefDecl{
-				Name: &ast.Ident{Name: "INVALID"},
-				Type: &ast.BuiltinType{
-					Kind:  ast.Bool,
-					Flags: ast.Double,
-				},
+			name: "unnamed params",
+			conv: &cppgtypes.ErrorConvention{
+				Funcs:   []string{"foo_close"},
+				Failure: cppgtypes.FailureNonZero,
 			},
-			expectedErr: "not found in type map",
-		},
-		// typedef int INT;
-		{
-			name: "typedef int",
-			decl: &ast.TypedefDecl{
-				Name: &ast.Ident{Name: "INT"},
-				Type: &ast.BuiltinType{
-					Kind: ast.Int,
+			decls: []*ast.FuncDecl{
+				// int foo_close(int);
+				{
+					Name:        &ast.Ident{Name: "foo_close"},
+					MangledName: "foo_close",
+					Type: &ast.FuncType{
+						Params: &ast.FieldList{List: []*ast.Field{{Type: &ast.BuiltinType{Kind: ast.Int}}}},
+						Ret:    &ast.BuiltinType{Kind: ast.Int},
+					},
 				},
 			},
+			symbs: []cfg.SymbolEntry{
+				{CppName: "foo_close", MangleName: "foo_close", GoName: "FooClose"},
+			},
 			expected: `
 package testpkg
 
 import (
-"github.com/goplus/llgo/c"
-_ "unsafe"
+	"github.com/goplus/llgo/c"
+	"strconv"
+	_ "unsafe"
 )
 
-type INT c.Int
-			`,
+//go:linkname FooClose C.foo_close
+func FooClose(c.Int) c.Int
+// Error is an error code reported by a function of testpkg.
+type Error c.Int
+
+func (e Error) Error() string {
+	return "testpkg error " + strconv.Itoa(int(e))
+}
+// FooCloseErr wraps FooClose and reports failures as an error.
+func FooCloseErr(__llgo_arg_0 c.Int) (err error) {
+	ret := FooClose(__llgo_arg_0)
+	if ret != 0 {
+		err = Error(ret)
+	}
+	return
+}`,
 		},
 		{
-			name: "typedef array",
-			decl: &ast.TypedefDecl{
-				Name: &ast.Ident{Name: "name"},
-				Type: &ast.ArrayType{
-					Elt: &ast.BuiltinType{
-						Kind:  ast.Char,
-						Flags: ast.Signed,
+			name: "negative with out params",
+			conv: &cppgtypes.ErrorConvention{
+				Funcs:   []string{"foo_read"},
+				Failure: cppgtypes.FailureNegative,
+				Type:    "FooError",
+			},
+			decls: []*ast.FuncDecl{
+				// int foo_read(int fd, int *out_flags);
+				{
+					Name:        &ast.Ident{Name: "foo_read"},
+					MangledName: "foo_read",
+					Type: &ast.FuncType{
+						Params: &ast.FieldList{List: []*ast.Field{
+							intParam("fd"),
+							{Names: []*ast.Ident{{Name: "out_flags"}}, Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Int}}},
+						}},
+						Ret: &ast.BuiltinType{Kind: ast.Int},
 					},
-					Len: &ast.BasicLit{Kind: ast.IntLit, Value: "5"},
 				},
 			},
+			symbs: []cfg.SymbolEntry{
+				{CppName: "foo_read", MangleName: "foo_read", GoName: "FooRead"},
+			},
 			expected: `
 package testpkg
 
-import _ "unsafe"
+import (
+	"github.com/goplus/llgo/c"
+	"strconv"
+	_ "unsafe"
+)
 
-type Name [5]int8`,
+//go:linkname FooRead C.foo_read
+func FooRead(fd c.Int, out_flags *c.Int) c.Int
+// FooError is an error code reported by a function of testpkg.
+type FooError c.Int
+
+func (e FooError) Error() string {
+	return "testpkg error " + strconv.Itoa(int(e))
+}
+// FooReadErr wraps FooRead and returns its out parameters as results, reporting failures as an error.
+func FooReadErr(fd c.Int) (ret c.Int, out_flags c.Int, err error) {
+	ret = FooRead(fd, &out_flags)
+	if ret < 0 {
+		err = FooError(ret)
+	}
+	return
+}`,
 		},
-		// typedef void* ctx;
 		{
-			name: "typedef pointer",
-			decl: &ast.TypedefDecl{
-				Name: &ast.Ident{Name: "ctx"},
-				Type: &ast.PointerType{
-					X: &ast.BuiltinType{
-						Kind: ast.Void,
+			name: "null",
+			conv: &cppgtypes.ErrorConvention{
+				Funcs:   []string{"foo_new"},
+				Failure: cppgtypes.FailureNull,
+			},
+			decls: []*ast.FuncDecl{
+				// void *foo_new(void);
+				{
+					Name:        &ast.Ident{Name: "foo_new"},
+					MangledName: "foo_new",
+					Type: &ast.FuncType{
+						Ret: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}},
 					},
 				},
 			},
+			symbs: []cfg.SymbolEntry{
+				{CppName: "foo_new", MangleName: "foo_new", GoName: "FooNew"},
+			},
 			expected: `
 package testpkg
 
 import "unsafe"
 
-type Ctx unsafe.Pointer`,
-		},
+//go:linkname FooNew C.foo_new
+func FooNew() unsafe.Pointer
+// NullError reports a function of testpkg returning NULL.
+type NullError string
 
-		// typedef char* name;
-		{
-			name: "typedef pointer",
-			decl: &ast.TypedefDecl{
-				Name: &ast.Ident{Name: "name"},
-				Type: &ast.PointerType{
-					X: &ast.BuiltinType{
-						Kind:  ast.Char,
-						Flags: ast.Signed,
-					},
-				},
-			},
-			expected: `
-package testpkg
-import _ "unsafe"
-type Name *int8`,
-		},
-		{
-			name: "typedef invalid pointer",
-			decl: &ast.TypedefDecl{
-				Name: &ast.Ident{Name: "name"},
-				Type: &ast.PointerType{
-					X: &ast.BuiltinType{
-						Kind:  ast.Char,
-						Flags: ast.Double,
-					},
-				},
-			},
-			expectedErr: "error convert baseType",
+func (e NullError) Error() string {
+	return string(e)
+}
+// FooNewErr wraps FooNew and reports failures as an error.
+func FooNewErr() (ret unsafe.Pointer, err error) {
+	ret = FooNew()
+	if ret == nil {
+		err = NullError("foo_new returned NULL")
+	}
+	return
+}`,
 		},
 	}
-
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			testGenDecl(t, tc)
+			pkg := createTestPkg(t, &convert.PackageConfig{
+				SymbolTable: cfg.CreateSymbolTable(tc.symbs),
+				PkgBase: convert.PkgBase{
+					CppgConf: &cppgtypes.Config{
+						ErrorConventions: []*cppgtypes.ErrorConvention{tc.conv},
+					},
+				},
+			})
+			for _, decl := range tc.decls {
+				if err := pkg.NewFuncDecl(decl); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := pkg.WritePkgFiles(); err != nil {
+				t.Fatal(err)
+			}
+			comparePackageOutput(t, pkg, tc.expected)
 		})
 	}
 }
 
-func TestEnumDecl(t *testing.T) {
-	testCases := []genDeclTestCase{
-		{
-			name: "enum",
-			decl: &ast.EnumTypeDecl{
-				Name: &ast.Ident{Name: "Color"},
-				Type: &ast.EnumType{
-					Items: []*ast.EnumItem{
-						{Name: &ast.Ident{Name: "Red"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
-						{Name: &ast.Ident{Name: "Green"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
-						{Name: &ast.Ident{Name: "Blue"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "2"}},
-					},
-				},
+func TestWrapperNameConflict(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		SymbolTable: cfg.CreateSymbolTable(
+			[]cfg.SymbolEntry{
+				{CppName: "foo_get_size", MangleName: "foo_get_size", GoName: "FooGetSize"},
+				{CppName: "foo_get_size_out", MangleName: "foo_get_size_out", GoName: "FooGetSizeOut"},
 			},
-			expected: `
+		),
+	})
+	outParam := &ast.Field{
+		Names: []*ast.Ident{{Name: "size_out"}},
+		Type:  &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Int}},
+	}
+	// void foo_get_size(int *size_out);
+	// void foo_get_size_out(int *size_out);
+	for _, name := range []string{"foo_get_size", "foo_get_size_out"} {
+		err := pkg.NewFuncDecl(&ast.FuncDecl{
+			Name:        &ast.Ident{Name: name},
+			MangledName: name,
+			Type: &ast.FuncType{
+				Params: &ast.FieldList{List: []*ast.Field{outParam}},
+				Ret:    &ast.BuiltinType{Kind: ast.Void},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pkg.WritePkgFiles(); err != nil {
+		t.Fatal(err)
+	}
+	// the wrapper of foo_get_size is skipped, not the binding of foo_get_size_out
+	comparePackageOutput(t, pkg, `
 package testpkg
 
 import (
@@ -1411,26 +1654,2491 @@ import (
 	_ "unsafe"
 )
 
-type Color c.Int
-const (
-	ColorRed   Color = 0
-	ColorGreen Color = 1
-	ColorBlue  Color = 2
-)`,
+//go:linkname FooGetSize C.foo_get_size
+func FooGetSize(size_out *c.Int)
+//go:linkname FooGetSizeOut C.foo_get_size_out
+func FooGetSizeOut(size_out *c.Int)
+// FooGetSizeOutOut wraps FooGetSizeOut and returns its out parameters as results.
+func FooGetSizeOutOut() (size_out c.Int) {
+	FooGetSizeOut(&size_out)
+	return
+}`)
+}
+
+func TestExceptionWrapper(t *testing.T) {
+	tempDir, err := os.MkdirTemp(dir, "test_package_exc")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		OutputDir: tempDir,
+		SymbolTable: cfg.CreateSymbolTable(
+			[]cfg.SymbolEntry{
+				{CppName: "Foo::get(int)", MangleName: "_ZN3Foo3getEi", GoName: "(*Foo).Get"},
+				{CppName: "Foo::reset()", MangleName: "_ZN3Foo5resetEv", GoName: "(*Foo).Reset"},
+				{CppName: "Foo::size()", MangleName: "_ZNK3Foo4sizeEv", GoName: "(*Foo).Size"},
+				{CppName: "ns::parse(const char *)", MangleName: "_ZN2ns5parseEPKc", GoName: "Parse"},
+			},
+		),
+		PkgBase: convert.PkgBase{
+			CppgConf: &cppgtypes.Config{
+				CFlags:     "$(pkg-config --cflags foo)",
+				Libs:       "$(pkg-config --libs foo)",
+				Cplusplus:  true,
+				Exceptions: []string{"Foo::[gr]*", "ns::parse"},
+			},
+		},
+	})
+	pkg.SetCurFile(&convert.HeaderFile{
+		File:         "/path/to/testpkg.h",
+		IncPath:      "testpkg.h",
+		IsHeaderFile: true,
+		InCurPkg:     true,
+	})
+	foo := &ast.Ident{Name: "Foo"}
+	// class Foo {
+	//     int v;
+	// public:
+	//     int get(int i);
+	//     void reset();
+	//     int size() const;
+	// };
+	err = pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: foo,
+		Type: &ast.RecordType{
+			Tag: ast.Class,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "v"}}, Type: &ast.BuiltinType{Kind: ast.Int}, Access: ast.Private},
+				},
+			},
 		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decls := []*ast.FuncDecl{
 		{
-			name: "anonymous enum",
-			decl: &ast.EnumTypeDecl{
-				Name: nil,
-				Type: &ast.EnumType{
-					Items: []*ast.EnumItem{
-						{Name: &ast.Ident{Name: "red"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
-						{Name: &ast.Ident{Name: "green"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
-						{Name: &ast.Ident{Name: "blue"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "2"}},
+			DeclBase:    ast.DeclBase{Parent: foo},
+			Name:        &ast.Ident{Name: "get"},
+			MangledName: "_ZN3Foo3getEi",
+			Type: &ast.FuncType{
+				Params: &ast.FieldList{List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "i"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+				}},
+				Ret: &ast.BuiltinType{Kind: ast.Int},
+			},
+		},
+		{
+			DeclBase:    ast.DeclBase{Parent: foo},
+			Name:        &ast.Ident{Name: "reset"},
+			MangledName: "_ZN3Foo5resetEv",
+			Type:        &ast.FuncType{Params: &ast.FieldList{}, Ret: &ast.BuiltinType{Kind: ast.Void}},
+		},
+		{
+			DeclBase:    ast.DeclBase{Parent: foo},
+			Name:        &ast.Ident{Name: "size"},
+			MangledName: "_ZNK3Foo4sizeEv",
+			Type:        &ast.FuncType{Params: &ast.FieldList{}, Ret: &ast.BuiltinType{Kind: ast.Int}},
+			IsConst:     true,
+		},
+		// namespace ns { Foo &parse(const char *s); }
+		{
+			DeclBase:    ast.DeclBase{Parent: &ast.Ident{Name: "ns"}},
+			Name:        &ast.Ident{Name: "parse"},
+			MangledName: "_ZN2ns5parseEPKc",
+			Type: &ast.FuncType{
+				Params: &ast.FieldList{List: []*ast.Field{
+					{
+						Names: []*ast.Ident{{Name: "s"}},
+						Type: &ast.PointerType{X: &ast.QualifiedType{
+							X:          &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed},
+							Qualifiers: ast.Const,
+						}},
 					},
-				},
+				}},
+				Ret: &ast.LvalueRefType{X: foo},
 			},
-			expected: `
+		},
+	}
+	for _, decl := range decls {
+		if err := pkg.NewFuncDecl(decl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pkg.WritePkgFiles(); err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import (
+	"errors"
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+
+type Foo struct {
+	V c.Int
+}
+// llgo:link (*Foo).Get C._ZN3Foo3getEi
+func (recv_ *Foo) Get(i c.Int) c.Int {
+	return 0
+}
+// llgo:link (*Foo).Reset C._ZN3Foo5resetEv
+func (recv_ *Foo) Reset() {
+}
+// llgo:link (*Foo).Size C._ZNK3Foo4sizeEv
+func (recv_ *Foo) Size() c.Int {
+	return 0
+}
+//go:linkname Parse C._ZN2ns5parseEPKc
+func Parse(s *int8) *Foo
+//go:linkname exc_Foo_Get C.llcppg_exc__ZN3Foo3getEi
+func exc_Foo_Get(self *Foo, i c.Int, exc **int8) c.Int
+// GetErr wraps Get and returns a C++ exception thrown by it as an error.
+func (recv_ *Foo) GetErr(i c.Int) (ret c.Int, err error) {
+	var exc *int8
+	ret = exc_Foo_Get(recv_, i, &exc)
+	if exc != nil {
+		err = errors.New(c.GoString(exc))
+		c.Free(unsafe.Pointer(exc))
+	}
+	return
+}
+//go:linkname exc_Foo_Reset C.llcppg_exc__ZN3Foo5resetEv
+func exc_Foo_Reset(self *Foo, exc **int8)
+// ResetErr wraps Reset and returns a C++ exception thrown by it as an error.
+func (recv_ *Foo) ResetErr() (err error) {
+	var exc *int8
+	exc_Foo_Reset(recv_, &exc)
+	if exc != nil {
+		err = errors.New(c.GoString(exc))
+		c.Free(unsafe.Pointer(exc))
+	}
+	return
+}
+//go:linkname exc_Parse C.llcppg_exc__ZN2ns5parseEPKc
+func exc_Parse(s *int8, exc **int8) *Foo
+// ParseErr wraps Parse and returns a C++ exception thrown by it as an error.
+func ParseErr(s *int8) (ret *Foo, err error) {
+	var exc *int8
+	ret = exc_Parse(s, &exc)
+	if exc != nil {
+		err = errors.New(c.GoString(exc))
+		c.Free(unsafe.Pointer(exc))
+	}
+	return
+}`)
+	if _, err := pkg.WriteLinkFile(); err != nil {
+		t.Fatal(err)
+	}
+	link, err := os.ReadFile(filepath.Join(tempDir, "testpkg_autogen_link.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(link), `const LLGoFiles string = "$(pkg-config --cflags foo): _wrap/testpkg_autogen.cpp"`) {
+		t.Fatalf("LLGoFiles not found in link file:\n%s", link)
+	}
+	shims, err := os.ReadFile(filepath.Join(tempDir, "_wrap", "testpkg_autogen.cpp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(shims) != `// Code generated by gogensig. DO NOT EDIT.
+
+#include <cstring>
+#include <exception>
+#include <new>
+#include <type_traits>
+#include <utility>
+#include <testpkg.h>
+
+extern "C" int llcppg_exc__ZN3Foo3getEi(Foo *self, int a0, char **exc) {
+	try {
+		return self->get(a0);
+	} catch (const std::exception &e) {
+		*exc = strdup(e.what());
+	} catch (...) {
+		*exc = strdup("unknown C++ exception");
+	}
+	return {};
+}
+
+extern "C" void llcppg_exc__ZN3Foo5resetEv(Foo *self, char **exc) {
+	try {
+		self->reset();
+	} catch (const std::exception &e) {
+		*exc = strdup(e.what());
+	} catch (...) {
+		*exc = strdup("unknown C++ exception");
+	}
+}
+
+extern "C" Foo *llcppg_exc__ZN2ns5parseEPKc(char const *a0, char **exc) {
+	try {
+		return &ns::parse(a0);
+	} catch (const std::exception &e) {
+		*exc = strdup(e.what());
+	} catch (...) {
+		*exc = strdup("unknown C++ exception");
+	}
+	return {};
+}
+` {
+		t.Fatalf("unexpected shims:\n%s", shims)
+	}
+}
+
+func TestStdTypes(t *testing.T) {
+	tempDir, err := os.MkdirTemp(dir, "test_package_std")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		OutputDir: tempDir,
+		SymbolTable: cfg.CreateSymbolTable(
+			[]cfg.SymbolEntry{
+				{CppName: "split(const std::string &, char)", MangleName: "_Z5splitRKNSt7__cxx1112basic_stringIcSt11char_traitsIcESaIcEEEc", GoName: "Split"},
+				{CppName: "range(int)", MangleName: "_Z5rangei", GoName: "Range"},
+				{CppName: "each(std::span<const int>, std::function<bool (int)>)", MangleName: "_Z4eachSt4spanIKiLm18446744073709551615EESt8functionIFbiEE", GoName: "Each"},
+				{CppName: "Foo::name()", MangleName: "_ZNK3Foo4nameEv", GoName: "(*Foo).Name"},
+			},
+		),
+		PkgBase: convert.PkgBase{
+			CppgConf: &cppgtypes.Config{
+				CFlags:     "$(pkg-config --cflags foo)",
+				Libs:       "$(pkg-config --libs foo)",
+				Cplusplus:  true,
+				Exceptions: []string{"Foo::name"},
+			},
+		},
+	})
+	pkg.SetCurFile(&convert.HeaderFile{
+		File:         "/path/to/testpkg.h",
+		IncPath:      "testpkg.h",
+		IsHeaderFile: true,
+		InCurPkg:     true,
+	})
+	std := &ast.Ident{Name: "std"}
+	stdName := func(name string) ast.Expr {
+		return &ast.ScopingExpr{Parent: std, X: &ast.Ident{Name: name}}
+	}
+	instance := func(template ast.Expr, args ...ast.Expr) ast.Expr {
+		list := &ast.FieldList{}
+		for _, arg := range args {
+			list.List = append(list.List, &ast.Field{Type: arg})
+		}
+		return &ast.InstantiationType{Template: template, Args: list}
+	}
+	char := &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}
+	cxx11String := instance(&ast.ScopingExpr{
+		Parent: &ast.ScopingExpr{Parent: std, X: &ast.Ident{Name: "__cxx11"}},
+		X:      &ast.Ident{Name: "basic_string"},
+	}, char)
+	intFn := &ast.FuncType{
+		Params: &ast.FieldList{List: []*ast.Field{{Type: &ast.BuiltinType{Kind: ast.Int}}}},
+		Ret:    &ast.BuiltinType{Kind: ast.Bool},
+	}
+	foo := &ast.Ident{Name: "Foo"}
+	// class Foo {
+	//     int v;
+	// public:
+	//     std::string name() const;
+	// };
+	err = pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: foo,
+		Type: &ast.RecordType{
+			Tag: ast.Class,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "v"}}, Type: &ast.BuiltinType{Kind: ast.Int}, Access: ast.Private},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decls := []*ast.FuncDecl{
+		// std::vector<std::string> split(const std::string &s, char sep);
+		{
+			Name:        &ast.Ident{Name: "split"},
+			MangledName: "_Z5splitRKNSt7__cxx1112basic_stringIcSt11char_traitsIcESaIcEEEc",
+			Type: &ast.FuncType{
+				Params: &ast.FieldList{List: []*ast.Field{
+					{
+						Names: []*ast.Ident{{Name: "s"}},
+						Type:  &ast.LvalueRefType{X: &ast.QualifiedType{X: stdName("string"), Qualifiers: ast.Const}},
+					},
+					{Names: []*ast.Ident{{Name: "sep"}}, Type: char},
+				}},
+				Ret: instance(stdName("vector"), cxx11String),
+			},
+		},
+		// std::vector<int> range(int n);
+		{
+			Name:        &ast.Ident{Name: "range"},
+			MangledName: "_Z5rangei",
+			Type: &ast.FuncType{
+				Params: &ast.FieldList{List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "n"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+				}},
+				Ret: instance(stdName("vector"), &ast.BuiltinType{Kind: ast.Int}),
+			},
+		},
+		// void each(std::span<const int> v, std::function<bool(int)> fn);
+		{
+			Name:        &ast.Ident{Name: "each"},
+			MangledName: "_Z4eachSt4spanIKiLm18446744073709551615EESt8functionIFbiEE",
+			Type: &ast.FuncType{
+				Params: &ast.FieldList{List: []*ast.Field{
+					{
+						Names: []*ast.Ident{{Name: "v"}},
+						Type:  instance(stdName("span"), &ast.QualifiedType{X: &ast.BuiltinType{Kind: ast.Int}, Qualifiers: ast.Const}),
+					},
+					{Names: []*ast.Ident{{Name: "fn"}}, Type: instance(stdName("function"), intFn)},
+				}},
+				Ret: &ast.BuiltinType{Kind: ast.Void},
+			},
+		},
+		{
+			DeclBase:    ast.DeclBase{Parent: foo},
+			Name:        &ast.Ident{Name: "name"},
+			MangledName: "_ZNK3Foo4nameEv",
+			Type:        &ast.FuncType{Params: &ast.FieldList{}, Ret: stdName("string")},
+			IsConst:     true,
+		},
+	}
+	for _, decl := range decls {
+		if err := pkg.NewFuncDecl(decl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import (
+	"errors"
+	"github.com/goplus/llgo/c"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+type Foo struct {
+	V c.Int
+}
+// stdFree frees the data of the Go string or slice that header points to, allocated by a shim.
+//go:linkname stdFree C.llcppg_std_free
+func stdFree(header unsafe.Pointer)
+// stdString returns a copy of the string s allocated by a shim and frees s.
+func stdString(s string) string {
+	ret := strings.Clone(s)
+	stdFree(unsafe.Pointer(&s))
+	return ret
+}
+// stdStrings returns a copy of the strings s allocated by a shim and frees s.
+func stdStrings(s []string) []string {
+	ret := make([]string, len(s))
+	for i, e := range s {
+		ret[i] = stdString(e)
+	}
+	stdFree(unsafe.Pointer(&s))
+	return ret
+}
+//go:linkname std_Split C.llcppg_std__Z5splitRKNSt7__cxx1112basic_stringIcSt11char_traitsIcESaIcEEEc
+func std_Split(ret *[]string, s *string, sep int8)
+func Split(s string, sep int8) (ret []string) {
+	var r []string
+	std_Split(&r, &s, sep)
+	ret = stdStrings(r)
+	return
+}
+//go:linkname std_Range C.llcppg_std__Z5rangei
+func std_Range(ret *[]c.Int, n c.Int)
+func Range(n c.Int) (ret []c.Int) {
+	var r []c.Int
+	std_Range(&r, n)
+	ret = append(ret, r...)
+	stdFree(unsafe.Pointer(&r))
+	return
+}
+// stdCallFunc0 is the C function type of stdCall0.
+// llgo:type C
+type stdCallFunc0 func(fn unsafe.Pointer, a0 c.Int) bool
+// stdCall0 calls the Go func(c.Int) bool that fn points to.
+func stdCall0(fn unsafe.Pointer, a0 c.Int) bool {
+	return (*(*func(c.Int) bool)(fn))(a0)
+}
+// stdReleaseFunc is the C function type of stdRelease.
+// llgo:type C
+type stdReleaseFunc func(fn unsafe.Pointer)
+// stdFuncs keeps the Go funcs passed to C++ alive until they are released.
+var stdFuncs sync.Map
+// stdKeep keeps the Go func that fn points to alive until stdRelease(fn).
+func stdKeep(fn unsafe.Pointer) unsafe.Pointer {
+	stdFuncs.Store(fn, nil)
+	return fn
+}
+// stdRelease is called by C++ when the last copy of the std::function of fn is destroyed.
+func stdRelease(fn unsafe.Pointer) {
+	stdFuncs.Delete(fn)
+}
+//go:linkname std_Each C.llcppg_std__Z4eachSt4spanIKiLm18446744073709551615EESt8functionIFbiEE
+func std_Each(v *[]c.Int, fn_call stdCallFunc0, fn unsafe.Pointer, fn_release stdReleaseFunc)
+func Each(v []c.Int, fn func(c.Int) bool) {
+	std_Each(&v, stdCall0, stdKeep(unsafe.Pointer(&fn)), stdRelease)
+}
+//go:linkname std_Foo_Name C.llcppg_std__ZNK3Foo4nameEv
+func std_Foo_Name(ret *string, self *Foo, exc **int8)
+func (recv_ *Foo) Name() (ret string, err error) {
+	var r string
+	var exc *int8
+	std_Foo_Name(&r, recv_, &exc)
+	ret = stdString(r)
+	if exc != nil {
+		err = errors.New(c.GoString(exc))
+		c.Free(unsafe.Pointer(exc))
+	}
+	return
+}`)
+	if _, err := pkg.WriteLinkFile(); err != nil {
+		t.Fatal(err)
+	}
+	shims, err := os.ReadFile(filepath.Join(tempDir, "_wrap", "testpkg_autogen.cpp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(shims), `extern "C" void llcppg_std__Z5splitRKNSt7__cxx1112basic_stringIcSt11char_traitsIcESaIcEEEc(llcppg::slice *ret, const llcppg::string *a0, char a1) {
+	std::string v0(a0->data, a0->len);
+	llcppg::from_strings(ret, split(v0, a1));
+}
+
+extern "C" void llcppg_std__Z5rangei(llcppg::slice *ret, int a0) {
+	llcppg::from_slice(ret, range(a0));
+}
+
+extern "C" void llcppg_std__Z4eachSt4spanIKiLm18446744073709551615EESt8functionIFbiEE(const llcppg::slice *a0, bool (*a1_call)(void *, int), void *a1, void (*a1_release)(void *)) {
+	auto v0 = llcppg::to_span<std::span<int const>>(a0);
+	std::function<bool (int)> v1 = llcppg::to_function(a1_call, a1, a1_release);
+	each(std::move(v0), std::move(v1));
+}
+
+extern "C" void llcppg_std__ZNK3Foo4nameEv(llcppg::string *ret, Foo *self, char **exc) {
+	try {
+		llcppg::from_string(ret, self->name());
+	} catch (const std::exception &e) {
+		*exc = strdup(e.what());
+	} catch (...) {
+		*exc = strdup("unknown C++ exception");
+	}
+}
+`) {
+		t.Fatalf("unexpected shims:\n%s", shims)
+	}
+}
+
+func TestBaseClasses(t *testing.T) {
+	tempDir, err := os.MkdirTemp(dir, "test_package_bases")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		OutputDir: tempDir,
+		SymbolTable: cfg.CreateSymbolTable(
+			[]cfg.SymbolEntry{
+				{CppName: "Shape::area()", MangleName: "_ZNK5Shape4areaEv", GoName: "(*Shape).Area"},
+				{CppName: "Circle::area()", MangleName: "_ZNK6Circle4areaEv", GoName: "(*Circle).Area"},
+				{CppName: "Named::name()", MangleName: "_ZNK5Named4nameEv", GoName: "(*Named).Name"},
+			},
+		),
+		PkgBase: convert.PkgBase{
+			CppgConf: &cppgtypes.Config{
+				CFlags:    "$(pkg-config --cflags foo)",
+				Libs:      "$(pkg-config --libs foo)",
+				Cplusplus: true,
+			},
+		},
+	})
+	pkg.SetCurFile(&convert.HeaderFile{
+		File:         "/path/to/testpkg.h",
+		IncPath:      "testpkg.h",
+		IsHeaderFile: true,
+		InCurPkg:     true,
+	})
+	shape := &ast.Ident{Name: "Shape"}
+	circle := &ast.Ident{Name: "Circle"}
+	named := &ast.Ident{Name: "Named"}
+	integer := &ast.BuiltinType{Kind: ast.Int}
+	double := &ast.BuiltinType{Kind: ast.Float, Flags: ast.Double}
+	area := func(parent *ast.Ident, mangled string) *ast.FuncDecl {
+		return &ast.FuncDecl{
+			DeclBase:    ast.DeclBase{Parent: parent},
+			Name:        &ast.Ident{Name: "area"},
+			MangledName: mangled,
+			Type:        &ast.FuncType{Params: &ast.FieldList{}, Ret: double},
+			IsConst:     true,
+			IsVirtual:   true,
+		}
+	}
+	shapeArea := area(shape, "_ZNK5Shape4areaEv")
+	circleArea := area(circle, "_ZNK6Circle4areaEv")
+	circleArea.IsOverride = true
+	nameMethod := &ast.FuncDecl{
+		DeclBase:    ast.DeclBase{Parent: named},
+		Name:        &ast.Ident{Name: "name"},
+		MangledName: "_ZNK5Named4nameEv",
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{},
+			Ret:    &ast.PointerType{X: &ast.QualifiedType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, Qualifiers: ast.Const}},
+		},
+		IsConst: true,
+	}
+	field := func(name string, typ ast.Expr) *ast.Field {
+		return &ast.Field{Names: []*ast.Ident{{Name: name}}, Type: typ, Access: ast.Public}
+	}
+	typeDecls := []*ast.TypeDecl{
+		// class Shape {
+		// public:
+		//     virtual double area() const;
+		//     int id;
+		// };
+		{
+			Name: shape,
+			Type: &ast.RecordType{
+				Tag:     ast.Class,
+				Fields:  &ast.FieldList{List: []*ast.Field{field("id", integer)}},
+				Methods: []*ast.FuncDecl{shapeArea},
+				Layout:  &ast.RecordLayout{Size: 16, Align: 8, Offsets: []int64{8}},
+			},
+		},
+		// class Circle : public Shape {
+		// public:
+		//     double area() const override;
+		//     double r;
+		// };
+		{
+			Name: circle,
+			Type: &ast.RecordType{
+				Tag:     ast.Class,
+				Fields:  &ast.FieldList{List: []*ast.Field{field("r", double)}},
+				Methods: []*ast.FuncDecl{circleArea},
+				Layout:  &ast.RecordLayout{Size: 24, Align: 8, Offsets: []int64{16}},
+				Bases:   []*ast.BaseSpec{{Type: shape, Access: ast.Public}},
+			},
+		},
+		// class Named {
+		// public:
+		//     const char *name() const;
+		//     long n;
+		// };
+		{
+			Name: named,
+			Type: &ast.RecordType{
+				Tag:     ast.Class,
+				Fields:  &ast.FieldList{List: []*ast.Field{field("n", &ast.BuiltinType{Kind: ast.Int, Flags: ast.Long})}},
+				Methods: []*ast.FuncDecl{nameMethod},
+			},
+		},
+		// class Widget : public Shape, public Named {
+		// public:
+		//     int w;
+		// };
+		{
+			Name: &ast.Ident{Name: "Widget"},
+			Type: &ast.RecordType{
+				Tag:    ast.Class,
+				Fields: &ast.FieldList{List: []*ast.Field{field("w", integer)}},
+				Layout: &ast.RecordLayout{Size: 32, Align: 8, Offsets: []int64{24}},
+				Bases: []*ast.BaseSpec{
+					{Type: shape, Access: ast.Public},
+					{Type: named, Access: ast.Public, Offset: 16},
+				},
+			},
+		},
+		// class Node : public virtual Named, private Shape {};
+		{
+			Name: &ast.Ident{Name: "Node"},
+			Type: &ast.RecordType{
+				Tag:    ast.Class,
+				Fields: &ast.FieldList{},
+				Layout: &ast.RecordLayout{Size: 24, Align: 8},
+				Bases: []*ast.BaseSpec{
+					{Type: named, Access: ast.Public, Virtual: true, Offset: -1},
+					{Type: shape, Access: ast.Private},
+				},
+			},
+		},
+	}
+	for _, decl := range typeDecls {
+		if err := pkg.NewTypeDecl(decl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, decl := range []*ast.FuncDecl{shapeArea, circleArea, nameMethod} {
+		if err := pkg.NewFuncDecl(decl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pkg.WritePkgFiles(); err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+
+type Shape struct {
+	_  [0]uint64
+	_  [8]byte
+	Id c.Int
+	_  [4]byte
+}
+
+type Circle struct {
+	Shape
+	R float64
+}
+
+type Named struct {
+	N c.Long
+}
+
+type Widget struct {
+	_ [0]uint64
+	_ [24]byte
+	W c.Int
+	_ [4]byte
+}
+
+type Node struct {
+	_ [0]uint64
+	_ [24]byte
+}
+
+// llgo:link (*Shape).Area C._ZNK5Shape4areaEv
+func (recv_ *Shape) Area() float64 {
+	return 0
+}
+
+// llgo:link (*Circle).Area C._ZNK6Circle4areaEv
+func (recv_ *Circle) Area() float64 {
+	return 0
+}
+
+// llgo:link (*Named).Name C._ZNK5Named4nameEv
+func (recv_ *Named) Name() *int8 {
+	return nil
+}
+
+// AsShape returns the Shape base of p.
+func (p *Circle) AsShape() *Shape {
+	return &p.Shape
+}
+
+// CircleFromShape returns the Circle whose Shape base is p, or nil if p is not the base of a Circle.
+//go:linkname CircleFromShape C.llcppg_cast_testpkg_Shape_Circle
+func CircleFromShape(p *Shape) *Circle
+
+// AsShape returns the Shape base of p.
+func (p *Widget) AsShape() *Shape {
+	return (*Shape)(unsafe.Pointer(p))
+}
+
+// WidgetFromShape returns the Widget whose Shape base is p, or nil if p is not the base of a Widget.
+//go:linkname WidgetFromShape C.llcppg_cast_testpkg_Shape_Widget
+func WidgetFromShape(p *Shape) *Widget
+
+// AsNamed returns the Named base of p.
+func (p *Widget) AsNamed() *Named {
+	return (*Named)(unsafe.Add(unsafe.Pointer(p), 16))
+}
+
+// Area calls Area on the Shape base of p.
+func (p *Widget) Area() float64 {
+	return p.AsShape().Area()
+}
+
+// Name calls Name on the Named base of p.
+func (p *Widget) Name() *int8 {
+	return p.AsNamed().Name()
+}
+
+// AsNamed returns the Named base of p.
+// llgo:link (*Node).AsNamed C.llcppg_cast_testpkg_Node_Named
+func (p *Node) AsNamed() *Named {
+	return nil
+}
+
+// Name calls Name on the Named base of p.
+func (p *Node) Name() *int8 {
+	return p.AsNamed().Name()
+}`)
+	if _, err := pkg.WriteLinkFile(); err != nil {
+		t.Fatal(err)
+	}
+	shims, err := os.ReadFile(filepath.Join(tempDir, "_wrap", "testpkg_autogen.cpp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(shims), `extern "C" Circle *llcppg_cast_testpkg_Shape_Circle(Shape *self) {
+	return dynamic_cast<Circle *>(self);
+}
+
+extern "C" Widget *llcppg_cast_testpkg_Shape_Widget(Shape *self) {
+	return dynamic_cast<Widget *>(self);
+}
+
+extern "C" Named *llcppg_cast_testpkg_Node_Named(Node *self) {
+	return self;
+}
+`) {
+		t.Fatalf("unexpected shims:\n%s", shims)
+	}
+}
+
+func TestStaticMembers(t *testing.T) {
+	tempDir, err := os.MkdirTemp(dir, "test_package_static")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		OutputDir: tempDir,
+		SymbolTable: cfg.CreateSymbolTable(
+			[]cfg.SymbolEntry{
+				{CppName: "Counter::create(int)", MangleName: "_ZN7Counter6createEi", GoName: "Counter_Create"},
+				// named like a method by an older llcppsymg
+				{CppName: "Counter::reset()", MangleName: "_ZN7Counter5resetEv", GoName: "(*Counter).Reset"},
+				{CppName: "Counter::get()", MangleName: "_ZN7Counter3getEv", GoName: "(*Counter).Get"},
+				{CppName: "Counter::count", MangleName: "_ZN7Counter5countE", GoName: "Counter_Count"},
+				{CppName: "Counter::names", MangleName: "_ZN7Counter5namesE", GoName: "Counter_Names"},
+				{CppName: "Counter::hidden", MangleName: "_ZN7Counter6hiddenE", GoName: "Counter_Hidden"},
+			},
+		),
+		PkgBase: convert.PkgBase{
+			CppgConf: &cppgtypes.Config{Cplusplus: true},
+		},
+	})
+	pkg.SetCurFile(&convert.HeaderFile{
+		File:         "/path/to/testpkg.h",
+		IncPath:      "testpkg.h",
+		IsHeaderFile: true,
+		InCurPkg:     true,
+	})
+	counter := &ast.Ident{Name: "Counter"}
+	integer := &ast.BuiltinType{Kind: ast.Int}
+	charPtr := &ast.PointerType{X: &ast.QualifiedType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, Qualifiers: ast.Const}}
+	method := func(name, mangled string, params []*ast.Field, ret ast.Expr, static bool) *ast.FuncDecl {
+		return &ast.FuncDecl{
+			DeclBase:    ast.DeclBase{Parent: counter},
+			Name:        &ast.Ident{Name: name},
+			MangledName: mangled,
+			Type:        &ast.FuncType{Params: &ast.FieldList{List: params}, Ret: ret},
+			IsStatic:    static,
+		}
+	}
+	field := func(name string, typ ast.Expr, access ast.AccessSpecifier, static bool) *ast.Field {
+		return &ast.Field{Names: []*ast.Ident{{Name: name}}, Type: typ, Access: access, IsStatic: static}
+	}
+	methods := []*ast.FuncDecl{
+		method("create", "_ZN7Counter6createEi", []*ast.Field{field("n", integer, ast.Public, false)}, &ast.PointerType{X: counter}, true),
+		method("reset", "_ZN7Counter5resetEv", nil, integer, true),
+		method("get", "_ZN7Counter3getEv", nil, integer, false),
+	}
+	// class Counter {
+	// public:
+	//     static Counter *create(int n);
+	//     static int reset();
+	//     int get();
+	//     static int count;
+	//     static const char *names[4];
+	// private:
+	//     static int hidden;
+	//     int n;
+	// };
+	decl := &ast.TypeDecl{
+		Name: counter,
+		Type: &ast.RecordType{
+			Tag: ast.Class,
+			Fields: &ast.FieldList{List: []*ast.Field{
+				field("count", integer, ast.Public, true),
+				field("names", &ast.ArrayType{Elt: charPtr, Len: &ast.BasicLit{Kind: ast.IntLit, Value: "4"}}, ast.Public, true),
+				field("hidden", integer, ast.Private, true),
+				field("n", integer, ast.Private, false),
+			}},
+			Methods: methods,
+		},
+	}
+	if err := pkg.NewTypeDecl(decl); err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range methods {
+		if err := pkg.NewFuncDecl(method); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pkg.WritePkgFiles(); err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Counter struct {
+	N c.Int
+}
+
+//go:linkname Counter_Count _ZN7Counter5countE
+var Counter_Count c.Int
+
+//go:linkname Counter_Names _ZN7Counter5namesE
+var Counter_Names [4]*int8
+
+//go:linkname Counter_Create C._ZN7Counter6createEi
+func Counter_Create(n c.Int) *Counter
+
+//go:linkname Counter_Reset C._ZN7Counter5resetEv
+func Counter_Reset() c.Int
+
+// llgo:link (*Counter).Get C._ZN7Counter3getEv
+func (recv_ *Counter) Get() c.Int {
+	return 0
+}`)
+}
+
+func TestLifecycle(t *testing.T) {
+	fooPtr := &ast.PointerType{X: &ast.Ident{Name: "Foo"}}
+	// Foo *foo_new(int size);
+	fooNew := &ast.FuncDecl{
+		Name:        &ast.Ident{Name: "foo_new"},
+		MangledName: "foo_new",
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{List: []*ast.Field{
+				{Names: []*ast.Ident{{Name: "size"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+			}},
+			Ret: fooPtr,
+		},
+	}
+	// void foo_free(Foo *f);
+	fooFree := &ast.FuncDecl{
+		Name:        &ast.Ident{Name: "foo_free"},
+		MangledName: "foo_free",
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{List: []*ast.Field{
+				{Names: []*ast.Ident{{Name: "f"}}, Type: fooPtr},
+			}},
+			Ret: &ast.BuiltinType{Kind: ast.Void},
+		},
+	}
+	testCases := []struct {
+		name     string
+		conf     *cppgtypes.Lifecycle
+		decls    []*ast.FuncDecl
+		symbs    []cfg.SymbolEntry
+		expected string
+	}{
+		{
+			name: "pattern",
+			conf: &cppgtypes.Lifecycle{Finalizer: true},
+			decls: []*ast.FuncDecl{fooNew, fooFree},
+			symbs: []cfg.SymbolEntry{
+				{CppName: "foo_new", MangleName: "foo_new", GoName: "FooNew"},
+				{CppName: "foo_free", MangleName: "foo_free", GoName: "(*Foo).Free"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"runtime"
+	_ "unsafe"
+)
+
+type Foo struct {
+	A c.Int
+}
+// The result is owned by the caller and must be released with (*Foo).Free.
+//go:linkname FooNew C.foo_new
+func FooNew(size c.Int) *Foo
+// It releases a Foo owned by a FooHandle on Close.
+// llgo:link (*Foo).Free C.foo_free
+func (recv_ *Foo) Free() {
+}
+// FooHandle owns a *Foo and releases it with foo_free on Close or when it becomes unreachable.
+type FooHandle struct {
+	*Foo
+}
+// Close releases the Foo with foo_free.
+// It is safe to call Close more than once.
+func (h *FooHandle) Close() {
+	if h.Foo != nil {
+		h.Foo.Free()
+		h.Foo = nil
+		runtime.SetFinalizer(h, nil)
+	}
+}
+// NewFoo creates a Foo with foo_new.
+// The caller owns the returned handle and must release it with Close.
+func NewFoo(size c.Int) *FooHandle {
+	ptr := FooNew(size)
+	if ptr == nil {
+		return nil
+	}
+	h := &FooHandle{ptr}
+	runtime.SetFinalizer(h, (*FooHandle).Close)
+	return h
+}`,
+		},
+		{
+			name:  "no lifecycle",
+			decls: []*ast.FuncDecl{fooNew, fooFree},
+			symbs: []cfg.SymbolEntry{
+				{CppName: "foo_new", MangleName: "foo_new", GoName: "FooNew"},
+				{CppName: "foo_free", MangleName: "foo_free", GoName: "FooFree"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	A c.Int
+}
+//go:linkname FooNew C.foo_new
+func FooNew(size c.Int) *Foo
+//go:linkname FooFree C.foo_free
+func FooFree(f *Foo)`,
+		},
+		{
+			name: "explicit pair before patterns",
+			conf: &cppgtypes.Lifecycle{
+				Pairs: []*cppgtypes.LifecyclePair{{New: "foo_new", Free: "foo_release"}},
+			},
+			decls: []*ast.FuncDecl{fooNew, fooFree, {
+				// void foo_release(Foo *f);
+				Name:        &ast.Ident{Name: "foo_release"},
+				MangledName: "foo_release",
+				Type:        fooFree.Type,
+			}},
+			symbs: []cfg.SymbolEntry{
+				{CppName: "foo_new", MangleName: "foo_new", GoName: "FooNew"},
+				{CppName: "foo_free", MangleName: "foo_free", GoName: "FooFree"},
+				{CppName: "foo_release", MangleName: "foo_release", GoName: "FooRelease"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	A c.Int
+}
+// The result is owned by the caller and must be released with FooRelease.
+//go:linkname FooNew C.foo_new
+func FooNew(size c.Int) *Foo
+//go:linkname FooFree C.foo_free
+func FooFree(f *Foo)
+// It releases a Foo owned by a FooHandle on Close.
+//go:linkname FooRelease C.foo_release
+func FooRelease(f *Foo)
+// FooHandle owns a *Foo and releases it with foo_release on Close.
+type FooHandle struct {
+	*Foo
+}
+// Close releases the Foo with foo_release.
+// It is safe to call Close more than once.
+func (h *FooHandle) Close() {
+	if h.Foo != nil {
+		FooRelease(h.Foo)
+		h.Foo = nil
+	}
+}
+// NewFoo creates a Foo with foo_new.
+// The caller owns the returned handle and must release it with Close.
+func NewFoo(size c.Int) *FooHandle {
+	ptr := FooNew(size)
+	if ptr == nil {
+		return nil
+	}
+	h := &FooHandle{ptr}
+	return h
+}`,
+		},
+		{
+			name: "explicit pair",
+			conf: &cppgtypes.Lifecycle{
+				Pairs: []*cppgtypes.LifecyclePair{{New: "foo_open", Free: "foo_close"}},
+			},
+			decls: []*ast.FuncDecl{
+				// Foo *foo_open(void);
+				{
+					Name:        &ast.Ident{Name: "foo_open"},
+					MangledName: "foo_open",
+					Type:        &ast.FuncType{Ret: fooPtr},
+				},
+				// int foo_close(Foo *);
+				{
+					Name:        &ast.Ident{Name: "foo_close"},
+					MangledName: "foo_close",
+					Type: &ast.FuncType{
+						Params: &ast.FieldList{List: []*ast.Field{{Type: fooPtr}}},
+						Ret:    &ast.BuiltinType{Kind: ast.Int},
+					},
+				},
+			},
+			symbs: []cfg.SymbolEntry{
+				{CppName: "foo_open", MangleName: "foo_open", GoName: "FooOpen"},
+				{CppName: "foo_close", MangleName: "foo_close", GoName: "FooClose"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	A c.Int
+}
+// The result is owned by the caller and must be released with FooClose.
+//go:linkname FooOpen C.foo_open
+func FooOpen() *Foo
+// It releases a Foo owned by a FooHandle on Close.
+//go:linkname FooClose C.foo_close
+func FooClose(*Foo) c.Int
+// FooHandle owns a *Foo and releases it with foo_close on Close.
+type FooHandle struct {
+	*Foo
+}
+// Close releases the Foo with foo_close.
+// It is safe to call Close more than once.
+func (h *FooHandle) Close() {
+	if h.Foo != nil {
+		FooClose(h.Foo)
+		h.Foo = nil
+	}
+}
+// NewFoo creates a Foo with foo_open.
+// The caller owns the returned handle and must release it with Close.
+func NewFoo() *FooHandle {
+	ptr := FooOpen()
+	if ptr == nil {
+		return nil
+	}
+	h := &FooHandle{ptr}
+	return h
+}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pkg := createTestPkg(t, &convert.PackageConfig{
+				SymbolTable: cfg.CreateSymbolTable(tc.symbs),
+				PkgBase: convert.PkgBase{
+					CppgConf: &cppgtypes.Config{Lifecycle: tc.conf},
+				},
+			})
+			err := pkg.NewTypeDecl(&ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{List: []*ast.Field{
+						{Names: []*ast.Ident{{Name: "a"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+					}},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, decl := range tc.decls {
+				if err := pkg.NewFuncDecl(decl); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := pkg.WritePkgFiles(); err != nil {
+				t.Fatal(err)
+			}
+			comparePackageOutput(t, pkg, tc.expected)
+		})
+	}
+}
+
+func TestFuncFieldAccessors(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		PkgBase: convert.PkgBase{
+			CppgConf: &cppgtypes.Config{FuncFields: []string{"Op*", "Hooks"}},
+		},
+	})
+	// typedef void (*Callback)(void *ctx);
+	err := pkg.NewTypedefDecl(&ast.TypedefDecl{
+		Name: &ast.Ident{Name: "Callback"},
+		Type: &ast.PointerType{X: &ast.FuncType{
+			Params: &ast.FieldList{List: []*ast.Field{
+				{Names: []*ast.Ident{{Name: "ctx"}}, Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}}},
+			}},
+			Ret: &ast.BuiltinType{Kind: ast.Void},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// struct Ops {
+	//	int (*read)(void *ctx, char *buf, int n);
+	//	Callback done;
+	//	void *ctx;
+	// };
+	err = pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "Ops"},
+		Type: &ast.RecordType{
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{List: []*ast.Field{
+				{
+					Names: []*ast.Ident{{Name: "read"}},
+					Type: &ast.PointerType{X: &ast.FuncType{
+						Params: &ast.FieldList{List: []*ast.Field{
+							{Names: []*ast.Ident{{Name: "ctx"}}, Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}}},
+							{Names: []*ast.Ident{{Name: "buf"}}, Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}}},
+							{Names: []*ast.Ident{{Name: "n"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+						}},
+						Ret: &ast.BuiltinType{Kind: ast.Int},
+					}},
+				},
+				{Names: []*ast.Ident{{Name: "done"}}, Type: &ast.Ident{Name: "Callback"}},
+				{Names: []*ast.Ident{{Name: "ctx"}}, Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}}},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// typedef struct { static int count; Callback cb; } Hooks;
+	// typedef struct { static int count; Callback cb; } Other;
+	// a static field isn't a field of the Go struct, the fields are matched by name
+	for _, name := range []string{"Hooks", "Other"} {
+		err = pkg.NewTypedefDecl(&ast.TypedefDecl{
+			Name: &ast.Ident{Name: name},
+			Type: &ast.RecordType{
+				Tag: ast.Struct,
+				Fields: &ast.FieldList{List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "count"}}, Type: &ast.BuiltinType{Kind: ast.Int}, IsStatic: true},
+					{Names: []*ast.Ident{{Name: "cb"}}, Type: &ast.Ident{Name: "Callback"}},
+				}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pkg.WritePkgFiles(); err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+
+// llgo:type C
+type Callback func(ctx unsafe.Pointer)
+
+type Ops struct {
+	Read unsafe.Pointer
+	Done unsafe.Pointer
+	Ctx  unsafe.Pointer
+}
+type Hooks struct {
+	Cb unsafe.Pointer
+}
+type Other struct {
+	Cb unsafe.Pointer
+}
+
+// OpsReadFunc is the C function type of the Read field of Ops.
+// llgo:type C
+type OpsReadFunc func(ctx unsafe.Pointer, buf *int8, n c.Int) c.Int
+
+// ReadFunc returns the Read field of p.
+func (p *Ops) ReadFunc() OpsReadFunc {
+	return *(*OpsReadFunc)(unsafe.Pointer(&p.Read))
+}
+
+// SetReadFunc sets the Read field of p to fn.
+func (p *Ops) SetReadFunc(fn OpsReadFunc) {
+	p.Read = *(*unsafe.Pointer)(unsafe.Pointer(&fn))
+}
+
+// DoneFunc returns the Done field of p.
+func (p *Ops) DoneFunc() Callback {
+	return *(*Callback)(unsafe.Pointer(&p.Done))
+}
+
+// SetDoneFunc sets the Done field of p to fn.
+func (p *Ops) SetDoneFunc(fn Callback) {
+	p.Done = *(*unsafe.Pointer)(unsafe.Pointer(&fn))
+}
+
+// CbFunc returns the Cb field of p.
+func (p *Hooks) CbFunc() Callback {
+	return *(*Callback)(unsafe.Pointer(&p.Cb))
+}
+
+// SetCbFunc sets the Cb field of p to fn.
+func (p *Hooks) SetCbFunc(fn Callback) {
+	p.Cb = *(*unsafe.Pointer)(unsafe.Pointer(&fn))
+}`)
+}
+
+func TestStructDecl(t *testing.T) {
+	testCases := []genDeclTestCase{
+		// struct Foo {}
+		{
+			name: "empty struct",
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag:    ast.Struct,
+					Fields: &ast.FieldList{},
+				},
+			},
+			expected: `
+package testpkg
+
+import _ "unsafe"
+
+type Foo struct {
+}`,
+		},
+		// invalid struct type
+		{
+			name: "invalid struct type",
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "InvalidStruct"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names: []*ast.Ident{{Name: "invalidField"}},
+								Type:  &ast.BuiltinType{Kind: ast.Bool, Flags: ast.Long},
+							},
+						},
+					},
+				},
+			},
+			expectedErr: "not found in type map",
+		},
+		// struct Foo { int a; double b; bool c; }
+		{
+			name: "struct field builtin type",
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names: []*ast.Ident{{Name: "a"}},
+								Type: &ast.BuiltinType{
+									Kind: ast.Int,
+								},
+							},
+							{
+								Names: []*ast.Ident{{Name: "b"}},
+								Type: &ast.BuiltinType{
+									Kind:  ast.Float,
+									Flags: ast.Double,
+								},
+							},
+							{
+								Names: []*ast.Ident{{Name: "c"}},
+								Type: &ast.BuiltinType{
+									Kind: ast.Bool,
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+"github.com/goplus/llgo/c"
+_ "unsafe"
+)
+
+type Foo struct {
+	A c.Int
+	B float64
+	C bool
+}`,
+		},
+		// struct Foo { int* a; double* b; bool* c;void* d; }
+		{
+			name: "struct field pointer",
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names: []*ast.Ident{{Name: "a"}},
+								Type: &ast.PointerType{
+									X: &ast.BuiltinType{
+										Kind: ast.Int,
+									},
+								},
+							},
+							{
+								Names: []*ast.Ident{{Name: "b"}},
+								Type: &ast.PointerType{
+									X: &ast.BuiltinType{
+										Kind:  ast.Float,
+										Flags: ast.Double,
+									}},
+							},
+							{
+								Names: []*ast.Ident{{Name: "c"}},
+								Type: &ast.PointerType{
+									X: &ast.BuiltinType{
+										Kind: ast.Bool,
+									},
+								},
+							},
+							{
+								Names: []*ast.Ident{{Name: "d"}},
+								Type: &ast.PointerType{
+									X: &ast.BuiltinType{
+										Kind: ast.Void,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+
+type Foo struct {
+	A *c.Int
+	B *float64
+	C *bool
+	D unsafe.Pointer
+}`},
+		// struct Foo { char a[4]; int b[3][4]; }
+		{
+			name: "struct array field",
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names: []*ast.Ident{{Name: "a"}},
+								Type: &ast.ArrayType{
+									Elt: &ast.BuiltinType{
+										Kind:  ast.Char,
+										Flags: ast.Signed,
+									},
+									Len: &ast.BasicLit{
+										Kind:  ast.IntLit,
+										Value: "4",
+									},
+								},
+							},
+							{
+								Names: []*ast.Ident{{Name: "b"}},
+								Type: &ast.ArrayType{
+									Elt: &ast.ArrayType{
+										Elt: &ast.BuiltinType{
+											Kind: ast.Int,
+										},
+										Len: &ast.BasicLit{Kind: ast.IntLit, Value: "4"},
+									},
+									Len: &ast.BasicLit{Kind: ast.IntLit, Value: "3"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+"github.com/goplus/llgo/c"
+_ "unsafe"
+)
+
+type Foo struct {
+	A [4]int8
+	B [3][4]c.Int
+}`},
+		{
+			name: "struct array field",
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names: []*ast.Ident{{Name: "a"}},
+								Type: &ast.ArrayType{
+									Elt: &ast.BuiltinType{
+										Kind:  ast.Char,
+										Flags: ast.Signed,
+									},
+									Len: &ast.BasicLit{
+										Kind:  ast.IntLit,
+										Value: "4",
+									},
+								},
+							},
+							{
+								Names: []*ast.Ident{{Name: "b"}},
+								Type: &ast.ArrayType{
+									Elt: &ast.ArrayType{
+										Elt: &ast.BuiltinType{
+											Kind: ast.Int,
+										},
+										Len: &ast.BasicLit{Kind: ast.IntLit, Value: "4"},
+									},
+									Len: &ast.BasicLit{Kind: ast.IntLit, Value: "3"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+"github.com/goplus/llgo/c"
+_ "unsafe"
+)
+
+type Foo struct {
+	A [4]int8
+	B [3][4]c.Int
+}`},
+		{
+			name: "anonymous struct",
+			decl: &ast.TypeDecl{
+				Name: nil,
+				Type: &ast.RecordType{
+					Tag:    ast.Struct,
+					Fields: &ast.FieldList{},
+				},
+			},
+			expected: `
+package testpkg
+import _ "unsafe"
+			`},
+		{
+			name: "struct array field without len",
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names: []*ast.Ident{{Name: "a"}},
+								Type: &ast.ArrayType{
+									Elt: &ast.BuiltinType{
+										Kind:  ast.Char,
+										Flags: ast.Signed,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedErr: "unsupport field with array without length",
+		},
+		{
+			name: "struct array field without len",
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names: []*ast.Ident{{Name: "a"}},
+								Type: &ast.ArrayType{
+									Elt: &ast.BuiltinType{
+										Kind:  ast.Char,
+										Flags: ast.Signed,
+									},
+									Len: &ast.BuiltinType{Kind: ast.TypeKind(ast.Signed)}, //invalid
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedErr: "can't determine the array length",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestTypedefFunc(t *testing.T) {
+	testCases := []genDeclTestCase{
+		// typedef int (*Foo) (int a, int b);
+		{
+			name: "typedef func",
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.PointerType{
+					X: &ast.FuncType{
+						Params: &ast.FieldList{
+							List: []*ast.Field{
+								{
+									Type: &ast.BuiltinType{
+										Kind: ast.Int,
+									},
+									Names: []*ast.Ident{{Name: "a"}},
+								},
+								{
+									Type: &ast.BuiltinType{
+										Kind: ast.Int,
+									},
+									Names: []*ast.Ident{{Name: "b"}},
+								},
+							},
+						},
+						Ret: &ast.BuiltinType{
+							Kind: ast.Int,
+						},
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+"github.com/goplus/llgo/c"
+_ "unsafe"
+)
+// llgo:type C
+type Foo func(a c.Int, b c.Int) c.Int`,
+		},
+		// typedef void (*Callback)(void *ctx, int, int len);
+		{
+			name: "typedef func with unnamed param",
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "Callback"},
+				Type: &ast.PointerType{
+					X: &ast.FuncType{
+						Params: &ast.FieldList{
+							List: []*ast.Field{
+								{
+									Type:  &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}},
+									Names: []*ast.Ident{{Name: "ctx"}},
+								},
+								{
+									Type: &ast.BuiltinType{Kind: ast.Int},
+								},
+								{
+									Type:  &ast.BuiltinType{Kind: ast.Int},
+									Names: []*ast.Ident{{Name: "len"}},
+								},
+							},
+						},
+						Ret: &ast.BuiltinType{Kind: ast.Void},
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+"github.com/goplus/llgo/c"
+"unsafe"
+)
+// llgo:type C
+type Callback func(ctx unsafe.Pointer, __llgo_arg_1 c.Int, len c.Int)`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+// Test Redefine error
+func TestRedef(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		OutputDir: "",
+		SymbolTable: cfg.CreateSymbolTable(
+			[]cfg.SymbolEntry{
+				{CppName: "Bar", MangleName: "Bar", GoName: "Bar"},
+			},
+		),
+	})
+
+	flds := &ast.FieldList{
+		List: []*ast.Field{
+			{
+				Type: &ast.BuiltinType{Kind: ast.Int},
+			},
+		},
+	}
+	pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "Foo"},
+		Type: &ast.RecordType{
+			Tag:    ast.Struct,
+			Fields: flds,
+		},
+	})
+
+	err := pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "Foo"},
+		Type: &ast.RecordType{
+			Tag:    ast.Struct,
+			Fields: flds,
+		},
+	})
+	if err == nil {
+		t.Fatal("Expect a redefine err")
+	}
+
+	pkg.NewTypedefDecl(&ast.TypedefDecl{
+		Name: &ast.Ident{Name: "Foo"},
+		Type: &ast.Ident{Name: "Foo"},
+	})
+
+	err = pkg.NewFuncDecl(&ast.FuncDecl{
+		Name:        &ast.Ident{Name: "Bar"},
+		MangledName: "Bar",
+		Type: &ast.FuncType{
+			Ret: &ast.BuiltinType{
+				Kind: ast.Void,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal("NewFuncDecl failed", err)
+	}
+
+	err = pkg.NewFuncDecl(&ast.FuncDecl{
+		Name:        &ast.Ident{Name: "Bar"},
+		MangledName: "Bar",
+		Type:        &ast.FuncType{},
+	})
+	if err == nil {
+		t.Fatal("Expect a redefine err")
+	}
+
+	err = pkg.NewEnumTypeDecl(&ast.EnumTypeDecl{
+		Name: &ast.Ident{Name: "Foo"},
+		Type: &ast.EnumType{},
+	})
+
+	if err == nil {
+		t.Fatal("Expect a redefine err")
+	}
+
+	err = pkg.NewEnumTypeDecl(&ast.EnumTypeDecl{
+		Name: nil,
+		Type: &ast.EnumType{
+			Items: []*ast.EnumItem{
+				{Name: &ast.Ident{Name: "Foo"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+			},
+		},
+	})
+
+	if err == nil {
+		t.Fatal("Expect a redefine err")
+	}
+
+	var buf bytes.Buffer
+	err = pkg.GetGenPackage().WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+
+	expect := `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	c.Int
+}
+//go:linkname Bar C.Bar
+func Bar()
+`
+	comparePackageOutput(t, pkg, expect)
+}
+
+func TestTypedef(t *testing.T) {
+	testCases := []genDeclTestCase{
+		// typedef double DOUBLE;
+		{
+			name: "typedef double",
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "DOUBLE"},
+				Type: &ast.BuiltinType{
+					Kind:  ast.Float,
+					Flags: ast.Double,
+				},
+			},
+			expected: `
+package testpkg
+
+import _ "unsafe"
+
+type DOUBLE float64`,
+		},
+		// invalid typedef
+		{
+			name: "invalid typedef",
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "INVALID"},
+				Type: &ast.BuiltinType{
+					Kind:  ast.Bool,
+					Flags: ast.Double,
+				},
+			},
+			expectedErr: "not found in type map",
+		},
+		// typedef int INT;
+		{
+			name: "typedef int",
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "INT"},
+				Type: &ast.BuiltinType{
+					Kind: ast.Int,
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+"github.com/goplus/llgo/c"
+_ "unsafe"
+)
+
+type INT c.Int
+			`,
+		},
+		{
+			name: "typedef array",
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "name"},
+				Type: &ast.ArrayType{
+					Elt: &ast.BuiltinType{
+						Kind:  ast.Char,
+						Flags: ast.Signed,
+					},
+					Len: &ast.BasicLit{Kind: ast.IntLit, Value: "5"},
+				},
+			},
+			expected: `
+package testpkg
+
+import _ "unsafe"
+
+type Name [5]int8`,
+		},
+		// typedef void* ctx;
+		{
+			name: "typedef pointer",
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "ctx"},
+				Type: &ast.PointerType{
+					X: &ast.BuiltinType{
+						Kind: ast.Void,
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import "unsafe"
+
+type Ctx unsafe.Pointer`,
+		},
+
+		// typedef char* name;
+		{
+			name: "typedef pointer",
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "name"},
+				Type: &ast.PointerType{
+					X: &ast.BuiltinType{
+						Kind:  ast.Char,
+						Flags: ast.Signed,
+					},
+				},
+			},
+			expected: `
+package testpkg
+import _ "unsafe"
+type Name *int8`,
+		},
+		{
+			name: "typedef invalid pointer",
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "name"},
+				Type: &ast.PointerType{
+					X: &ast.BuiltinType{
+						Kind:  ast.Char,
+						Flags: ast.Double,
+					},
+				},
+			},
+			expectedErr: "error convert baseType",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestEnumDecl(t *testing.T) {
+	testCases := []genDeclTestCase{
+		{
+			name: "enum",
+			decl: &ast.EnumTypeDecl{
+				Name: &ast.Ident{Name: "Color"},
+				Type: &ast.EnumType{
+					Items: []*ast.EnumItem{
+						{Name: &ast.Ident{Name: "Red"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+						{Name: &ast.Ident{Name: "Green"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
+						{Name: &ast.Ident{Name: "Blue"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "2"}},
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Color c.Int
+const (
+	ColorRed   Color = 0
+	ColorGreen Color = 1
+	ColorBlue  Color = 2
+)`,
+		},
+		{
+			name: "anonymous enum",
+			decl: &ast.EnumTypeDecl{
+				Name: nil,
+				Type: &ast.EnumType{
+					Items: []*ast.EnumItem{
+						{Name: &ast.Ident{Name: "red"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+						{Name: &ast.Ident{Name: "green"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
+						{Name: &ast.Ident{Name: "blue"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "2"}},
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+const (
+	Red   c.Int = 0
+	Green c.Int = 1
+	Blue  c.Int = 2
+)`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestIdentRefer(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{})
+	pkg.SetCurFile(&convert.HeaderFile{
+		File:         "/path/to/stdio.h",
+		IncPath:      "stdio.h",
+		IsHeaderFile: true,
+		InCurPkg:     true,
+		IsSys:        true,
+	})
+	pkg.NewTypedefDecl(&ast.TypedefDecl{
+		DeclBase: ast.DeclBase{
+			Loc: &ast.Location{File: "/path/to/stdio.h"},
+		},
+		Name: &ast.Ident{Name: "undefType"},
+		Type: &ast.BuiltinType{
+			Kind:  ast.Char,
+			Flags: ast.Signed,
+		},
+	})
+	pkg.SetCurFile(&convert.HeaderFile{
+		File:         "/path/to/notsys.h",
+		IncPath:      "notsys.h",
+		IsHeaderFile: true,
+		InCurPkg:     true,
+		IsSys:        false,
+	})
+	t.Run("undef sys ident ref", func(t *testing.T) {
+		err := pkg.NewTypeDecl(&ast.TypeDecl{
+			DeclBase: ast.DeclBase{
+				Loc: &ast.Location{File: "/path/to/notsys.h"},
+			},
+			Name: &ast.Ident{Name: "Foo"},
+			Type: &ast.RecordType{
+				Tag: ast.Struct,
+				Fields: &ast.FieldList{
+					List: []*ast.Field{
+						{
+							Names: []*ast.Ident{{Name: "notfound"}},
+							Type: &ast.Ident{
+								Name: "undefType",
+							},
+						},
+					},
+				},
+			},
+		})
+		compareError(t, err, "undefType not found")
+	})
+	t.Run("undef tag ident ref", func(t *testing.T) {
+		err := pkg.NewTypeDecl(&ast.TypeDecl{
+			Name: &ast.Ident{Name: "Bar"},
+			Type: &ast.RecordType{
+				Tag: ast.Struct,
+				Fields: &ast.FieldList{
+					List: []*ast.Field{
+						{
+							Names: []*ast.Ident{{Name: "notfound"}},
+							Type: &ast.TagExpr{
+								Tag: ast.Class,
+								Name: &ast.Ident{
+									Name: "undefType",
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+		compareError(t, err, "undefType not found")
+	})
+	t.Run("type alias", func(t *testing.T) {
+		pkg := createTestPkg(t, &convert.PackageConfig{
+			PkgBase: convert.PkgBase{
+				CppgConf: &cppgtypes.Config{},
+			},
+		})
+		err := pkg.NewTypedefDecl(&ast.TypedefDecl{
+			Name: &ast.Ident{Name: "int8_t"},
+			Type: &ast.BuiltinType{
+				Kind:  ast.Char,
+				Flags: ast.Signed,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pkg.NewTypeDecl(&ast.TypeDecl{
+			Name: &ast.Ident{Name: "Foo"},
+			Type: &ast.RecordType{
+				Tag: ast.Struct,
+				Fields: &ast.FieldList{
+					List: []*ast.Field{
+						{
+							Names: []*ast.Ident{{Name: "a"}},
+							Type: &ast.Ident{
+								Name: "int8_t",
+							},
+						},
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		comparePackageOutput(t, pkg, `
+		package testpkg
+		import _ "unsafe"
+		type Int8T int8
+		type Foo struct {
+			A Int8T
+		}
+		`)
+	})
+}
+
+func TestForwardDecl(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		OutputDir: "",
+		SymbolTable: cfg.CreateSymbolTable(
+			[]cfg.SymbolEntry{
+				{CppName: "Bar", MangleName: "Bar", GoName: "Bar"},
+			},
+		),
+	})
+
+	forwardDecl := &ast.TypeDecl{
+		Name: &ast.Ident{Name: "Foo"},
+		Type: &ast.RecordType{
+			Tag: ast.Struct,
+		},
+	}
+	// forward decl
+	err := pkg.NewTypeDecl(forwardDecl)
+	if err != nil {
+		t.Fatalf("Forward decl failed: %v", err)
+	}
+
+	// complete decl
+	err = pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "Foo"},
+		Type: &ast.RecordType{
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{
+						Names: []*ast.Ident{{Name: "a"}},
+						Type:  &ast.BuiltinType{Kind: ast.Int},
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("NewTypeDecl failed: %v", err)
+	}
+
+	err = pkg.NewTypeDecl(forwardDecl)
+
+	if err != nil {
+		t.Fatalf("NewTypeDecl failed: %v", err)
+	}
+
+	expect := `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	A c.Int
+}
+`
+	comparePackageOutput(t, pkg, expect)
+}
+
+func TestOpaqueType(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		SymbolTable: cfg.CreateSymbolTable(
+			[]cfg.SymbolEntry{
+				{CppName: "foo_new", MangleName: "foo_new", GoName: "FooNew"},
+			},
+		),
+	})
+	// struct Foo;
+	err := pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "Foo"},
+		Type: &ast.RecordType{Tag: ast.Struct},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// struct Foo *foo_new(void);
+	err = pkg.NewFuncDecl(&ast.FuncDecl{
+		Name:        &ast.Ident{Name: "foo_new"},
+		MangledName: "foo_new",
+		Type: &ast.FuncType{
+			Ret: &ast.PointerType{X: &ast.TagExpr{Tag: ast.Struct, Name: &ast.Ident{Name: "Foo"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.WritePkgFiles(); err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import _ "unsafe"
+
+type Foo struct {
+	_ [0]byte
+}
+
+//go:linkname FooNew C.foo_new
+func FooNew() *Foo`)
+}
+
+func TestNestedType(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{})
+	pkg.SetCurFile(&convert.HeaderFile{
+		File:         "/path/to/testpkg.h",
+		IncPath:      "testpkg.h",
+		IsHeaderFile: true,
+		InCurPkg:     true,
+	})
+	// struct Foo {
+	//     int kind;
+	//     union { int i; double d; } value;
+	//     struct { int x; int y; };
+	//     enum { A, B } state;
+	//     struct { int n; } items[2];
+	// };
+	err := pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "Foo"},
+		Type: &ast.RecordType{
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{
+						Names: []*ast.Ident{{Name: "kind"}},
+						Type:  &ast.BuiltinType{Kind: ast.Int},
+					},
+					{
+						Names: []*ast.Ident{{Name: "value"}},
+						Type: &ast.RecordType{
+							Tag: ast.Union,
+							Fields: &ast.FieldList{
+								List: []*ast.Field{
+									{Names: []*ast.Ident{{Name: "i"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+									{Names: []*ast.Ident{{Name: "d"}}, Type: &ast.BuiltinType{Kind: ast.Float, Flags: ast.Double}},
+								},
+							},
+						},
+					},
+					{
+						Type: &ast.RecordType{
+							Tag: ast.Struct,
+							Fields: &ast.FieldList{
+								List: []*ast.Field{
+									{Names: []*ast.Ident{{Name: "x"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+									{Names: []*ast.Ident{{Name: "y"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+								},
+							},
+						},
+					},
+					{
+						Names: []*ast.Ident{{Name: "state"}},
+						Type: &ast.EnumType{
+							Items: []*ast.EnumItem{
+								{Name: &ast.Ident{Name: "A"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+								{Name: &ast.Ident{Name: "B"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
+							},
+						},
+					},
+					{
+						Names: []*ast.Ident{{Name: "items"}},
+						Type: &ast.ArrayType{
+							Elt: &ast.RecordType{
+								Tag: ast.Struct,
+								Fields: &ast.FieldList{
+									List: []*ast.Field{
+										{Names: []*ast.Ident{{Name: "n"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+									},
+								},
+							},
+							Len: &ast.BasicLit{Kind: ast.IntLit, Value: "2"},
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// typedef struct { struct { int a; } inner; } Bar;
+	err = pkg.NewTypedefDecl(&ast.TypedefDecl{
+		Name: &ast.Ident{Name: "Bar"},
+		Type: &ast.RecordType{
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{
+						Names: []*ast.Ident{{Name: "inner"}},
+						Type: &ast.RecordType{
+							Tag: ast.Struct,
+							Fields: &ast.FieldList{
+								List: []*ast.Field{
+									{Names: []*ast.Ident{{Name: "a"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	Kind  c.Int
+	Value Foo_Value
+	Foo_Anon0
+	State Foo_State
+	Items [2]Foo_Items
+}
+
+type Foo_Value struct {
+	D float64
+}
+
+type Foo_Anon0 struct {
+	X c.Int
+	Y c.Int
+}
+type Foo_State c.Int
+
+const (
+	FooStateA Foo_State = 0
+	FooStateB Foo_State = 1
+)
+
+type Foo_Items struct {
+	N c.Int
+}
+
+type Bar struct {
+	Inner Bar_Inner
+}
+
+type Bar_Inner struct {
+	A c.Int
+}`)
+	for _, name := range []string{"Foo_Value", "Foo_Anon0", "Foo_State", "Foo_Items", "Bar_Inner"} {
+		if _, ok := pkg.Pubs[name]; !ok {
+			t.Errorf("%s is not in llcppg.pub", name)
+		}
+	}
+}
+
+func TestUnionAnonMembers(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{})
+	float := &ast.BuiltinType{Kind: ast.Float}
+	// struct v {
+	//     union {
+	//         float xyz[3];
+	//         struct { float x, y, z; };
+	//     };
+	// };
+	err := pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "v"},
+		Type: &ast.RecordType{
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{
+						Type: &ast.RecordType{
+							Tag: ast.Union,
+							Fields: &ast.FieldList{
+								List: []*ast.Field{
+									{
+										Names: []*ast.Ident{{Name: "xyz"}},
+										Type:  &ast.ArrayType{Elt: float, Len: &ast.BasicLit{Kind: ast.IntLit, Value: "3"}},
+									},
+									{
+										Type: &ast.RecordType{
+											Tag: ast.Struct,
+											Fields: &ast.FieldList{
+												List: []*ast.Field{
+													{Names: []*ast.Ident{{Name: "x"}}, Type: float},
+													{Names: []*ast.Ident{{Name: "y"}}, Type: float},
+													{Names: []*ast.Ident{{Name: "z"}}, Type: float},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.WritePkgFiles(); err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import "unsafe"
+
+type V struct {
+	V_Anon0
+}
+
+type V_Anon0 struct {
+	Xyz [3]float32
+}
+
+type V_Anon0_Anon0 struct {
+	X float32
+	Y float32
+	Z float32
+}
+
+// X returns a pointer to the X member of p.
+func (p *V_Anon0) X() *float32 {
+	return &(*V_Anon0_Anon0)(unsafe.Pointer(p)).X
+}
+
+// Y returns a pointer to the Y member of p.
+func (p *V_Anon0) Y() *float32 {
+	return &(*V_Anon0_Anon0)(unsafe.Pointer(p)).Y
+}
+
+// Z returns a pointer to the Z member of p.
+func (p *V_Anon0) Z() *float32 {
+	return &(*V_Anon0_Anon0)(unsafe.Pointer(p)).Z
+}`)
+}
+
+func TestVarDecl(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		PkgBase: convert.PkgBase{
+			CppgConf: &cppgtypes.Config{TrimPrefixes: []string{"FOO_"}},
+		},
+	})
+	pkg.SetCurFile(&convert.HeaderFile{
+		File:         "/path/to/testpkg.h",
+		IncPath:      "testpkg.h",
+		IsHeaderFile: true,
+		InCurPkg:     true,
+	})
+	char := &ast.QualifiedType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, Qualifiers: ast.Const}
+	decls := []*ast.VarDecl{
+		// the max foo
+		// static const int FOO_MAX = 64;
+		{
+			DeclBase: ast.DeclBase{Doc: &ast.CommentGroup{List: []*ast.Comment{{Text: "/// the max foo"}}}},
+			Name:     &ast.Ident{Name: "FOO_MAX"},
+			Type:     &ast.QualifiedType{X: &ast.BuiltinType{Kind: ast.Int}, Qualifiers: ast.Const},
+			Value:    &ast.BasicLit{Kind: ast.IntLit, Value: "64"},
+		},
+		// constexpr double kPi = 3.5;
+		{
+			Name:  &ast.Ident{Name: "kPi"},
+			Type:  &ast.QualifiedType{X: &ast.BuiltinType{Kind: ast.Float, Flags: ast.Double}, Qualifiers: ast.Const},
+			Value: &ast.BasicLit{Kind: ast.FloatLit, Value: "3.5"},
+		},
+		// constexpr bool kDebug = true;
+		{
+			Name:  &ast.Ident{Name: "kDebug"},
+			Type:  &ast.QualifiedType{X: &ast.BuiltinType{Kind: ast.Bool}, Qualifiers: ast.Const},
+			Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"},
+		},
+		// static const char NAME[] = "foo";
+		{
+			Name:  &ast.Ident{Name: "NAME"},
+			Type:  &ast.ArrayType{Elt: char, Len: &ast.BasicLit{Kind: ast.IntLit, Value: "4"}},
+			Value: &ast.BasicLit{Kind: ast.StringLit, Value: `"foo"`},
+		},
+		// const char *const GREETING = "hi";
+		{
+			Name:  &ast.Ident{Name: "GREETING"},
+			Type:  &ast.QualifiedType{X: &ast.PointerType{X: char}, Qualifiers: ast.Const},
+			Value: &ast.BasicLit{Kind: ast.StringLit, Value: `"hi"`},
+		},
+	}
+	for _, decl := range decls {
+		if err := pkg.NewVarDecl(decl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// static const void *const NIL = 0;
+	err := pkg.NewVarDecl(&ast.VarDecl{
+		Name:  &ast.Ident{Name: "NIL"},
+		Type:  &ast.QualifiedType{X: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}}, Qualifiers: ast.Const},
+		Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"},
+	})
+	if !errors.Is(err, convert.ErrTypeConv) {
+		t.Fatalf("expected ErrTypeConv, got %v", err)
+	}
+	double := &ast.QualifiedType{X: &ast.BuiltinType{Kind: ast.Float, Flags: ast.Double}, Qualifiers: ast.Const}
+	for _, decl := range []*ast.VarDecl{
+		// static const double FOO_INF = INFINITY;
+		{Name: &ast.Ident{Name: "FOO_INF"}, Type: double, Value: &ast.BasicLit{Kind: ast.FloatLit, Value: "+Inf"}},
+		// static const double FOO_NAN = NAN;
+		{Name: &ast.Ident{Name: "FOO_NAN"}, Type: double, Value: &ast.BasicLit{Kind: ast.FloatLit, Value: "NaN"}},
+		// extern const double FOO_EPS;
+		{Name: &ast.Ident{Name: "FOO_EPS"}, Type: double},
+	} {
+		if err := pkg.NewVarDecl(decl); !errors.Is(err, convert.ErrTypeConv) {
+			t.Fatalf("%s: expected ErrTypeConv, got %v", decl.Name.Name, err)
+		}
+	}
+	comparePackageOutput(t, pkg, `
 package testpkg
 
 import (
@@ -1438,186 +4146,263 @@ import (
 	_ "unsafe"
 )
 
-const (
-	Red   c.Int = 0
-	Green c.Int = 1
-	Blue  c.Int = 2
-)`,
-		},
-	}
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			testGenDecl(t, tc)
-		})
-	}
+// the max foo
+const MAX c.Int = 64
+const KPi float64 = 3.5
+const KDebug bool = true
+const NAME = "foo"
+const GREETING = "hi"`)
 }
 
-func TestIdentRefer(t *testing.T) {
+func TestArrayLen(t *testing.T) {
 	pkg := createTestPkg(t, &convert.PackageConfig{})
-	pkg.SetCurFile(&convert.HeaderFile{
-		File:         "/path/to/stdio.h",
-		IncPath:      "stdio.h",
-		IsHeaderFile: true,
-		InCurPkg:     true,
-		IsSys:        true,
+	// #define NAME_MAX 64
+	err := pkg.NewMacro(&ast.Macro{
+		Name: "NAME_MAX",
+		Tokens: []*ast.Token{
+			{Token: ctoken.IDENT, Lit: "NAME_MAX"},
+			{Token: ctoken.LITERAL, Lit: "64"},
+		},
 	})
-	pkg.NewTypedefDecl(&ast.TypedefDecl{
-		DeclBase: ast.DeclBase{
-			Loc: &ast.Location{File: "/path/to/stdio.h"},
+	if err != nil {
+		t.Fatal(err)
+	}
+	// enum { ROWS = 2 };
+	err = pkg.NewEnumTypeDecl(&ast.EnumTypeDecl{
+		Type: &ast.EnumType{
+			Items: []*ast.EnumItem{
+				{Name: &ast.Ident{Name: "ROWS"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "2"}},
+			},
 		},
-		Name: &ast.Ident{Name: "undefType"},
-		Type: &ast.BuiltinType{
-			Kind:  ast.Char,
-			Flags: ast.Signed,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	char := &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}
+	lenOf := func(n, name string) (ast.Expr, ast.Expr) {
+		return &ast.BasicLit{Kind: ast.IntLit, Value: n}, &ast.Ident{Name: name}
+	}
+	nameLen, nameLenExpr := lenOf("64", "NAME_MAX")
+	rowsLen, rowsLenExpr := lenOf("2", "ROWS")
+	colsLen, colsLenExpr := lenOf("3", "COLS")
+	otherLen, otherLenExpr := lenOf("32", "NAME_MAX")
+	// struct rec {
+	//     char name[NAME_MAX];
+	//     int m[ROWS][COLS];
+	//     char other[NAME_MAX / 2];
+	// };
+	err = pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "rec"},
+		Type: &ast.RecordType{
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "name"}}, Type: &ast.ArrayType{Elt: char, Len: nameLen, LenExpr: nameLenExpr}},
+					{Names: []*ast.Ident{{Name: "m"}}, Type: &ast.ArrayType{
+						Elt: &ast.ArrayType{Elt: &ast.BuiltinType{Kind: ast.Int}, Len: colsLen, LenExpr: colsLenExpr},
+						Len: rowsLen, LenExpr: rowsLenExpr,
+					}},
+					{Names: []*ast.Ident{{Name: "other"}}, Type: &ast.ArrayType{Elt: char, Len: otherLen, LenExpr: otherLenExpr}},
+				},
+			},
 		},
 	})
-	pkg.SetCurFile(&convert.HeaderFile{
-		File:         "/path/to/notsys.h",
-		IncPath:      "notsys.h",
-		IsHeaderFile: true,
-		InCurPkg:     true,
-		IsSys:        false,
+	if err != nil {
+		t.Fatal(err)
+	}
+	// typedef char name_t[NAME_MAX];
+	err = pkg.NewTypedefDecl(&ast.TypedefDecl{
+		Name: &ast.Ident{Name: "name_t"},
+		Type: &ast.ArrayType{Elt: char, Len: nameLen, LenExpr: nameLenExpr},
 	})
-	t.Run("undef sys ident ref", func(t *testing.T) {
-		err := pkg.NewTypeDecl(&ast.TypeDecl{
-			DeclBase: ast.DeclBase{
-				Loc: &ast.Location{File: "/path/to/notsys.h"},
-			},
-			Name: &ast.Ident{Name: "Foo"},
-			Type: &ast.RecordType{
-				Tag: ast.Struct,
-				Fields: &ast.FieldList{
-					List: []*ast.Field{
-						{
-							Names: []*ast.Ident{{Name: "notfound"}},
-							Type: &ast.Ident{
-								Name: "undefType",
-							},
-						},
-					},
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+const NAMEMAX = 64
+const ROWS c.Int = 2
+
+type Rec struct {
+	Name  [NAMEMAX]int8
+	M     [ROWS][3]c.Int
+	Other [32]int8
+}
+type NameT [NAMEMAX]int8`)
+}
+
+func TestRecordLayout(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{})
+	char := &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}
+	integer := &ast.BuiltinType{Kind: ast.Int}
+	// #pragma pack(1)
+	// struct hdr { char kind; int len; char pad[3]; } __attribute__((aligned(4)));
+	err := pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "hdr"},
+		Type: &ast.RecordType{
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "kind"}}, Type: char},
+					{Names: []*ast.Ident{{Name: "len"}}, Type: integer},
+					{Names: []*ast.Ident{{Name: "pad"}}, Type: &ast.ArrayType{Elt: char, Len: &ast.BasicLit{Kind: ast.IntLit, Value: "3"}}},
 				},
 			},
-		})
-		compareError(t, err, "undefType not found")
+			Layout: &ast.RecordLayout{Aligned: true, Size: 8, Align: 4, Offsets: []int64{0, 1, 5}},
+		},
 	})
-	t.Run("undef tag ident ref", func(t *testing.T) {
-		err := pkg.NewTypeDecl(&ast.TypeDecl{
-			Name: &ast.Ident{Name: "Bar"},
-			Type: &ast.RecordType{
-				Tag: ast.Struct,
-				Fields: &ast.FieldList{
-					List: []*ast.Field{
-						{
-							Names: []*ast.Ident{{Name: "notfound"}},
-							Type: &ast.TagExpr{
-								Tag: ast.Class,
-								Name: &ast.Ident{
-									Name: "undefType",
-								},
-							},
-						},
-					},
+	if err != nil {
+		t.Fatal(err)
+	}
+	// struct id { int id __attribute__((packed)); } __attribute__((aligned(8)));
+	err = pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "id"},
+		Type: &ast.RecordType{
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "id"}}, Type: integer},
 				},
 			},
-		})
-		compareError(t, err, "undefType not found")
+			Layout: &ast.RecordLayout{Packed: true, Aligned: true, Size: 8, Align: 8, Offsets: []int64{0}},
+		},
 	})
-	t.Run("type alias", func(t *testing.T) {
-		pkg := createTestPkg(t, &convert.PackageConfig{
-			PkgBase: convert.PkgBase{
-				CppgConf: &cppgtypes.Config{},
-			},
-		})
-		err := pkg.NewTypedefDecl(&ast.TypedefDecl{
-			Name: &ast.Ident{Name: "int8_t"},
-			Type: &ast.BuiltinType{
-				Kind:  ast.Char,
-				Flags: ast.Signed,
-			},
-		})
-		if err != nil {
-			t.Fatal(err)
-		}
-		err = pkg.NewTypeDecl(&ast.TypeDecl{
-			Name: &ast.Ident{Name: "Foo"},
-			Type: &ast.RecordType{
-				Tag: ast.Struct,
-				Fields: &ast.FieldList{
-					List: []*ast.Field{
-						{
-							Names: []*ast.Ident{{Name: "a"}},
-							Type: &ast.Ident{
-								Name: "int8_t",
-							},
-						},
-					},
+	if err != nil {
+		t.Fatal(err)
+	}
+	// struct vec { float v[4]; } __attribute__((aligned(16)));
+	err = pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "vec"},
+		Type: &ast.RecordType{
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "v"}}, Type: &ast.ArrayType{Elt: &ast.BuiltinType{Kind: ast.Float}, Len: &ast.BasicLit{Kind: ast.IntLit, Value: "4"}}},
 				},
 			},
-		})
-		if err != nil {
-			t.Fatal(err)
-		}
-		comparePackageOutput(t, pkg, `
-		package testpkg
-		import _ "unsafe"
-		type Int8T int8
-		type Foo struct {
-			A Int8T
-		}
-		`)
+			Layout: &ast.RecordLayout{Aligned: true, Size: 16, Align: 16, Offsets: []int64{0}},
+		},
 	})
+	if !errors.Is(err, convert.ErrLayout) {
+		t.Fatalf("expected ErrLayout, got %v", err)
+	}
+	if err := pkg.WritePkgFiles(); err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+
+type Hdr struct {
+	_    [0]uint32
+	Kind int8
+	len  [4]byte
+	Pad  [3]int8
 }
 
-func TestForwardDecl(t *testing.T) {
-	pkg := createTestPkg(t, &convert.PackageConfig{
-		OutputDir: "",
-		SymbolTable: cfg.CreateSymbolTable(
-			[]cfg.SymbolEntry{
-				{CppName: "Bar", MangleName: "Bar", GoName: "Bar"},
-			},
-		),
-	})
+type Id struct {
+	_  [0]uint64
+	Id c.Int
+	_  [4]byte
+}
 
-	forwardDecl := &ast.TypeDecl{
-		Name: &ast.Ident{Name: "Foo"},
+type Vec struct {
+	_ [0]byte
+}
+
+// Len returns the Len field of p.
+func (p *Hdr) Len() c.Int {
+	return *(*c.Int)(unsafe.Pointer(&p.len))
+}
+
+// SetLen sets the Len field of p to v.
+func (p *Hdr) SetLen(v c.Int) {
+	*(*c.Int)(unsafe.Pointer(&p.len)) = v
+}`)
+}
+
+func TestNestedDecl(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{})
+	pkg.SetCurFile(&convert.HeaderFile{
+		File:         "/path/to/testpkg.h",
+		IncPath:      "testpkg.h",
+		IsHeaderFile: true,
+		InCurPkg:     true,
+	})
+	outer := &ast.Ident{Name: "Outer"}
+	scoped := func(name string) ast.Expr {
+		return &ast.ScopingExpr{Parent: outer, X: &ast.Ident{Name: name}}
+	}
+	// struct Outer {
+	//     struct Inner { int a; };
+	//     enum Kind { A };
+	//     typedef int Size;
+	//     Inner in;
+	//     Kind kind;
+	//     Size size;
+	// };
+	err := pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: outer,
 		Type: &ast.RecordType{
-			Tag:    ast.Struct,
-			Fields: &ast.FieldList{},
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "in"}}, Type: scoped("Inner")},
+					{Names: []*ast.Ident{{Name: "kind"}}, Type: scoped("Kind")},
+					{Names: []*ast.Ident{{Name: "size"}}, Type: scoped("Size")},
+				},
+			},
 		},
-	}
-	// forward decl
-	err := pkg.NewTypeDecl(forwardDecl)
+	})
 	if err != nil {
-		t.Fatalf("Forward decl failed: %v", err)
+		t.Fatal(err)
 	}
-
-	// complete decl
 	err = pkg.NewTypeDecl(&ast.TypeDecl{
-		Name: &ast.Ident{Name: "Foo"},
+		DeclBase: ast.DeclBase{Parent: outer},
+		Name:     &ast.Ident{Name: "Inner"},
 		Type: &ast.RecordType{
 			Tag: ast.Struct,
 			Fields: &ast.FieldList{
 				List: []*ast.Field{
-					{
-						Names: []*ast.Ident{{Name: "a"}},
-						Type:  &ast.BuiltinType{Kind: ast.Int},
-					},
+					{Names: []*ast.Ident{{Name: "a"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
 				},
 			},
 		},
 	})
-
 	if err != nil {
-		t.Fatalf("NewTypeDecl failed: %v", err)
+		t.Fatal(err)
 	}
-
-	err = pkg.NewTypeDecl(forwardDecl)
-
+	err = pkg.NewEnumTypeDecl(&ast.EnumTypeDecl{
+		DeclBase: ast.DeclBase{Parent: outer},
+		Name:     &ast.Ident{Name: "Kind"},
+		Type: &ast.EnumType{
+			Items: []*ast.EnumItem{
+				{Name: &ast.Ident{Name: "A"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+			},
+		},
+	})
 	if err != nil {
-		t.Fatalf("NewTypeDecl failed: %v", err)
+		t.Fatal(err)
 	}
-
-	expect := `
+	err = pkg.NewTypedefDecl(&ast.TypedefDecl{
+		DeclBase: ast.DeclBase{Parent: outer},
+		Name:     &ast.Ident{Name: "Size"},
+		Type:     &ast.BuiltinType{Kind: ast.Int},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, pkg, `
 package testpkg
 
 import (
@@ -1625,11 +4410,28 @@ import (
 	_ "unsafe"
 )
 
-type Foo struct {
+type Outer struct {
+	In   Outer_Inner
+	Kind Outer_Kind
+	Size Outer_Size
+}
+
+type Outer_Inner struct {
 	A c.Int
 }
-`
-	comparePackageOutput(t, pkg, expect)
+type Outer_Kind c.Int
+type Outer_Size c.Int
+
+const OuterKindA Outer_Kind = 0`)
+	for cname, goName := range map[string]string{
+		"Outer::Inner": "Outer_Inner",
+		"Outer::Kind":  "Outer_Kind",
+		"Outer::Size":  "Outer_Size",
+	} {
+		if pkg.Pubs[cname] != goName {
+			t.Errorf("llcppg.pub: %s is %q, want %q", cname, pkg.Pubs[cname], goName)
+		}
+	}
 }
 
 type genDeclTestCase struct {
@@ -1671,6 +4473,8 @@ func testGenDecl(t *testing.T, tc genDeclTestCase) {
 	} else {
 		if err != nil {
 			t.Errorf("Declaration generation failed: %v", err)
+		} else if err = pkg.WritePkgFiles(); err != nil {
+			t.Errorf("WritePkgFiles failed: %v", err)
 		} else {
 			comparePackageOutput(t, pkg, tc.expected)
 		}
@@ -1755,7 +4559,7 @@ func TestTypeClean(t *testing.T) {
 			addType: func() {
 				pkg.NewTypeDecl(&ast.TypeDecl{
 					Name: &ast.Ident{Name: "Foo1"},
-					Type: &ast.RecordType{Tag: ast.Struct},
+					Type: &ast.RecordType{Tag: ast.Struct, Fields: &ast.FieldList{}},
 				})
 			},
 			headerFile: "/path/to/file1.h",
@@ -2090,6 +4894,18 @@ func TestIncPathToPkgInvalidRegex(t *testing.T) {
 	convert.IncPathToPkg("any_path")
 }
 
+func TestImportsDepError(t *testing.T) {
+	loader := convert.NewPkgDepLoader(nil, nil)
+	_, err := loader.Imports([]string{"example.com/foo"})
+	var depErr *errs.DepImportError
+	if !errors.As(err, &depErr) || depErr.Index != 0 || depErr.Path != "example.com/foo" {
+		t.Fatalf("expected a DepImportError of $.deps[0], got %v", err)
+	}
+	if want := `$.deps[0]: can't import "example.com/foo": go.mod not found`; err.Error() != want {
+		t.Fatalf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
 func TestImport(t *testing.T) {
 	t.Run("invalid mod", func(t *testing.T) {
 		loader := convert.PkgDepLoader{}
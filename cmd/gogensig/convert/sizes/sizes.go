@@ -20,3 +20,11 @@ func init() {
 func Sizeof(T types.Type) int64 {
 	return std.Sizeof(T)
 }
+
+func Alignof(T types.Type) int64 {
+	return std.Alignof(T)
+}
+
+func Offsetsof(fields []*types.Var) []int64 {
+	return std.Offsetsof(fields)
+}
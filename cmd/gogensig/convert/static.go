@@ -0,0 +1,84 @@
+/*
+This file converts the static members of C++ classes. They have no this, so a
+static member function is a package-level function and a public static data
+member is a package-level variable linked to its symbol, both named by the
+staticName pattern of llcppg.cfg, like Foo_Create and Foo_Count.
+*/
+package convert
+
+import (
+	"go/token"
+	"log"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+	cppgtypes "github.com/goplus/llcppg/types"
+)
+
+func (p *Package) staticName() string {
+	if p.CppgConf == nil {
+		return ""
+	}
+	return p.CppgConf.StaticName
+}
+
+// staticFuncSpec returns the spec of the package-level function of a static
+// member function whose symbol is named like a method, eg. (*Foo).Create.
+func (p *Package) staticFuncSpec(fnSpec *GoFuncSpec) *GoFuncSpec {
+	return NewGoFuncSpec(cppgtypes.StaticMemberName(p.staticName(), fnSpec.RecvName, fnSpec.FnName))
+}
+
+// instanceFields returns the fields of a record without its static data members.
+func instanceFields(fields *ast.FieldList) *ast.FieldList {
+	if fields == nil {
+		return nil
+	}
+	var list []*ast.Field
+	for _, field := range fields.List {
+		if !field.IsStatic {
+			list = append(list, field)
+		}
+	}
+	if len(list) == len(fields.List) {
+		return fields
+	}
+	return &ast.FieldList{List: list}
+}
+
+// genStaticVars declares the public static data members of the C++ record
+// cname, which are found in the symbol table by their C++ names, as variables:
+//
+//	//go:linkname Foo_Count _ZN3Foo5countE
+//	var Foo_Count c.Int
+func (p *Package) genStaticVars(cname ast.Expr, record *ast.RecordType) {
+	if record.Fields == nil {
+		return
+	}
+	scope := p.p.Types.Scope()
+	for _, field := range record.Fields.List {
+		if !field.IsStatic || field.Access != ast.Public || len(field.Names) == 0 {
+			continue
+		}
+		name := cppName(cname, field.Names[0].Name)
+		symb, err := p.cvt.symbolTable.LookupCppSymbol(name)
+		if err != nil {
+			continue
+		}
+		if scope.Lookup(symb.GoName) != nil {
+			if dbg.GetDebugError() {
+				log.Printf("genStaticVars: %s of %s is already defined\n", symb.GoName, name)
+			}
+			continue
+		}
+		typ, err := p.ToType(field.Type)
+		if err != nil {
+			if dbg.GetDebugError() {
+				log.Printf("genStaticVars: can't convert the type of %s: %v\n", name, err)
+			}
+			continue
+		}
+		doc := DeclCommentGroup(field.Doc, nil, name)
+		doc.AddCommentGroup(NewVarDocComments(symb.MangleName, symb.GoName))
+		p.p.NewVarDefs(scope).SetComments(doc.CommentGroup).New(token.NoPos, typ, symb.GoName)
+	}
+}
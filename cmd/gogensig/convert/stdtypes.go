@@ -0,0 +1,876 @@
+/*
+This file maps the common C++ standard library types of function signatures to
+Go values: std::string and std::string_view to string, std::vector<T> and
+std::span<T> to []T, and std::function<R(A...)> to func(A...) R. A function
+using them is called through an extern "C" shim converting its parameters and
+result, and its Go wrapper takes the name of the raw binding.
+*/
+package convert
+
+import (
+	"fmt"
+	goast "go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/gogen"
+	"github.com/goplus/llcppg/ast"
+)
+
+const stdShimPrefix = "llcppg_std_" // prefix of the C symbols of the std:: shims, followed by the mangled name
+
+// stdKind is the kind of a standard C++ type passed as a Go value.
+type stdKind int
+
+const (
+	stdString   stdKind = iota + 1 // std::string or std::string_view, a Go string
+	stdSlice                       // std::vector<T> or std::span<T>, a Go []T
+	stdFunction                    // std::function<R(A...)>, a Go func(A...) R
+)
+
+// stdType is a standard C++ type of a parameter or a result.
+type stdType struct {
+	kind stdKind
+	typ  ast.Expr      // the type without references and qualifiers, like std::vector<int>
+	elem ast.Expr      // the element type of a slice
+	strs bool          // the elements of a slice are strings
+	span bool          // the slice is a std::span viewing the Go slice
+	fn   *ast.FuncType // the signature of a function
+}
+
+// stdSignature is the signature of a function using standard C++ types.
+type stdSignature struct {
+	params []*stdType // aligned with the parameters of the function, nil for other types
+	ret    *stdType   // nil for other types
+}
+
+// stdHelpers are the Go helpers of the std:: shims, declared on first use.
+type stdHelpers struct {
+	objs  map[string]types.Object // helpers by name
+	calls map[string]*types.Func  // trampolines by the Go func types they call
+	types map[string]types.Type   // C func types of the trampolines, by the Go func types they call
+}
+
+// stdSignatureOf returns the signature of funcDecl if it uses standard C++
+// types, or nil.
+func stdSignatureOf(funcDecl *ast.FuncDecl) (*stdSignature, error) {
+	sig := &stdSignature{}
+	used := false
+	var params []*ast.Field
+	if funcDecl.Type.Params != nil {
+		params = funcDecl.Type.Params.List
+	}
+	for _, field := range params {
+		if field == nil {
+			// reported by ToSigSignature
+			sig.params = append(sig.params, nil)
+			continue
+		}
+		st, err := stdTypeOf(field.Type)
+		if err != nil {
+			return nil, err
+		}
+		sig.params = append(sig.params, st)
+		used = used || st != nil
+	}
+	ret, err := stdTypeOf(funcDecl.Type.Ret)
+	if err != nil {
+		return nil, err
+	}
+	if ret != nil && ret.kind == stdFunction {
+		return nil, fmt.Errorf("std::function result is not supported")
+	}
+	sig.ret = ret
+	if !used && ret == nil {
+		return nil, nil
+	}
+	return sig, nil
+}
+
+// stdTypeOf returns the standard C++ type of a parameter or a result, or nil
+// if expr is none of them.
+func stdTypeOf(expr ast.Expr) (*stdType, error) {
+	typ := expr
+	switch t := typ.(type) {
+	case *ast.LvalueRefType:
+		typ = t.X
+	case *ast.RvalueRefType:
+		typ = t.X
+	}
+	typ = ast.Unqualified(typ)
+	inst, _ := typ.(*ast.InstantiationType)
+	if inst == nil {
+		switch stdName(typ) {
+		case "std::string", "std::string_view":
+			return &stdType{kind: stdString, typ: typ}, nil
+		}
+		return nil, nil
+	}
+	name := stdName(inst.Template)
+	arg := templateArg(inst)
+	switch name {
+	case "std::basic_string", "std::basic_string_view":
+		if t, ok := ast.Unqualified(arg).(*ast.BuiltinType); !ok || t.Kind != ast.Char {
+			return nil, fmt.Errorf("%s of other than char is not supported", name)
+		}
+		return &stdType{kind: stdString, typ: typ}, nil
+	case "std::vector", "std::span":
+		st := &stdType{kind: stdSlice, typ: typ, elem: arg, span: name == "std::span"}
+		elem, err := stdTypeOf(arg)
+		if err != nil {
+			return nil, err
+		}
+		if elem != nil {
+			if elem.kind != stdString || st.span || arg != elem.typ {
+				return nil, fmt.Errorf("%s of %s is not supported", name, stdName(elem.typ))
+			}
+			st.strs = true
+		}
+		return st, nil
+	case "std::function":
+		fn, ok := arg.(*ast.FuncType)
+		if !ok {
+			return nil, fmt.Errorf("std::function of other than a function type is not supported")
+		}
+		for _, field := range fn.Params.List {
+			if _, ok := field.Type.(*ast.Variadic); ok {
+				return nil, fmt.Errorf("variadic std::function is not supported")
+			}
+		}
+		for _, field := range append(fn.Params.List, &ast.Field{Type: fn.Ret}) {
+			if st, _ := stdTypeOf(field.Type); st != nil {
+				return nil, fmt.Errorf("std::function of %s is not supported", stdName(st.typ))
+			}
+		}
+		return &stdType{kind: stdFunction, typ: typ, fn: fn}, nil
+	}
+	return nil, nil
+}
+
+// stdName returns the qualified name of a type of the std namespace without
+// its inline namespaces, like std::string of std::__1::string.
+func stdName(expr ast.Expr) string {
+	if tag, ok := expr.(*ast.TagExpr); ok {
+		expr = tag.Name
+	}
+	if inst, ok := expr.(*ast.InstantiationType); ok {
+		expr = inst.Template
+	}
+	parts := strings.Split(cppScope(expr), "::")
+	if parts[0] != "std" {
+		return ""
+	}
+	name := parts[:1]
+	for _, part := range parts[1:] {
+		if !strings.HasPrefix(part, "__") {
+			name = append(name, part)
+		}
+	}
+	return strings.Join(name, "::")
+}
+
+// templateArg returns the first template argument of inst, or nil.
+func templateArg(inst *ast.InstantiationType) ast.Expr {
+	if inst.Args == nil || len(inst.Args.List) == 0 {
+		return nil
+	}
+	return inst.Args.List[0].Type
+}
+
+// stdGoType returns the Go type of st.
+func (p *Package) stdGoType(st *stdType) (types.Type, error) {
+	switch st.kind {
+	case stdString:
+		return types.Typ[types.String], nil
+	case stdSlice:
+		if st.strs {
+			return types.NewSlice(types.Typ[types.String]), nil
+		}
+		elem, err := p.ToType(ast.Unqualified(st.elem))
+		if err != nil {
+			return nil, err
+		}
+		return types.NewSlice(elem), nil
+	}
+	sig, err := p.cvt.ToSignature(st.fn, nil)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), false), nil
+}
+
+// genStdFunc generates the Go wrapper of funcDecl using standard C++ types,
+// named after its raw binding, which calls a shim converting the Go values:
+//
+//	//go:linkname std_Split C.llcppg_std__Z5splitRKNSt7__cxx1112basic_stringIcSt11char_traitsIcESaIcEEEc
+//	func std_Split(ret *[]string, s *string, sep int8)
+//
+//	func Split(s string, sep int8) (ret []string) {
+//		var r []string
+//		std_Split(&r, &s, sep)
+//		ret = stdStrings(r)
+//		return
+//	}
+//
+// A std::function parameter is passed as a trampoline calling the Go func, a
+// pointer to it kept alive by stdKeep, and stdRelease. If the function is listed
+// in the exceptions of llcppg.cfg, the wrapper also returns a C++ exception
+// thrown by it as an error.
+func (p *Package) genStdFunc(fnSpec *GoFuncSpec, funcDecl *ast.FuncDecl, recv *types.Var, std *stdSignature) error {
+	pkg := p.p
+	fields := funcDecl.Type.Params.List
+	first := 0
+	if recv != nil {
+		first = 1
+	}
+	hasNamedParam := false
+	for _, field := range fields {
+		if _, ok := field.Type.(*ast.Variadic); ok {
+			return fmt.Errorf("variadic parameters can't be forwarded")
+		}
+		hasNamedParam = hasNamedParam || len(field.Names) > 0
+	}
+
+	var params []*types.Var
+	for i, field := range fields[first:] {
+		name := ""
+		if len(field.Names) > 0 {
+			name = avoidKeyword(field.Names[0].Name)
+		} else if hasNamedParam {
+			name = fmt.Sprintf("__llgo_arg_%d", i)
+		}
+		var typ types.Type
+		var err error
+		if st := std.params[first+i]; st != nil {
+			typ, err = p.stdGoType(st)
+		} else {
+			typ, err = p.cvt.paramType(field.Type)
+		}
+		if err != nil {
+			return err
+		}
+		params = append(params, pkg.NewParam(token.NoPos, name, typ))
+	}
+	params = namedParams(pkg, types.NewTuple(params...))
+	paramTuple := types.NewTuple(params...)
+
+	var retType types.Type
+	var err error
+	if std.ret != nil {
+		retType, err = p.stdGoType(std.ret)
+	} else if !Expr(funcDecl.Type.Ret).IsVoid() && !funcDecl.IsConstructor {
+		retType, err = p.ToType(funcDecl.Type.Ret)
+	}
+	if err != nil {
+		return err
+	}
+
+	catch := p.catchesExceptions(funcDecl)
+	shim := stdShimPrefix + funcDecl.MangledName
+	src, err := stdShimSource(funcDecl, shim, p.isMemberFunc(funcDecl), std, catch)
+	if err != nil {
+		return err
+	}
+
+	if err := p.declareStdHelpers(std, params, first); err != nil {
+		return err
+	}
+
+	// the raw binding of the shim
+	bindName := "std_" + fnSpec.FnName
+	var bindParams []*types.Var
+	if std.ret != nil {
+		bindParams = append(bindParams, pkg.NewParam(token.NoPos, uniqueParamName("ret", paramTuple), types.NewPointer(retType)))
+	}
+	if recv != nil {
+		bindName = "std_" + getNamedType(recv.Type()).Obj().Name() + "_" + fnSpec.FnName
+		bindParams = append(bindParams, pkg.NewParam(token.NoPos, "self", recv.Type()))
+	}
+	if obj := pkg.Types.Scope().Lookup(bindName); obj != nil {
+		return fmt.Errorf("%s already defined", bindName)
+	}
+	for i, param := range params {
+		st := std.params[first+i]
+		switch {
+		case st == nil:
+			bindParams = append(bindParams, pkg.NewParam(token.NoPos, param.Name(), param.Type()))
+		case st.kind == stdFunction:
+			bindParams = append(bindParams,
+				pkg.NewParam(token.NoPos, param.Name()+"_call", p.std.types[p.stdTypeString(param.Type().(*types.Signature))]),
+				pkg.NewParam(token.NoPos, param.Name(), types.Typ[types.UnsafePointer]),
+				pkg.NewParam(token.NoPos, param.Name()+"_release", p.std.objs["stdReleaseFunc"].Type()))
+		default:
+			bindParams = append(bindParams, pkg.NewParam(token.NoPos, param.Name(), types.NewPointer(param.Type())))
+		}
+	}
+	excName := uniqueParamName("exc", paramTuple)
+	if catch {
+		bindParams = append(bindParams, pkg.NewParam(token.NoPos, excName, types.NewPointer(types.NewPointer(types.Typ[types.Int8]))))
+	}
+	var bindResults *types.Tuple
+	if std.ret == nil && retType != nil {
+		bindResults = types.NewTuple(pkg.NewParam(token.NoPos, "", retType))
+	}
+	binding := pkg.NewFuncDecl(token.NoPos, bindName, types.NewSignatureType(nil, nil, nil, types.NewTuple(bindParams...), bindResults, false))
+	binding.SetComments(pkg, NewFuncDocComments(shim, bindName))
+
+	// the wrapper
+	var ret, errVar *types.Var
+	var results []*types.Var
+	if retType != nil {
+		ret = pkg.NewParam(token.NoPos, uniqueParamName("ret", paramTuple), retType)
+		results = append(results, ret)
+	}
+	if catch {
+		errVar = pkg.NewParam(token.NoPos, uniqueParamName("err", paramTuple), types.Universe.Lookup("error").Type())
+		results = append(results, errVar)
+	}
+	var wrapRecv *types.Var
+	if recv != nil {
+		wrapRecv = pkg.NewParam(token.NoPos, recv.Name(), recv.Type())
+	}
+	fn := pkg.NewFunc(wrapRecv, fnSpec.FnName, paramTuple, types.NewTuple(results...), false)
+	doc := DeclCommentGroup(funcDecl.Doc, funcDecl.Attrs, funcDecl.Name.Name)
+	if len(doc.List) > 0 {
+		fn.SetComments(pkg, doc.CommentGroup)
+	}
+	p.shims = append(p.shims, &cppShim{header: p.curFile.IncPath, src: src, std: true})
+
+	cb := fn.BodyStart(pkg)
+	var r types.Object
+	if std.ret != nil {
+		rName := uniqueParamName("r", paramTuple)
+		cb.NewVar(retType, rName)
+		r = cb.Scope().Lookup(rName)
+	}
+	var exc types.Object
+	if catch {
+		cb.NewVar(types.NewPointer(types.Typ[types.Int8]), excName)
+		exc = cb.Scope().Lookup(excName)
+	}
+	if std.ret == nil && ret != nil {
+		cb.VarRef(ret)
+	}
+	cb.Val(binding.Func)
+	nargs := 0
+	if r != nil {
+		cb.VarRef(r).UnaryOp(token.AND)
+		nargs++
+	}
+	if wrapRecv != nil {
+		cb.Val(wrapRecv)
+		nargs++
+	}
+	unsafePtr := types.Typ[types.UnsafePointer]
+	for i, param := range params {
+		st := std.params[first+i]
+		switch {
+		case st == nil:
+			cb.Val(param)
+			nargs++
+		case st.kind == stdFunction:
+			call := p.std.calls[p.stdTypeString(param.Type().(*types.Signature))]
+			keep, release := p.std.objs["stdKeep"], p.std.objs["stdRelease"]
+			cb.Val(call).Val(keep).Typ(unsafePtr).VarRef(param).UnaryOp(token.AND).Call(1).Call(1).Val(release)
+			nargs += 3
+		default:
+			cb.VarRef(param).UnaryOp(token.AND)
+			nargs++
+		}
+	}
+	if exc != nil {
+		cb.VarRef(exc).UnaryOp(token.AND)
+		nargs++
+	}
+	cb.Call(nargs)
+	if std.ret == nil && ret != nil {
+		cb.Assign(1)
+	} else {
+		cb.EndStmt()
+	}
+	if std.ret != nil {
+		p.stdResult(cb, std.ret, ret, r)
+	}
+	if exc != nil {
+		clib := pkg.Import(cLibPath)
+		cb.If().Val(exc).CompareNil(token.NEQ).Then().
+			VarRef(errVar).Val(pkg.Import("errors").Ref("New")).Val(clib.Ref("GoString")).Val(exc).Call(1).Call(1).Assign(1).
+			Val(clib.Ref("Free")).Typ(unsafePtr).Val(exc).Call(1).Call(1).EndStmt().
+			End()
+	}
+	if len(results) > 0 {
+		cb.Return(0)
+	}
+	cb.End()
+	return nil
+}
+
+// declareStdHelpers declares the Go helpers used by the wrapper of a function
+// of the signature std with the Go parameters params, which skip the first
+// parameters of std.
+func (p *Package) declareStdHelpers(std *stdSignature, params []*types.Var, first int) error {
+	var names []string
+	for i, param := range params {
+		if st := std.params[first+i]; st != nil && st.kind == stdFunction {
+			if err := p.declareStdCall(param.Type().(*types.Signature)); err != nil {
+				return err
+			}
+			names = append(names, "stdReleaseFunc", "stdKeep", "stdRelease")
+		}
+	}
+	if std.ret != nil {
+		names = append(names, stdResultHelper(std.ret))
+	}
+	for _, name := range names {
+		if _, err := p.stdHelper(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stdResultHelper returns the name of the helper copying a result of st.
+func stdResultHelper(st *stdType) string {
+	switch {
+	case st.kind == stdString:
+		return "stdString"
+	case st.strs:
+		return "stdStrings"
+	}
+	return "stdFree"
+}
+
+// stdResult generates the copy of the result r allocated by the shim to ret,
+// freeing r:
+//
+//	ret = stdString(r)
+//	ret = stdStrings(r)
+//
+// or for the other slices:
+//
+//	ret = append(ret, r...)
+//	stdFree(unsafe.Pointer(&r))
+func (p *Package) stdResult(cb *gogen.CodeBuilder, st *stdType, ret *types.Var, r types.Object) {
+	name := stdResultHelper(st)
+	helper := p.std.objs[name]
+	if name != "stdFree" {
+		cb.VarRef(ret).Val(helper).Val(r).Call(1).Assign(1)
+		return
+	}
+	cb.VarRef(ret).Val(p.p.Builtin().Ref("append")).Val(ret).Val(r).Call(2, true).Assign(1)
+	cb.Val(helper).Typ(types.Typ[types.UnsafePointer]).VarRef(r).UnaryOp(token.AND).Call(1).Call(1).EndStmt()
+}
+
+// stdShimSource returns the C++ source of the shim of funcDecl using standard
+// C++ types, whose first parameter is self if member is true:
+//
+//	extern "C" void llcppg_std__Z5splitRKNSt7__cxx1112basic_stringIcSt11char_traitsIcESaIcEEEc(llcppg::slice *ret, const llcppg::string *a0, char a1) {
+//		std::string v0(a0->data, a0->len);
+//		llcppg::from_strings(ret, split(v0, a1));
+//	}
+//
+// The std:: parameters are converted to locals, and a std:: result is copied to
+// memory allocated by malloc. If catch is true, the what() text of a caught
+// exception is stored to the last parameter.
+func stdShimSource(funcDecl *ast.FuncDecl, shim string, member bool, std *stdSignature, catch bool) (string, error) {
+	var params, stmts, args []string
+	if std.ret != nil {
+		if std.ret.kind == stdString {
+			params = append(params, "llcppg::string *ret")
+		} else {
+			params = append(params, "llcppg::slice *ret")
+		}
+	}
+	for i, field := range funcDecl.Type.Params.List {
+		if member && i == 0 {
+			param, err := cppDecl(field.Type, "self")
+			if err != nil {
+				return "", err
+			}
+			params = append(params, param)
+			continue
+		}
+		name := "a" + strconv.Itoa(len(args))
+		st := std.params[i]
+		if st == nil {
+			param, err := cppDecl(field.Type, name)
+			if err != nil {
+				return "", err
+			}
+			params = append(params, param)
+			if _, ok := field.Type.(*ast.RvalueRefType); ok {
+				name = "std::move(" + name + ")"
+			}
+			args = append(args, name)
+			continue
+		}
+		typ, err := cppDecl(st.typ, "")
+		if err != nil {
+			return "", err
+		}
+		local := "v" + strconv.Itoa(len(args))
+		switch {
+		case st.kind == stdString:
+			params = append(params, "const llcppg::string *"+name)
+			stmts = append(stmts, fmt.Sprintf("%s %s(%s->data, %s->len);", typ, local, name, name))
+		case st.kind == stdFunction:
+			call := &ast.FuncType{Ret: st.fn.Ret, Params: &ast.FieldList{List: append([]*ast.Field{
+				{Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}}},
+			}, st.fn.Params.List...)}}
+			param, err := cppDecl(&ast.PointerType{X: call}, name+"_call")
+			if err != nil {
+				return "", err
+			}
+			params = append(params, param, "void *"+name, "void (*"+name+"_release)(void *)")
+			stmts = append(stmts, fmt.Sprintf("%s %s = llcppg::to_function(%s_call, %s, %s_release);", typ, local, name, name, name))
+		default:
+			conv := "to_vector"
+			switch {
+			case st.span:
+				conv = "to_span"
+			case st.strs:
+				conv = "to_strings"
+			}
+			params = append(params, "const llcppg::slice *"+name)
+			stmts = append(stmts, fmt.Sprintf("auto %s = llcppg::%s<%s>(%s);", local, conv, typ, name))
+		}
+		if _, ok := field.Type.(*ast.LvalueRefType); !ok {
+			local = "std::move(" + local + ")"
+		}
+		args = append(args, local)
+	}
+	if catch {
+		params = append(params, "char **exc")
+	}
+
+	call, ret := shimCall(funcDecl, member, args)
+	void := true
+	switch {
+	case std.ret != nil:
+		conv := "from_slice"
+		switch {
+		case std.ret.kind == stdString:
+			conv = "from_string"
+		case std.ret.strs:
+			conv = "from_strings"
+		}
+		stmts = append(stmts, "llcppg::"+conv+"(ret, "+call+");")
+		ret = nil
+	default:
+		switch t := ret.(type) {
+		case *ast.LvalueRefType:
+			ret, call = &ast.PointerType{X: t.X}, "&"+call
+		case *ast.RvalueRefType:
+			return "", fmt.Errorf("rvalue reference result is not supported")
+		}
+		void = ret == nil || Expr(ret).IsVoid()
+		if void {
+			stmts = append(stmts, call+";")
+		} else {
+			stmts = append(stmts, "return "+call+";")
+		}
+	}
+	if void {
+		ret = &ast.BuiltinType{Kind: ast.Void}
+	}
+	decl, err := cppDecl(ret, shim+"("+strings.Join(params, ", ")+")")
+	if err != nil {
+		return "", err
+	}
+	if void {
+		return shimBody(decl, stmts, catch, nil)
+	}
+	return shimBody(decl, stmts, catch, ret)
+}
+
+// declareStdCall declares the trampoline calling a Go func of the type sig
+// from C++ and its C func type, if they are not declared yet:
+//
+//	// llgo:type C
+//	type stdCallFunc0 func(fn unsafe.Pointer, a0 c.Int) c.Int
+//
+//	func stdCall0(fn unsafe.Pointer, a0 c.Int) c.Int {
+//		return (*(*func(c.Int) c.Int)(fn))(a0)
+//	}
+func (p *Package) declareStdCall(sig *types.Signature) error {
+	p.initStd()
+	key := p.stdTypeString(sig)
+	if _, ok := p.std.types[key]; ok {
+		return nil
+	}
+	pkg := p.p
+	n := strconv.Itoa(len(p.std.types))
+	name, typeName := "stdCall"+n, "stdCallFunc"+n
+	if pkg.Types.Scope().Lookup(name) != nil || pkg.Types.Scope().Lookup(typeName) != nil {
+		return fmt.Errorf("%s or %s already defined", name, typeName)
+	}
+	unsafePtr := types.Typ[types.UnsafePointer]
+	fnParam := pkg.NewParam(token.NoPos, "fn", unsafePtr)
+	params := []*types.Var{fnParam}
+	for i := 0; i < sig.Params().Len(); i++ {
+		params = append(params, pkg.NewParam(token.NoPos, "a"+strconv.Itoa(i), sig.Params().At(i).Type()))
+	}
+
+	typeBlock := pkg.NewTypeDefs()
+	typeBlock.SetComments(&goast.CommentGroup{List: []*goast.Comment{
+		{Text: "// " + typeName + " is the C function type of " + name + "."},
+		{Text: TYPEC},
+	}})
+	decl := typeBlock.NewType(typeName)
+	decl.InitType(pkg, types.NewSignatureType(nil, nil, nil, types.NewTuple(params...), sig.Results(), false))
+
+	fn := pkg.NewFunc(nil, name, types.NewTuple(params...), sig.Results(), false)
+	fn.SetComments(pkg, lineComments(name+" calls the Go "+key+" that fn points to."))
+	cb := fn.BodyStart(pkg).Typ(types.NewPointer(sig)).Val(fnParam).Call(1).Elem()
+	for _, param := range params[1:] {
+		cb.Val(param)
+	}
+	cb.Call(len(params) - 1)
+	if sig.Results().Len() > 0 {
+		cb.Return(1)
+	} else {
+		cb.EndStmt()
+	}
+	cb.End()
+	p.std.types[key] = decl.Type()
+	p.std.calls[key] = fn.Func
+	return nil
+}
+
+// stdTypeString returns the spelling of a Go func type in the package.
+func (p *Package) stdTypeString(sig *types.Signature) string {
+	return types.TypeString(sig, func(pkg *types.Package) string {
+		if pkg == p.p.Types {
+			return ""
+		}
+		return pkg.Name()
+	})
+}
+
+func (p *Package) initStd() {
+	if p.std == nil {
+		p.std = &stdHelpers{
+			objs:  make(map[string]types.Object),
+			calls: make(map[string]*types.Func),
+			types: make(map[string]types.Type),
+		}
+	}
+}
+
+// stdHelper returns the Go helper of the shims named name, declaring it on
+// first use in the current file.
+func (p *Package) stdHelper(name string) (types.Object, error) {
+	p.initStd()
+	if obj, ok := p.std.objs[name]; ok {
+		return obj, nil
+	}
+	pkg := p.p
+	if pkg.Types.Scope().Lookup(name) != nil {
+		return nil, fmt.Errorf("%s already defined", name)
+	}
+	unsafePtr := types.Typ[types.UnsafePointer]
+	var obj types.Object
+	switch name {
+	case "stdFree":
+		// func stdFree(header unsafe.Pointer)
+		params := types.NewTuple(pkg.NewParam(token.NoPos, "header", unsafePtr))
+		decl := pkg.NewFuncDecl(token.NoPos, name, types.NewSignatureType(nil, nil, nil, params, nil, false))
+		doc := lineComments(name + " frees the data of the Go string or slice that header points to, allocated by a shim.")
+		doc.List = append(doc.List, NewFuncDocComments("llcppg_std_free", name).List...)
+		decl.SetComments(pkg, doc)
+		obj = decl.Func
+	case "stdString":
+		// func stdString(s string) string {
+		//	ret := strings.Clone(s)
+		//	stdFree(unsafe.Pointer(&s))
+		//	return ret
+		// }
+		free, err := p.stdHelper("stdFree")
+		if err != nil {
+			return nil, err
+		}
+		str := types.Typ[types.String]
+		s := pkg.NewParam(token.NoPos, "s", str)
+		fn := pkg.NewFunc(nil, name, types.NewTuple(s), types.NewTuple(pkg.NewParam(token.NoPos, "", str)), false)
+		fn.SetComments(pkg, lineComments(name+" returns a copy of the string s allocated by a shim and frees s."))
+		cb := fn.BodyStart(pkg)
+		cb.DefineVarStart(token.NoPos, "ret").Val(pkg.Import("strings").Ref("Clone")).Val(s).Call(1).EndInit(1)
+		ret := cb.Scope().Lookup("ret")
+		cb.Val(free).Typ(unsafePtr).VarRef(s).UnaryOp(token.AND).Call(1).Call(1).EndStmt()
+		cb.Val(ret).Return(1).End()
+		obj = fn.Func
+	case "stdStrings":
+		// func stdStrings(s []string) []string {
+		//	ret := make([]string, len(s))
+		//	for i, e := range s {
+		//		ret[i] = stdString(e)
+		//	}
+		//	stdFree(unsafe.Pointer(&s))
+		//	return ret
+		// }
+		free, err := p.stdHelper("stdFree")
+		if err != nil {
+			return nil, err
+		}
+		str, err := p.stdHelper("stdString")
+		if err != nil {
+			return nil, err
+		}
+		strs := types.NewSlice(types.Typ[types.String])
+		s := pkg.NewParam(token.NoPos, "s", strs)
+		fn := pkg.NewFunc(nil, name, types.NewTuple(s), types.NewTuple(pkg.NewParam(token.NoPos, "", strs)), false)
+		fn.SetComments(pkg, lineComments(name+" returns a copy of the strings s allocated by a shim and frees s."))
+		cb := fn.BodyStart(pkg)
+		builtin := pkg.Builtin()
+		cb.DefineVarStart(token.NoPos, "ret").Val(builtin.Ref("make")).Typ(strs).Val(builtin.Ref("len")).Val(s).Call(1).Call(2).EndInit(1)
+		ret := cb.Scope().Lookup("ret")
+		cb.ForRange("i", "e").Val(s).RangeAssignThen(token.NoPos)
+		cb.Val(ret).Val(cb.Scope().Lookup("i")).IndexRef(1).Val(str).Val(cb.Scope().Lookup("e")).Call(1).Assign(1)
+		cb.End()
+		cb.Val(free).Typ(unsafePtr).VarRef(s).UnaryOp(token.AND).Call(1).Call(1).EndStmt()
+		cb.Val(ret).Return(1).End()
+		obj = fn.Func
+	case "stdFuncs":
+		// var stdFuncs sync.Map
+		defs := pkg.NewVarDefs(pkg.Types.Scope())
+		defs.SetComments(lineComments(name + " keeps the Go funcs passed to C++ alive until they are released."))
+		defs.New(token.NoPos, pkg.Import("sync").Ref("Map").Type(), name)
+		obj = pkg.Types.Scope().Lookup(name)
+	case "stdKeep":
+		// func stdKeep(fn unsafe.Pointer) unsafe.Pointer {
+		//	stdFuncs.Store(fn, nil)
+		//	return fn
+		// }
+		funcs, err := p.stdHelper("stdFuncs")
+		if err != nil {
+			return nil, err
+		}
+		fnParam := pkg.NewParam(token.NoPos, "fn", unsafePtr)
+		fn := pkg.NewFunc(nil, name, types.NewTuple(fnParam), types.NewTuple(pkg.NewParam(token.NoPos, "", unsafePtr)), false)
+		fn.SetComments(pkg, lineComments(name+" keeps the Go func that fn points to alive until stdRelease(fn)."))
+		fn.BodyStart(pkg).
+			Val(funcs).MemberVal("Store").Val(fnParam).Val(nil).Call(2).EndStmt().
+			Val(fnParam).Return(1).End()
+		obj = fn.Func
+	case "stdReleaseFunc":
+		// llgo:type C
+		// type stdReleaseFunc func(fn unsafe.Pointer)
+		params := types.NewTuple(pkg.NewParam(token.NoPos, "fn", unsafePtr))
+		typeBlock := pkg.NewTypeDefs()
+		typeBlock.SetComments(&goast.CommentGroup{List: []*goast.Comment{
+			{Text: "// " + name + " is the C function type of stdRelease."},
+			{Text: TYPEC},
+		}})
+		decl := typeBlock.NewType(name)
+		decl.InitType(pkg, types.NewSignatureType(nil, nil, nil, params, nil, false))
+		obj = decl.Type().Obj()
+	case "stdRelease":
+		// func stdRelease(fn unsafe.Pointer) {
+		//	stdFuncs.Delete(fn)
+		// }
+		funcs, err := p.stdHelper("stdFuncs")
+		if err != nil {
+			return nil, err
+		}
+		fnParam := pkg.NewParam(token.NoPos, "fn", unsafePtr)
+		fn := pkg.NewFunc(nil, name, types.NewTuple(fnParam), nil, false)
+		fn.SetComments(pkg, lineComments(name+" is called by C++ when the last copy of the std::function of fn is destroyed."))
+		fn.BodyStart(pkg).Val(funcs).MemberVal("Delete").Val(fnParam).Call(1).EndStmt().End()
+		obj = fn.Func
+	default:
+		return nil, fmt.Errorf("unknown std helper %s", name)
+	}
+	p.std.objs[name] = obj
+	return obj, nil
+}
+
+// stdRuntime is the C++ source of the marshaling helpers of the std:: shims.
+const stdRuntime = `#include <algorithm>
+#include <cstdint>
+#include <cstdlib>
+#include <functional>
+#include <memory>
+#include <string>
+#include <vector>
+
+namespace llcppg {
+
+// string is the header of a Go string.
+struct string {
+	const char *data;
+	intptr_t len;
+};
+
+// slice is the header of a Go slice.
+struct slice {
+	void *data;
+	intptr_t len;
+	intptr_t cap;
+};
+
+template <class V> V to_vector(const slice *s) {
+	auto data = static_cast<const typename V::value_type *>(s->data);
+	return V(data, data + s->len);
+}
+
+template <class V> V to_strings(const slice *s) {
+	auto data = static_cast<const string *>(s->data);
+	V v;
+	v.reserve(s->len);
+	for (intptr_t i = 0; i < s->len; i++) {
+		v.emplace_back(data[i].data, data[i].len);
+	}
+	return v;
+}
+
+template <class S> S to_span(const slice *s) {
+	return S(static_cast<typename S::pointer>(s->data), s->len);
+}
+
+template <class S> void from_string(string *ret, const S &s) {
+	char *data = static_cast<char *>(malloc(s.size()));
+	std::copy(s.begin(), s.end(), data);
+	ret->data = data;
+	ret->len = s.size();
+}
+
+template <class V> void from_slice(slice *ret, const V &v) {
+	using T = typename V::value_type;
+	T *data = static_cast<T *>(malloc(sizeof(T) * v.size()));
+	std::copy(v.begin(), v.end(), data);
+	ret->data = data;
+	ret->len = ret->cap = v.size();
+}
+
+template <class V> void from_strings(slice *ret, const V &v) {
+	string *data = static_cast<string *>(malloc(sizeof(string) * v.size()));
+	for (size_t i = 0; i < v.size(); i++) {
+		from_string(&data[i], v[i]);
+	}
+	ret->data = data;
+	ret->len = ret->cap = v.size();
+}
+
+// handle is a Go func shared by the copies of its std::function, released when
+// the last one is destroyed.
+struct handle {
+	void *fn;
+	void (*release)(void *);
+	handle(void *fn, void (*release)(void *)) : fn(fn), release(release) {}
+	handle(const handle &) = delete;
+	~handle() { release(fn); }
+};
+
+template <class R, class... A>
+std::function<R(A...)> to_function(R (*call)(void *, A...), void *fn, void (*release)(void *)) {
+	auto h = std::make_shared<handle>(fn, release);
+	return [call, h](A... a) -> R { return call(h->fn, a...); };
+}
+
+} // namespace llcppg
+
+// llcppg_std_free is weak, as every package with std:: shims defines it.
+extern "C" __attribute__((weak)) void llcppg_std_free(void *header) { free(*static_cast<void **>(header)); }
+`
@@ -9,7 +9,6 @@ import (
 	"go/token"
 	"go/types"
 	"log"
-	"unsafe"
 
 	"github.com/goplus/gogen"
 	"github.com/goplus/llcppg/ast"
@@ -23,6 +22,7 @@ type TypeContext int
 
 var (
 	ErrTypeConv = errors.New("error convert type")
+	ErrLayout   = errors.New("can't match the C layout")
 )
 
 const (
@@ -48,9 +48,19 @@ type TypeConv struct {
 	symbolTable *config.SymbolTable // llcppg.symb.json
 	typeMap     *BuiltinTypeMap
 	ctx         TypeContext
+	record      *recordScope // the named record whose fields are being converted
 	conf        *TypeConfig
 }
 
+// recordScope names the anonymous records and enums nested in the fields of a
+// named record, like the union of struct a { union { int x; } u; }, which is
+// named A_U. A nested type of a field without name is named A_Anon0, A_Anon1...
+type recordScope struct {
+	name  string // the Go name of the record
+	field string // the Go name of the field being converted
+	anons int    // the number of nested types of fields without name
+}
+
 type TypeConfig struct {
 	Package      *Package
 	Types        *types.Package
@@ -77,8 +87,15 @@ func (p *TypeConv) ToType(expr ast.Expr) (types.Type, error) {
 	case *ast.BuiltinType:
 		typ, err := p.typeMap.FindBuiltinType(*t)
 		return typ, err
+	case *ast.QualifiedType:
+		// Go has no type qualifiers
+		return p.ToType(t.X)
 	case *ast.PointerType:
 		return p.handlePointerType(t)
+	case *ast.LvalueRefType:
+		return p.handleRefType(t.X)
+	case *ast.RvalueRefType:
+		return p.handleRefType(t.X)
 	case *ast.ArrayType:
 		return p.handleArrayType(t)
 	case *ast.FuncType:
@@ -88,7 +105,15 @@ func (p *TypeConv) ToType(expr ast.Expr) (types.Type, error) {
 	case *ast.Variadic:
 		return types.NewSlice(gogen.TyEmptyInterface), nil
 	case *ast.RecordType:
+		if p.record != nil {
+			return p.conf.Package.nestedType(p.nestedName(), t)
+		}
 		return p.RecordTypeToStruct(t)
+	case *ast.EnumType:
+		if p.record != nil {
+			return p.conf.Package.nestedType(p.nestedName(), t)
+		}
+		return nil, fmt.Errorf("%w: unsupported anonymous enum type", ErrTypeConv)
 	default:
 		return nil, fmt.Errorf("%w: unsupported type %T", ErrTypeConv, expr)
 	}
@@ -113,7 +138,11 @@ func (p *TypeConv) handleArrayType(t *ast.ArrayType) (types.Type, error) {
 		return nil, fmt.Errorf("%s", "can't determine the array length")
 	}
 
-	return types.NewArray(elemType, int64(len)), nil
+	arr := types.NewArray(elemType, int64(len))
+	if ident, ok := t.LenExpr.(*ast.Ident); ok && p.conf.Package != nil {
+		p.conf.Package.addArrayLen(arr, ident.Name)
+	}
+	return arr, nil
 }
 
 // - void* -> c.Pointer
@@ -192,17 +221,24 @@ func (p *TypeConv) handleIdentRefer(t ast.Expr) (types.Type, error) {
 		}
 		return typ, nil
 	case *ast.ScopingExpr:
-		// todo(zzy)
+		if name := p.conf.Package.scopingName(t); name != "" {
+			typ, err := lookup(name)
+			if err != nil {
+				return nil, fmt.Errorf("%s not found %w", name, err)
+			}
+			return typ, nil
+		}
 	case *ast.TagExpr:
-		// todo(zzy):scoping
-		if ident, ok := t.Name.(*ast.Ident); ok {
-			typ, err := lookup(ident.Name)
+		switch name := t.Name.(type) {
+		case *ast.Ident:
+			typ, err := lookup(name.Name)
 			if err != nil {
-				return nil, fmt.Errorf("%s not found", ident.Name)
+				return nil, fmt.Errorf("%s not found", name.Name)
 			}
 			return typ, nil
+		case *ast.ScopingExpr:
+			return p.handleIdentRefer(name)
 		}
-		// todo(zzy):scoping expr
 	}
 	return nil, errs.NewUnsupportedReferError(t)
 }
@@ -229,6 +265,14 @@ func (p *TypeConv) ToSignature(funcType *ast.FuncType, recv *types.Var) (*types.
 	return types.NewSignatureType(recv, nil, nil, params, results, variadic), nil
 }
 
+// paramType converts the type of a function parameter, like ToSignature.
+func (p *TypeConv) paramType(expr ast.Expr) (types.Type, error) {
+	ctx := p.ctx
+	p.ctx = Param
+	defer func() { p.ctx = ctx }()
+	return p.ToType(expr)
+}
+
 // Convert ast.FieldList to types.Tuple (Function Param)
 func (p *TypeConv) fieldListToParams(params *ast.FieldList) (*types.Tuple, bool, error) {
 	if params == nil {
@@ -296,11 +340,19 @@ func (p *TypeConv) fieldListToVars(params *ast.FieldList, hasNamedParam bool) ([
 	return vars, nil
 }
 
-// todo(zzy): use  Unused [unsafe.Sizeof(0)]byte in the source code
-func (p *TypeConv) defaultRecordField() []*types.Var {
-	return []*types.Var{
-		types.NewVar(token.NoPos, p.Types, "Unused", types.NewArray(types.Typ[types.Byte], int64(unsafe.Sizeof(0)))),
-	}
+// opaqueStruct returns the Go type of an opaque C type, like a struct that is
+// declared but never defined, or a record type that can't be converted:
+//
+//	struct {
+//		_ [0]byte
+//	}
+//
+// It is zero-size and has no accessible fields, so it is only usable by pointer
+// and its size doesn't pretend to be the size of the C type.
+func (p *TypeConv) opaqueStruct() types.Type {
+	return types.NewStruct([]*types.Var{
+		types.NewField(token.NoPos, p.Types, "_", types.NewArray(types.Universe.Lookup("byte").Type(), 0), false),
+	}, nil)
 }
 
 func (p *TypeConv) fieldToVar(field *ast.Field, hasNamedParam bool, argIndex int) (*types.Var, error) {
@@ -316,13 +368,22 @@ func (p *TypeConv) fieldToVar(field *ast.Field, hasNamedParam bool, argIndex int
 		name = fmt.Sprintf("__llgo_arg_%d", argIndex)
 	}
 
+	if p.ctx == Record {
+		name = getFieldName(name)
+		if p.record != nil {
+			p.record.field = name
+		}
+	}
 	typ, err := p.ToType(field.Type)
 	if err != nil {
 		return nil, err
 	}
 
+	embedded := false
 	if p.ctx == Record {
-		name = getFieldName(name)
+		// an anonymous member of a nested type, like union { int x; };
+		_, named := typ.(*types.Named)
+		embedded = named && name == ""
 	} else {
 		_, isVariadic := field.Type.(*ast.Variadic)
 		if isVariadic && hasNamedParam {
@@ -331,7 +392,32 @@ func (p *TypeConv) fieldToVar(field *ast.Field, hasNamedParam bool, argIndex int
 			name = avoidKeyword(name)
 		}
 	}
-	return types.NewVar(token.NoPos, p.Types, name, typ), nil
+	fieldVar := types.NewVar(token.NoPos, p.Types, name, typ)
+	if embedded {
+		fieldVar = types.NewField(token.NoPos, p.Types, name, typ, true)
+	}
+	if p.ctx == Record && p.conf.Package != nil {
+		p.conf.Package.setObjComments(fieldVar, field.Doc, field.Comment)
+	}
+	return fieldVar, nil
+}
+
+// funcFieldType returns the Go func type of a function pointer field of a record,
+// which is converted to a c.Pointer, or nil if the field is not a function pointer.
+// It is a signature for an anonymous function pointer type like int (*read)(void *),
+// and a named type for a typedef like lua_CFunction.
+func (p *TypeConv) funcFieldType(field *ast.Field) types.Type {
+	ctx := p.ctx
+	p.ctx = Param
+	defer func() { p.ctx = ctx }()
+	typ, err := p.ToType(field.Type)
+	if err != nil {
+		return nil
+	}
+	if _, ok := typ.Underlying().(*types.Signature); ok {
+		return typ
+	}
+	return nil
 }
 
 func (p *TypeConv) RecordTypeToStruct(recordType *ast.RecordType) (types.Type, error) {
@@ -339,12 +425,31 @@ func (p *TypeConv) RecordTypeToStruct(recordType *ast.RecordType) (types.Type, e
 	p.ctx = Record
 	defer func() { p.ctx = ctx }()
 	var fields []*types.Var
-	flds, err := p.fieldListToVars(recordType.Fields, false)
+	fieldList := recordType.Fields
+	if recordType.Layout == nil {
+		// a static field takes no storage, the layout offsets skip it by -1
+		fieldList = instanceFields(fieldList)
+	}
+	flds, err := p.fieldListToVars(fieldList, false)
 	if err != nil {
 		return nil, err
 	}
+	var offsets []int64
 	if recordType.Tag != ast.Union {
 		fields = flds
+		if recordType.Layout != nil {
+			offsets = recordType.Layout.Offsets
+		}
+		if len(recordType.Bases) > 0 {
+			bases, baseOffsets, err := p.baseFields(recordType, flds)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(bases, fields...)
+			if recordType.Layout != nil {
+				offsets = append(baseOffsets, offsets...)
+			}
+		}
 	} else {
 		var maxFld *types.Var
 		maxSize := int64(0)
@@ -359,21 +464,47 @@ func (p *TypeConv) RecordTypeToStruct(recordType *ast.RecordType) (types.Type, e
 		}
 		if maxFld != nil {
 			fields = []*types.Var{maxFld}
+			offsets = []int64{0}
 		}
+		if p.record != nil && p.conf.Package != nil {
+			p.conf.Package.addUnionMembers(p.record.name, flds, maxFld)
+		}
+	}
+	if recordType.Layout != nil {
+		return p.layoutStruct(fields, offsets, recordType.Layout)
 	}
 	return types.NewStruct(fields, nil), nil
 }
 
+// NamedRecordTypeToStruct converts the record type of the Go type name, and names
+// the anonymous records and enums nested in its fields after it.
+func (p *TypeConv) NamedRecordTypeToStruct(name string, recordType *ast.RecordType) (types.Type, error) {
+	record := p.record
+	p.record = &recordScope{name: name}
+	defer func() { p.record = record }()
+	return p.RecordTypeToStruct(recordType)
+}
+
+// nestedName returns the Go name of an anonymous type nested in the field being
+// converted.
+func (p *TypeConv) nestedName() string {
+	r := p.record
+	if r.field != "" {
+		return r.name + "_" + r.field
+	}
+	name := fmt.Sprintf("%s_Anon%d", r.name, r.anons)
+	r.anons++
+	return name
+}
+
 func (p *TypeConv) ToDefaultEnumType() types.Type {
 	return p.typeMap.CType("Int")
 }
 
-// todo(zzy): Current forward declaration detection is imprecise
-// It incorrectly treats both empty struct `struct a {}` and forward declaration `struct a` as the same
-// by only checking if Fields.List is empty
-// Should use recordType == nil to identify forward declarations, which requires llcppsigfetch support
+// inComplete reports whether recordType is a forward declaration like struct a,
+// which has no fields, unlike an empty struct a {}.
 func (p *TypeConv) inComplete(recordType *ast.RecordType) bool {
-	return recordType.Fields != nil && len(recordType.Fields.List) == 0
+	return recordType.Fields == nil
 }
 
 // typedecl,enumdecl,funcdecl,funcdecl
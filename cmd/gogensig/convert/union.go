@@ -0,0 +1,106 @@
+/*
+This file generates accessors of the members of C11 anonymous structs and unions
+in unions. A union is converted to a struct of its largest member, so the other
+anonymous members are not in the Go struct, and their members are reached through
+pointers to the union.
+*/
+package convert
+
+import (
+	"go/token"
+	"go/types"
+	"log"
+)
+
+// unionMember is an anonymous member of a union which is not kept in the Go
+// struct of the union.
+type unionMember struct {
+	union string     // the Go name of the union
+	field *types.Var // the embedded field of the anonymous member
+	file  *HeaderFile
+}
+
+// addUnionMembers records the anonymous members of the union named union which
+// are not kept in its Go struct.
+func (p *Package) addUnionMembers(union string, fields []*types.Var, kept *types.Var) {
+	for _, field := range fields {
+		if field != kept && field.Embedded() {
+			p.unionMembers = append(p.unionMembers, &unionMember{union: union, field: field, file: p.curFile})
+		}
+	}
+}
+
+// genUnionAccessors generates the accessors of the members of the recorded
+// anonymous members. An accessor returns a pointer to the member, which is at the
+// same address as the union, so the layout of the union is unchanged:
+//
+//	// struct v { union { float xyz[3]; struct { float x, y, z; }; }; };
+//	type V struct {
+//		V_Anon0
+//	}
+//
+//	type V_Anon0 struct {
+//		Xyz [3]float32
+//	}
+//
+//	// X returns a pointer to the X member of p.
+//	func (p *V_Anon0) X() *float32 {
+//		return &(*V_Anon0_Anon0)(unsafe.Pointer(p)).X
+//	}
+//
+// The accessors of an anonymous member of a struct are promoted, so v.X() works
+// like v.x in C.
+func (p *Package) genUnionAccessors() {
+	defer p.SetCurFile(p.curFile)
+	for _, um := range p.unionMembers {
+		obj, ok := p.p.Types.Scope().Lookup(um.union).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		p.SetCurFile(um.file)
+		for _, member := range promotedFields(um.field.Type()) {
+			if p.isFieldOrMethod(named, member.Name()) {
+				log.Printf("genUnionAccessors: %s.%s already defined\n", um.union, member.Name())
+				continue
+			}
+			p.genUnionAccessor(named, um.field.Type(), member)
+		}
+	}
+}
+
+func (p *Package) genUnionAccessor(named *types.Named, memberType types.Type, member *types.Var) {
+	pkg := p.p
+	recv := pkg.NewParam(token.NoPos, "p", types.NewPointer(named))
+	results := types.NewTuple(pkg.NewParam(token.NoPos, "", types.NewPointer(member.Type())))
+	fn := pkg.NewFunc(recv, member.Name(), nil, results, false)
+	fn.SetComments(pkg, lineComments(
+		member.Name()+" returns a pointer to the "+member.Name()+" member of p.",
+	))
+	fn.BodyStart(pkg).
+		Typ(types.NewPointer(memberType)).Typ(types.Typ[types.UnsafePointer]).Val(recv).Call(1).Call(1).
+		MemberVal(member.Name()).UnaryOp(token.AND).
+		Return(1).End()
+}
+
+// promotedFields returns the named fields of the struct type typ, including the
+// fields promoted from its embedded fields.
+func promotedFields(typ types.Type) []*types.Var {
+	structType, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	var fields []*types.Var
+	for i := 0; i < structType.NumFields(); i++ {
+		field := structType.Field(i)
+		if field.Embedded() {
+			fields = append(fields, promotedFields(field.Type())...)
+		} else if field.Name() != "" && field.Name() != "_" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
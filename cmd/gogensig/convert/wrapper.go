@@ -0,0 +1,342 @@
+/*
+This file generates Go friendly wrappers around the raw C bindings.
+*/
+package convert
+
+import (
+	"fmt"
+	goast "go/ast"
+	"go/token"
+	"go/types"
+	"log"
+	"strings"
+
+	"github.com/goplus/gogen"
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+	cppgtypes "github.com/goplus/llcppg/types"
+)
+
+const (
+	outWrapperSuffix = "Out"
+	errWrapperSuffix = "Err"
+)
+
+// genFuncWrappers generates the wrappers of the declared raw bindings. They are
+// generated once all the raw bindings are declared, so that a wrapper never
+// takes the name of a raw binding declared after it: the wrapper is skipped
+// instead.
+func (p *Package) genFuncWrappers() {
+	if len(p.declFuncs) == 0 {
+		return
+	}
+	defer p.SetCurFile(p.curFile)
+	for _, fn := range p.declFuncs {
+		p.SetCurFile(fn.file)
+		p.genFuncWrapper(fn.fnSpec, fn.sig, fn.funcDecl)
+	}
+}
+
+// genFuncWrapper generates a wrapper for funcDecl if it has out parameters
+// or matches an error convention of llcppg.cfg, and a wrapper catching its
+// exceptions if it's listed in the exceptions of llcppg.cfg.
+func (p *Package) genFuncWrapper(fnSpec *GoFuncSpec, sig *types.Signature, funcDecl *ast.FuncDecl) {
+	outs := p.outParams(funcDecl, sig)
+	conv := p.errorConvention(funcDecl.Name.Name, sig)
+	if p.catchesExceptions(funcDecl) {
+		if conv != nil && dbg.GetDebugError() {
+			log.Printf("genFuncWrapper: error convention of %s is ignored, its exceptions are caught\n", funcDecl.Name.Name)
+		}
+		conv = nil
+		p.genExceptionWrapper(fnSpec, sig, funcDecl)
+	}
+	if len(outs) == 0 && conv == nil {
+		return
+	}
+	p.genWrapper(fnSpec, sig, funcDecl.Name.Name, outs, conv)
+}
+
+// outParams returns the indexes of the parameters of funcDecl that are out parameters.
+// An out parameter is either listed in the outParams of llcppg.cfg,
+// or inferred from a pointer-to-scalar parameter named out_* or *_out which
+// isn't a buffer, see isBufferParam.
+// The receiver (if any) and pointers to const are never treated as out parameters.
+func (p *Package) outParams(funcDecl *ast.FuncDecl, sig *types.Signature) []int {
+	if funcDecl.Type.Params == nil || sig.Variadic() {
+		return nil
+	}
+	var marked []string
+	if p.CppgConf != nil {
+		marked = p.CppgConf.OutParams[funcDecl.Name.Name]
+	}
+	first := 0
+	if sig.Recv() != nil {
+		first = 1
+	}
+	var outs []int
+	for i, field := range funcDecl.Type.Params.List[first:] {
+		if len(field.Names) == 0 {
+			continue
+		}
+		name := field.Names[0].Name
+		typ := sig.Params().At(i).Type()
+		if isConstPointer(field.Type) {
+			if contains(marked, name) && dbg.GetDebugError() {
+				log.Printf("outParams: %s of %s points to const\n", name, funcDecl.Name.Name)
+			}
+			continue
+		}
+		if contains(marked, name) {
+			if isOutPointer(typ, false) {
+				outs = append(outs, i)
+			}
+		} else if isOutParamName(name) && isOutPointer(typ, true) && !isBufferParam(funcDecl.Type.Params.List, first+i) {
+			outs = append(outs, i)
+		}
+	}
+	return outs
+}
+
+// isBufferParam reports whether the i-th parameter is likely a buffer supplied
+// by the caller rather than a scalar out parameter, that is it points to char
+// or void, like char *name_out, or a length parameter follows it, like
+// int *vals_out, size_t len.
+func isBufferParam(params []*ast.Field, i int) bool {
+	if ptr, ok := params[i].Type.(*ast.PointerType); ok {
+		if elem, ok := ptr.X.(*ast.BuiltinType); ok && (elem.Kind == ast.Char || elem.Kind == ast.Void) {
+			return true
+		}
+	}
+	if j := i + 1; j < len(params) && len(params[j].Names) > 0 {
+		// a length is passed by value, unlike an out parameter like int *count_out
+		if _, isPtr := params[j].Type.(*ast.PointerType); !isPtr && isLengthParamName(params[j].Names[0].Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLengthParamName reports whether name is likely the name of a length, that
+// is n, cap, len, size or count, or a name ending with the word len, size or
+// count, like buf_len or bufSize. Names are split into words by underscores and
+// case changes, so that names like calendar or resize are not lengths.
+func isLengthParamName(name string) bool {
+	words := splitWords(name)
+	if len(words) == 0 {
+		return false
+	}
+	switch last := words[len(words)-1]; last {
+	case "len", "size", "count":
+		return true
+	case "n", "cap":
+		return len(words) == 1
+	}
+	return false
+}
+
+// splitWords splits name into lower case words at underscores and case
+// changes, like buf_len, bufLen and BUFLen into buf and len.
+func splitWords(name string) []string {
+	var words []string
+	for _, part := range strings.Split(name, "_") {
+		start := 0
+		for i := 1; i < len(part); i++ {
+			prev, cur := part[i-1], part[i]
+			next := byte(0)
+			if i+1 < len(part) {
+				next = part[i+1]
+			}
+			if isUpper(cur) && (!isUpper(prev) || isLower(next)) {
+				words = append(words, strings.ToLower(part[start:i]))
+				start = i
+			}
+		}
+		if start < len(part) {
+			words = append(words, strings.ToLower(part[start:]))
+		}
+	}
+	return words
+}
+
+func isUpper(c byte) bool { return 'A' <= c && c <= 'Z' }
+func isLower(c byte) bool { return 'a' <= c && c <= 'z' }
+
+func isOutParamName(name string) bool {
+	return strings.HasPrefix(name, "out_") || strings.HasSuffix(name, "_out")
+}
+
+// isOutPointer reports whether typ can be used as an out parameter.
+// If scalar is true, only pointers to scalar types are accepted.
+func isOutPointer(typ types.Type, scalar bool) bool {
+	ptr, ok := typ.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	if !scalar {
+		return true
+	}
+	basic, ok := ptr.Elem().Underlying().(*types.Basic)
+	return ok && basic.Kind() != types.UnsafePointer
+}
+
+// genWrapper generates a wrapper for a raw binding.
+// The out parameters are removed from the parameter list and returned as
+// additional results after the result of the raw binding:
+//
+//	func FooGetSizeOut(f *Foo) (ret c.Int, width c.Int, height c.Int) {
+//		ret = FooGetSize(f, &width, &height)
+//		return
+//	}
+//
+// If an error convention is given, the failure is reported as the last result:
+//
+//	func OpenErr(filename *int8, ppDb **Sqlite3) (err error) {
+//		ret := Open(filename, ppDb)
+//		if ret != 0 {
+//			err = Error(ret)
+//		}
+//		return
+//	}
+func (p *Package) genWrapper(fnSpec *GoFuncSpec, sig *types.Signature, cname string, outs []int, conv *cppgtypes.ErrorConvention) {
+	pkg := p.p
+	name := fnSpec.FnName + outWrapperSuffix
+	if conv != nil {
+		name = fnSpec.FnName + errWrapperSuffix
+	}
+	if p.wrapperIsDefined(sig, name) {
+		if dbg.GetDebugError() {
+			log.Printf("genWrapper: %s already defined\n", name)
+		}
+		return
+	}
+	var errType types.Type
+	if conv != nil {
+		errType = p.errorType(conv, sig.Results().At(0).Type())
+		if errType == nil {
+			return
+		}
+	}
+
+	var params, results []*types.Var
+	var ret, errVar *types.Var
+	if sig.Results().Len() > 0 && (conv == nil || conv.Failure != cppgtypes.FailureNonZero) {
+		ret = pkg.NewParam(token.NoPos, uniqueParamName("ret", sig.Params()), sig.Results().At(0).Type())
+		results = append(results, ret)
+	}
+	outVars := make(map[int]*types.Var)
+	args := namedParams(pkg, sig.Params())
+	for i, param := range args {
+		if contains(outs, i) {
+			out := pkg.NewParam(token.NoPos, param.Name(), param.Type().(*types.Pointer).Elem())
+			outVars[i] = out
+			results = append(results, out)
+		} else {
+			params = append(params, param)
+		}
+	}
+	if conv != nil {
+		errVar = pkg.NewParam(token.NoPos, uniqueParamName("err", sig.Params()), types.Universe.Lookup("error").Type())
+		results = append(results, errVar)
+	}
+	var recv *gogen.Param
+	if sig.Recv() != nil {
+		recv = pkg.NewParam(token.NoPos, sig.Recv().Name(), sig.Recv().Type())
+	}
+	fn := pkg.NewFunc(recv, name, types.NewTuple(params...), types.NewTuple(results...), false)
+	fn.SetComments(pkg, wrapperDocComments(name, fnSpec.FnName, len(outs) > 0, conv))
+
+	cb := fn.BodyStart(pkg)
+	if ret != nil {
+		cb.VarRef(ret)
+	} else if conv != nil {
+		cb.DefineVarStart(token.NoPos, uniqueParamName("ret", sig.Params()))
+	}
+	if recv != nil {
+		cb.Val(recv).MemberVal(fnSpec.FnName)
+	} else {
+		cb.Val(pkg.Types.Scope().Lookup(fnSpec.FnName))
+	}
+	for i, arg := range args {
+		if out, ok := outVars[i]; ok {
+			cb.VarRef(out).UnaryOp(token.AND)
+		} else {
+			cb.Val(arg)
+		}
+	}
+	cb.Call(len(args))
+	switch {
+	case ret != nil:
+		cb.Assign(1)
+	case conv != nil:
+		cb.EndInit(1)
+		ret = cb.Scope().Lookup(uniqueParamName("ret", sig.Params())).(*types.Var)
+	default:
+		cb.EndStmt()
+	}
+	if conv != nil {
+		p.checkFailure(cb, conv, cname, ret, errVar, errType)
+	}
+	cb.Return(0).End()
+}
+
+func wrapperDocComments(name, fnName string, hasOuts bool, conv *cppgtypes.ErrorConvention) *goast.CommentGroup {
+	var txt string
+	switch {
+	case hasOuts && conv != nil:
+		txt = " and returns its out parameters as results, reporting failures as an error."
+	case conv != nil:
+		txt = " and reports failures as an error."
+	default:
+		txt = " and returns its out parameters as results."
+	}
+	return &goast.CommentGroup{List: []*goast.Comment{
+		{Text: "// " + name + " wraps " + fnName + txt},
+	}}
+}
+
+func (p *Package) wrapperIsDefined(sig *types.Signature, name string) bool {
+	if sig.Recv() != nil {
+		namedType := getNamedType(sig.Recv().Type())
+		for i := 0; i < namedType.NumMethods(); i++ {
+			if namedType.Method(i).Name() == name {
+				return true
+			}
+		}
+		return false
+	}
+	return p.p.Types.Scope().Lookup(name) != nil
+}
+
+// namedParams returns the parameters of a raw binding, naming the unnamed
+// ones like fieldToVar does so that a wrapper can forward them.
+func namedParams(pkg *gogen.Package, params *types.Tuple) []*types.Var {
+	vars := make([]*types.Var, params.Len())
+	for i := range vars {
+		param := params.At(i)
+		if param.Name() == "" {
+			param = pkg.NewParam(token.NoPos, fmt.Sprintf("__llgo_arg_%d", i), param.Type())
+		}
+		vars[i] = param
+	}
+	return vars
+}
+
+// uniqueParamName returns name, or name with a trailing underscore if it
+// conflicts with one of params.
+func uniqueParamName(name string, params *types.Tuple) string {
+	for i := 0; i < params.Len(); i++ {
+		if params.At(i).Name() == name {
+			return uniqueParamName(name+"_", params)
+		}
+	}
+	return name
+}
+
+func contains[T comparable](list []T, v T) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
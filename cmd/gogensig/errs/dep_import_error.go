@@ -0,0 +1,23 @@
+package errs
+
+import "fmt"
+
+// DepImportError is an error importing the package of the Index-th dep of
+// llcppg.cfg, reported at its JSON path like the problems of llcppg.cfg.
+type DepImportError struct {
+	Index int
+	Path  string
+	Err   error
+}
+
+func (p *DepImportError) Error() string {
+	return fmt.Sprintf("$.deps[%d]: can't import %q: %v", p.Index, p.Path, p.Err)
+}
+
+func (p *DepImportError) Unwrap() error {
+	return p.Err
+}
+
+func NewDepImportError(index int, path string, err error) *DepImportError {
+	return &DepImportError{Index: index, Path: path, Err: err}
+}
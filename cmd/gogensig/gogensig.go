@@ -17,6 +17,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -28,6 +29,7 @@ import (
 	"github.com/goplus/llcppg/cmd/gogensig/convert"
 	"github.com/goplus/llcppg/cmd/gogensig/convert/basic"
 	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+	"github.com/goplus/llcppg/cmd/gogensig/errs"
 	"github.com/goplus/llcppg/cmd/gogensig/unmarshal"
 )
 
@@ -70,7 +72,16 @@ func main() {
 	check(err)
 
 	err = prepareEnv(wd, conf.Name, conf.Deps)
-	check(err)
+	checkDeps(cfg, err)
+	defer func() {
+		// the deps are loaded by the package
+		if r := recover(); r != nil {
+			if err, ok := r.(error); ok {
+				checkDeps(cfg, err)
+			}
+			panic(r)
+		}
+	}()
 
 	p, _, err := basic.ConvertProcesser(&basic.Config{
 		AstConvertConfig: convert.AstConvertConfig{
@@ -95,6 +106,17 @@ func check(err error) {
 	}
 }
 
+// checkDeps reports an error importing the deps of the config file cfg like
+// llcppg reports the problems of the config file, and exits.
+func checkDeps(cfg string, err error) {
+	var depErr *errs.DepImportError
+	if errors.As(err, &depErr) {
+		fmt.Fprintf(os.Stderr, "invalid %s:\n  %s\n", cfg, depErr.Error())
+		os.Exit(1)
+	}
+	check(err)
+}
+
 func prepareEnv(wd, pkg string, deps []string) error {
 	dir := filepath.Join(wd, pkg)
 
@@ -113,10 +135,10 @@ func prepareEnv(wd, pkg string, deps []string) error {
 		return err
 	}
 
-	for _, dep := range deps {
+	for i, dep := range deps {
 		err := config.RunCommand(dir, "go", "get", dep)
 		if err != nil {
-			return err
+			return errs.NewDepImportError(i, dep, err)
 		}
 	}
 
@@ -25,6 +25,7 @@ func init() {
 		"Variadic":    Variadic,
 
 		"PointerType":   PointerType,
+		"QualifiedType": QualifiedType,
 		"LvalueRefType": LvalueRefType,
 		"RvalueRefType": RvalueRefType,
 
@@ -33,6 +34,10 @@ func init() {
 		"FieldList":   FieldList,
 		"ScopingExpr": ScopingExpr,
 		"TagExpr":     TagExpr,
+
+		"InstantiationType": InstantiationType,
+		"BaseSpec":          BaseSpec,
+
 		"EnumItem":    EnumItem,
 		"EnumType":    EnumType,
 		"FuncType":    FuncType,
@@ -42,6 +47,7 @@ func init() {
 		"FuncDecl":     FuncDecl,
 		"TypeDecl":     TypeDecl,
 		"EnumTypeDecl": EnumTypeDecl,
+		"VarDecl":      VarDecl,
 
 		"File":      File,
 		"FileEntry": FileEntry,
@@ -198,8 +204,10 @@ func XType(data []byte, xType ast.Node) (ast.Node, error) {
 		v.X = expr
 	case *ast.RvalueRefType:
 		v.X = expr
+	case *ast.QualifiedType:
+		v.X = expr
 	default:
-		return nil, newUnexpectType("XType", xType, "*ast.PointerType, *ast.LvalueRefType, *ast.RvalueRefType")
+		return nil, newUnexpectType("XType", xType, "*ast.PointerType, *ast.LvalueRefType, *ast.RvalueRefType, *ast.QualifiedType")
 	}
 
 	return xType, nil
@@ -209,6 +217,17 @@ func PointerType(data []byte) (ast.Node, error) {
 	return XType(data, &ast.PointerType{})
 }
 
+func QualifiedType(data []byte) (ast.Node, error) {
+	type qualifiedTemp struct {
+		Qualifiers ast.Qualifier
+	}
+	var qualifiedData qualifiedTemp
+	if err := json.Unmarshal(data, &qualifiedData); err != nil {
+		return nil, newDeserializeError("QualifiedType", qualifiedData, data, err)
+	}
+	return XType(data, &ast.QualifiedType{Qualifiers: qualifiedData.Qualifiers})
+}
+
 func LvalueRefType(data []byte) (ast.Node, error) {
 	return XType(data, &ast.LvalueRefType{})
 }
@@ -219,8 +238,9 @@ func RvalueRefType(data []byte) (ast.Node, error) {
 
 func ArrayType(data []byte) (ast.Node, error) {
 	type arrayTemp struct {
-		Elt json.RawMessage
-		Len json.RawMessage
+		Elt     json.RawMessage
+		Len     json.RawMessage
+		LenExpr json.RawMessage
 	}
 	var arrayData arrayTemp
 	if err := json.Unmarshal(data, &arrayData); err != nil {
@@ -252,6 +272,18 @@ func ArrayType(data []byte) (ast.Node, error) {
 		arrayType.Len = length
 	}
 
+	if len(arrayData.LenExpr) > 0 && !isJSONNull(arrayData.LenExpr) {
+		lenExprNode, err := Node(arrayData.LenExpr)
+		if err != nil {
+			return nil, newUnmarshalFieldError("ArrayType", arrayType, "LenExpr", data, err)
+		}
+		lenExpr, ok := lenExprNode.(ast.Expr)
+		if !ok {
+			return nil, newUnexpectType("ArrayType", lenExprNode, "ast.Expr")
+		}
+		arrayType.LenExpr = lenExpr
+	}
+
 	return arrayType, nil
 }
 
@@ -337,6 +369,36 @@ func TagExpr(data []byte) (ast.Node, error) {
 	return tagExpr, nil
 }
 
+func InstantiationType(data []byte) (ast.Node, error) {
+	type instantiationTypeTemp struct {
+		Template json.RawMessage
+		Args     json.RawMessage
+	}
+	var instData instantiationTypeTemp
+	if err := json.Unmarshal(data, &instData); err != nil {
+		return nil, newDeserializeError("InstantiationType", instData, data, err)
+	}
+
+	templateNode, err := Node(instData.Template)
+	if err != nil {
+		return nil, newUnmarshalFieldError("InstantiationType", instData, "Template", data, err)
+	}
+	template, ok := templateNode.(ast.Expr)
+	if !ok {
+		return nil, newUnexpectType("InstantiationType", templateNode, "ast.Expr")
+	}
+
+	argsNode, err := Node(instData.Args)
+	if err != nil {
+		return nil, newUnmarshalFieldError("InstantiationType", instData, "Args", data, err)
+	}
+	args, ok := argsNode.(*ast.FieldList)
+	if !ok {
+		return nil, newUnexpectType("InstantiationType", argsNode, &ast.FieldList{})
+	}
+	return &ast.InstantiationType{Template: template, Args: args}, nil
+}
+
 func ScopingExpr(data []byte) (ast.Node, error) {
 	type scopingExprTemp struct {
 		Parent json.RawMessage
@@ -374,8 +436,10 @@ func ScopingExpr(data []byte) (ast.Node, error) {
 
 func EnumItem(data []byte) (ast.Node, error) {
 	type enumItemTemp struct {
-		Name  *ast.Ident
-		Value json.RawMessage
+		Doc     *ast.CommentGroup
+		Name    *ast.Ident
+		Value   json.RawMessage
+		Comment *ast.CommentGroup
 	}
 	var enumItemData enumItemTemp
 
@@ -384,7 +448,9 @@ func EnumItem(data []byte) (ast.Node, error) {
 	}
 
 	enumItem := &ast.EnumItem{
-		Name: enumItemData.Name,
+		Doc:     enumItemData.Doc,
+		Name:    enumItemData.Name,
+		Comment: enumItemData.Comment,
 	}
 
 	if !isJSONNull(enumItemData.Value) {
@@ -432,6 +498,8 @@ func RecordType(data []byte) (ast.Node, error) {
 		Tag     ast.Tag
 		Fields  json.RawMessage
 		Methods []json.RawMessage
+		Layout  *ast.RecordLayout
+		Bases   []json.RawMessage
 	}
 	var recordTypeData recordTypeTemp
 	if err := json.Unmarshal(data, &recordTypeData); err != nil {
@@ -441,17 +509,21 @@ func RecordType(data []byte) (ast.Node, error) {
 	recordType := &ast.RecordType{
 		Tag:     recordTypeData.Tag,
 		Methods: []*ast.FuncDecl{},
+		Layout:  recordTypeData.Layout,
 	}
 
-	fieldsNode, err := Node(recordTypeData.Fields)
-	if err != nil {
-		return nil, newUnmarshalFieldError("RecordType", recordTypeData, "Fields", data, err)
-	}
-	fields, ok := fieldsNode.(*ast.FieldList)
-	if !ok {
-		return nil, newUnexpectType("RecordType", fieldsNode, &ast.FieldList{})
+	// Fields is null for a forward declaration
+	if !isJSONNull(recordTypeData.Fields) {
+		fieldsNode, err := Node(recordTypeData.Fields)
+		if err != nil {
+			return nil, newUnmarshalFieldError("RecordType", recordTypeData, "Fields", data, err)
+		}
+		fields, ok := fieldsNode.(*ast.FieldList)
+		if !ok {
+			return nil, newUnexpectType("RecordType", fieldsNode, &ast.FieldList{})
+		}
+		recordType.Fields = fields
 	}
-	recordType.Fields = fields
 
 	for _, methodData := range recordTypeData.Methods {
 		methodNode, err := Node(methodData)
@@ -465,9 +537,49 @@ func RecordType(data []byte) (ast.Node, error) {
 		recordType.Methods = append(recordType.Methods, method)
 	}
 
+	for _, baseData := range recordTypeData.Bases {
+		baseNode, err := Node(baseData)
+		if err != nil {
+			return nil, newUnmarshalFieldError("RecordType", recordTypeData, "Bases", data, err)
+		}
+		base, ok := baseNode.(*ast.BaseSpec)
+		if !ok {
+			return nil, newUnexpectType("RecordType", baseNode, &ast.BaseSpec{})
+		}
+		recordType.Bases = append(recordType.Bases, base)
+	}
+
 	return recordType, nil
 }
 
+func BaseSpec(data []byte) (ast.Node, error) {
+	type baseSpecTemp struct {
+		Type    json.RawMessage
+		Access  ast.AccessSpecifier
+		Virtual bool
+		Offset  int64
+	}
+	var baseData baseSpecTemp
+	if err := json.Unmarshal(data, &baseData); err != nil {
+		return nil, newDeserializeError("BaseSpec", baseData, data, err)
+	}
+
+	typeNode, err := Node(baseData.Type)
+	if err != nil {
+		return nil, newUnmarshalFieldError("BaseSpec", baseData, "Type", data, err)
+	}
+	typ, ok := typeNode.(ast.Expr)
+	if !ok {
+		return nil, newUnexpectType("BaseSpec", typeNode, "ast.Expr")
+	}
+	return &ast.BaseSpec{
+		Type:    typ,
+		Access:  baseData.Access,
+		Virtual: baseData.Virtual,
+		Offset:  baseData.Offset,
+	}, nil
+}
+
 func FuncType(data []byte) (ast.Node, error) {
 	type funcTypeTemp struct {
 		Params json.RawMessage
@@ -612,6 +724,39 @@ func TypeDefDecl(data []byte) (ast.Node, error) {
 	}, nil
 }
 
+func VarDecl(data []byte) (ast.Node, error) {
+	type varDeclTemp struct {
+		Name  *ast.Ident
+		Type  json.RawMessage
+		Value *ast.BasicLit
+	}
+	var varDeclData varDeclTemp
+	if err := json.Unmarshal(data, &varDeclData); err != nil {
+		return nil, newDeserializeError("VarDecl", varDeclData, data, err)
+	}
+
+	typeNode, err := Node(varDeclData.Type)
+	if err != nil {
+		return nil, newUnmarshalFieldError("VarDecl", varDeclData, "Type", data, err)
+	}
+	typ, ok := typeNode.(ast.Expr)
+	if !ok {
+		return nil, newUnexpectType("VarDecl", typeNode, "ast.Expr")
+	}
+
+	declBase, err := declBase(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.VarDecl{
+		DeclBase: declBase,
+		Name:     varDeclData.Name,
+		Type:     typ,
+		Value:    varDeclData.Value,
+	}, nil
+}
+
 func EnumTypeDecl(data []byte) (ast.Node, error) {
 	type enumTypeDeclTemp struct {
 		Name *ast.Ident
@@ -647,6 +792,7 @@ func declBase(data []byte) (ast.DeclBase, error) {
 	type declBaseTemp struct {
 		Loc    *ast.Location
 		Doc    *ast.CommentGroup
+		Attrs  []*ast.Attr
 		Parent json.RawMessage
 	}
 	var declBaseData declBaseTemp
@@ -655,8 +801,9 @@ func declBase(data []byte) (ast.DeclBase, error) {
 	}
 
 	declBase := ast.DeclBase{
-		Loc: declBaseData.Loc,
-		Doc: declBaseData.Doc,
+		Loc:   declBaseData.Loc,
+		Doc:   declBaseData.Doc,
+		Attrs: declBaseData.Attrs,
 	}
 
 	if !isJSONNull(declBaseData.Parent) {
@@ -68,6 +68,37 @@ func TestUnmarshalNode(t *testing.T) {
 					},
 				}},
 		},
+		{
+			name: "QualifiedType",
+			json: `{
+					"_Type":	"QualifiedType",
+					"X":	{
+						"_Type":	"PointerType",
+						"X":	{
+							"_Type":	"QualifiedType",
+							"X":	{
+								"_Type":	"BuiltinType",
+								"Kind":	2,
+								"Flags":	1
+							},
+							"Qualifiers":	1
+						}
+					},
+					"Qualifiers":	16
+				}`,
+			expected: &ast.QualifiedType{
+				X: &ast.PointerType{
+					X: &ast.QualifiedType{
+						X: &ast.BuiltinType{
+							Kind:  2,
+							Flags: 1,
+						},
+						Qualifiers: ast.Const,
+					},
+				},
+				Qualifiers: ast.Nonnull,
+			},
+		},
 		{
 			name: "ArrayType",
 			json: `{
@@ -87,6 +118,34 @@ func TestUnmarshalNode(t *testing.T) {
 				Len: nil,
 			},
 		},
+		{
+			name: "ArrayType with spelled length",
+			json: `{
+					"_Type":	"ArrayType",
+					"Elt":	{
+						"_Type":	"BuiltinType",
+						"Kind":	2,
+						"Flags":	1
+					},
+					"Len":	{
+						"_Type":	"BasicLit",
+						"Kind":	0,
+						"Value":	"64"
+					},
+					"LenExpr":	{
+						"_Type":	"Ident",
+						"Name":	"NAME_MAX"
+					}
+				}`,
+			expected: &ast.ArrayType{
+				Elt: &ast.BuiltinType{
+					Kind:  2,
+					Flags: 1,
+				},
+				Len:     &ast.BasicLit{Kind: ast.IntLit, Value: "64"},
+				LenExpr: &ast.Ident{Name: "NAME_MAX"},
+			},
+		},
 		{
 			name: "ArrayType",
 			json: `{
@@ -275,6 +334,45 @@ func TestUnmarshalNode(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "InstantiationType",
+			json: `{
+					"_Type":	"InstantiationType",
+					"Template":	{
+						"_Type":	"ScopingExpr",
+						"X":	{
+							"_Type":	"Ident",
+							"Name":	"vector"
+						},
+						"Parent":	{
+							"_Type":	"Ident",
+							"Name":	"std"
+						}
+					},
+					"Args":	{
+						"_Type":	"FieldList",
+						"List":	[{
+								"_Type":	"Field",
+								"Type":	{
+									"_Type":	"BuiltinType",
+									"Kind":	6,
+									"Flags":	0
+								}
+							}]
+					}
+				}`,
+			expected: &ast.InstantiationType{
+				Template: &ast.ScopingExpr{
+					X:      &ast.Ident{Name: "vector"},
+					Parent: &ast.Ident{Name: "std"},
+				},
+				Args: &ast.FieldList{
+					List: []*ast.Field{
+						{Type: &ast.BuiltinType{Kind: ast.Int}},
+					},
+				},
+			},
+		},
 		{
 			name: "Field",
 			json: `{
@@ -431,12 +529,20 @@ func TestUnmarshalNode(t *testing.T) {
 				"_Type":	"FuncDecl",
 				"Loc":	{
 					"_Type":	"Location",
-					"File":	"temp.h"
+					"File":	"temp.h",
+					"Line":	1,
+					"Column":	6,
+					"Offset":	5
 				},
 				"Doc":	{
 					"_Type":	"CommentGroup",
 					"List":	[]
 				},
+				"Attrs":	[{
+						"_Type":	"Attr",
+						"Name":	"format",
+						"Args":	["printf", "1", "2"]
+					}],
 				"Parent":	null,
 				"Name":	{
 					"_Type":	"Ident",
@@ -497,11 +603,17 @@ func TestUnmarshalNode(t *testing.T) {
 			expected: &ast.FuncDecl{
 				DeclBase: ast.DeclBase{
 					Loc: &ast.Location{
-						File: "temp.h",
+						File:   "temp.h",
+						Line:   1,
+						Column: 6,
+						Offset: 5,
 					},
 					Doc: &ast.CommentGroup{
 						List: []*ast.Comment{},
 					},
+					Attrs: []*ast.Attr{
+						{Name: ast.AttrFormat, Args: []string{"printf", "1", "2"}},
+					},
 				},
 				Name: &ast.Ident{Name: "foo"},
 				Type: &ast.FuncType{
@@ -775,6 +887,114 @@ func TestUnmarshalNode(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "RecordType forward declaration",
+			json: `{
+					"_Type":	"RecordType",
+					"Tag":	0,
+					"Fields":	null,
+					"Methods":	[]
+				}`,
+			expected: &ast.RecordType{
+				Tag:     0,
+				Methods: []*ast.FuncDecl{},
+			},
+		},
+		{
+			name: "RecordType with layout",
+			json: `{
+					"_Type":	"RecordType",
+					"Tag":	0,
+					"Fields":	{
+						"_Type":	"FieldList",
+						"List":	[{
+								"_Type":	"Field",
+								"Type":	{
+									"_Type":	"BuiltinType",
+									"Kind":	6,
+									"Flags":	0
+								},
+								"Doc":	null,
+								"Comment":	null,
+								"IsStatic":	false,
+								"Access":	1,
+								"Names":	[{
+										"_Type":	"Ident",
+										"Name":	"len"
+									}]
+							}]
+					},
+					"Methods":	[],
+					"Layout":	{
+						"_Type":	"RecordLayout",
+						"Packed":	true,
+						"Aligned":	false,
+						"Size":	5,
+						"Align":	1,
+						"Offsets":	[1]
+					}
+				}`,
+			expected: &ast.RecordType{
+				Tag: 0,
+				Fields: &ast.FieldList{
+					List: []*ast.Field{
+						{
+							Type:   &ast.BuiltinType{Kind: ast.Int},
+							Access: ast.Public,
+							Names:  []*ast.Ident{{Name: "len"}},
+						},
+					},
+				},
+				Methods: []*ast.FuncDecl{},
+				Layout: &ast.RecordLayout{
+					Packed:  true,
+					Size:    5,
+					Align:   1,
+					Offsets: []int64{1},
+				},
+			},
+		},
+		{
+			name: "RecordType with bases",
+			json: `{
+					"_Type":	"RecordType",
+					"Tag":	3,
+					"Fields":	{
+						"_Type":	"FieldList",
+						"List":	null
+					},
+					"Methods":	[],
+					"Layout":	null,
+					"Bases":	[{
+							"_Type":	"BaseSpec",
+							"Type":	{
+								"_Type":	"Ident",
+								"Name":	"A"
+							},
+							"Access":	1,
+							"Virtual":	false,
+							"Offset":	0
+						}, {
+							"_Type":	"BaseSpec",
+							"Type":	{
+								"_Type":	"Ident",
+								"Name":	"B"
+							},
+							"Access":	2,
+							"Virtual":	true,
+							"Offset":	-1
+						}]
+				}`,
+			expected: &ast.RecordType{
+				Tag:     3,
+				Fields:  &ast.FieldList{},
+				Methods: []*ast.FuncDecl{},
+				Bases: []*ast.BaseSpec{
+					{Type: &ast.Ident{Name: "A"}, Access: ast.Public},
+					{Type: &ast.Ident{Name: "B"}, Access: ast.Protected, Virtual: true, Offset: -1},
+				},
+			},
+		},
 		{
 			name: "TypedefDecl",
 			json: `{
@@ -817,6 +1037,51 @@ func TestUnmarshalNode(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "VarDecl",
+			json: `{
+				"_Type":	"VarDecl",
+				"Loc":	{
+					"_Type":	"Location",
+					"File":	"temp.h"
+				},
+				"Doc":	null,
+				"Parent":	null,
+				"Name":	{
+					"_Type":	"Ident",
+					"Name":	"FOO_MAX"
+				},
+				"Type":	{
+					"_Type":	"QualifiedType",
+					"X":	{
+						"_Type":	"BuiltinType",
+						"Kind":	6,
+						"Flags":	0
+					},
+					"Qualifiers":	1
+				},
+				"Value":	{
+					"_Type":	"BasicLit",
+					"Kind":	0,
+					"Value":	"64"
+				}
+			}`,
+			expected: &ast.VarDecl{
+				DeclBase: ast.DeclBase{
+					Loc: &ast.Location{
+						File: "temp.h",
+					},
+				},
+				Name: &ast.Ident{
+					Name: "FOO_MAX",
+				},
+				Type: &ast.QualifiedType{
+					X:          &ast.BuiltinType{Kind: ast.Int},
+					Qualifiers: ast.Const,
+				},
+				Value: &ast.BasicLit{Kind: ast.IntLit, Value: "64"},
+			},
+		},
 		{
 			name: "EnumItem",
 			json: `{
@@ -841,6 +1106,50 @@ func TestUnmarshalNode(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "EnumItem with comments",
+			json: `{
+						"_Type":	"EnumItem",
+						"Name":	{
+							"_Type":	"Ident",
+							"Name":	"a"
+						},
+						"Value":	{
+							"_Type":	"BasicLit",
+							"Kind":	0,
+							"Value":	"0"
+						},
+						"Doc":	{
+							"_Type":	"CommentGroup",
+							"List":	[{
+									"_Type":	"Comment",
+									"Text":	"/// doc\n"
+								}]
+						},
+						"Comment":	{
+							"_Type":	"CommentGroup",
+							"List":	[{
+									"_Type":	"Comment",
+									"Text":	"///< comment\n"
+								}]
+						}
+					}`,
+			expected: &ast.EnumItem{
+				Doc: &ast.CommentGroup{
+					List: []*ast.Comment{{Text: "/// doc\n"}},
+				},
+				Name: &ast.Ident{
+					Name: "a",
+				},
+				Value: &ast.BasicLit{
+					Kind:  0,
+					Value: "0",
+				},
+				Comment: &ast.CommentGroup{
+					List: []*ast.Comment{{Text: "///< comment\n"}},
+				},
+			},
+		},
 		{
 			name: "EnumTypeDecl",
 			json: `{
@@ -1938,6 +2247,37 @@ func TestUnmarshalErrors(t *testing.T) {
 			input:       `{"Name": {"_Type": "Token", "Token": 1, "Lit": "test"}, "Tag": 0}`,
 			expectedErr: "unmarshal error in TagExpr: got *ast.Token, want ast.Expr",
 		},
+		// unmarshalInstantiationType errors
+		{
+			name:        "unmarshalInstantiationType - Invalid JSON",
+			fn:          unmarshal.InstantiationType,
+			input:       `{"invalid": "json"`,
+			expectedErr: "unmarshal error in InstantiationType into unmarshal.instantiationTypeTemp",
+		},
+		{
+			name:        "unmarshalInstantiationType - Invalid Template",
+			fn:          unmarshal.InstantiationType,
+			input:       `{"Template": {"_Type": "InvalidType"}, "Args": {"_Type": "FieldList", "List": []}}`,
+			expectedErr: "unmarshal error in InstantiationType when converting Template of unmarshal.instantiationTypeTemp",
+		},
+		{
+			name:        "unmarshalInstantiationType - Unexpected Template",
+			fn:          unmarshal.InstantiationType,
+			input:       `{"Template": {"_Type": "Token", "Token": 1, "Lit": "test"}, "Args": {"_Type": "FieldList", "List": []}}`,
+			expectedErr: "unmarshal error in InstantiationType: got *ast.Token, want ast.Expr",
+		},
+		{
+			name:        "unmarshalInstantiationType - Invalid Args",
+			fn:          unmarshal.InstantiationType,
+			input:       `{"Template": {"_Type": "Ident", "Name": "vector"}, "Args": {"_Type": "InvalidType"}}`,
+			expectedErr: "unmarshal error in InstantiationType when converting Args of unmarshal.instantiationTypeTemp",
+		},
+		{
+			name:        "unmarshalInstantiationType - Unexpected Args",
+			fn:          unmarshal.InstantiationType,
+			input:       `{"Template": {"_Type": "Ident", "Name": "vector"}, "Args": {"_Type": "Ident", "Name": "int"}}`,
+			expectedErr: "unmarshal error in InstantiationType: got *ast.Ident, want *ast.FieldList",
+		},
 
 		// unmarshalScopingExpr errors
 		{
@@ -2042,6 +2382,38 @@ func TestUnmarshalErrors(t *testing.T) {
 			input:       `{"Tag": 0, "Fields": {"_Type": "FieldList", "List": []}, "Methods": [{"_Type": "Token", "Token": 1, "Lit": "test"}]}`,
 			expectedErr: "unmarshal error in RecordType: got *ast.Token, want *ast.FuncDecl",
 		},
+		{
+			name:        "unmarshalRecordType - Invalid Base",
+			fn:          unmarshal.RecordType,
+			input:       `{"Tag": 3, "Fields": {"_Type": "FieldList", "List": []}, "Methods": [], "Bases": [{"_Type": "InvalidType"}]}`,
+			expectedErr: "unmarshal error in RecordType when converting Bases of unmarshal.recordTypeTemp",
+		},
+		{
+			name:        "unmarshalRecordType - Unexpected Base",
+			fn:          unmarshal.RecordType,
+			input:       `{"Tag": 3, "Fields": {"_Type": "FieldList", "List": []}, "Methods": [], "Bases": [{"_Type": "Ident", "Name": "A"}]}`,
+			expectedErr: "unmarshal error in RecordType: got *ast.Ident, want *ast.BaseSpec",
+		},
+
+		// unmarshalBaseSpec errors
+		{
+			name:        "unmarshalBaseSpec - Invalid JSON",
+			fn:          unmarshal.BaseSpec,
+			input:       `{"invalid": "json"`,
+			expectedErr: "unmarshal error in BaseSpec into unmarshal.baseSpecTemp",
+		},
+		{
+			name:        "unmarshalBaseSpec - Invalid Type",
+			fn:          unmarshal.BaseSpec,
+			input:       `{"Type": {"_Type": "InvalidType"}, "Access": 1}`,
+			expectedErr: "unmarshal error in BaseSpec when converting Type of unmarshal.baseSpecTemp",
+		},
+		{
+			name:        "unmarshalBaseSpec - Unexpected Type",
+			fn:          unmarshal.BaseSpec,
+			input:       `{"Type": {"_Type": "Token", "Token": 1, "Lit": "test"}, "Access": 1}`,
+			expectedErr: "unmarshal error in BaseSpec: got *ast.Token, want ast.Expr",
+		},
 
 		// unmarshalFuncType errors
 		{
@@ -12,11 +12,12 @@ type DocVisitor interface {
 	VisitFuncDecl(funcDecl *ast.FuncDecl)
 	VisitDone(path string)
 	VisitStruct(structName *ast.Ident, fields *ast.FieldList, typeDecl *ast.TypeDecl)
-	//VisitClass(className *ast.Ident, fields *ast.FieldList, typeDecl *ast.TypeDecl)
-	//VisitMethod(className *ast.Ident, method *ast.FuncDecl, typeDecl *ast.TypeDecl)
+	VisitClass(className *ast.Ident, fields *ast.FieldList, typeDecl *ast.TypeDecl)
+	VisitMethod(className *ast.Ident, method *ast.FuncDecl, typeDecl *ast.TypeDecl)
 	VisitUnion(unionName *ast.Ident, fields *ast.FieldList, typeDecl *ast.TypeDecl)
 	VisitEnumTypeDecl(enumTypeDecl *ast.EnumTypeDecl)
 	VisitTypedefDecl(typedefDecl *ast.TypedefDecl)
+	VisitVarDecl(varDecl *ast.VarDecl)
 	VisitMacro(macro *ast.Macro)
 }
 
@@ -38,6 +39,8 @@ func (p *BaseDocVisitor) visitNode(decl ast.Node) {
 		p.visitEnumTypeDecl(v)
 	case *ast.TypedefDecl:
 		p.visitTypedefDecl(v)
+	case *ast.VarDecl:
+		p.visitVarDecl(v)
 	default:
 		panic(fmt.Errorf("todo visit %v", v))
 	}
@@ -68,24 +71,25 @@ func (p *BaseDocVisitor) visitTypeDecl(typeDecl *ast.TypeDecl) {
 	if typeDecl == nil {
 		return
 	}
-	if typeDecl.Type.Tag == ast.Class {
+	switch typeDecl.Type.Tag {
+	case ast.Class:
 		p.visitClass(typeDecl.Name, typeDecl.Type.Fields, typeDecl)
-		for _, method := range typeDecl.Type.Methods {
-			p.visitMethod(typeDecl.Name, method, typeDecl)
-		}
-	} else if typeDecl.Type.Tag == ast.Struct {
+	case ast.Struct:
 		p.visitStruct(typeDecl.Name, typeDecl.Type.Fields, typeDecl)
-	} else if typeDecl.Type.Tag == ast.Union {
+	case ast.Union:
 		p.visitUnion(typeDecl.Name, typeDecl.Type.Fields, typeDecl)
 	}
+	for _, method := range typeDecl.Type.Methods {
+		p.visitMethod(typeDecl.Name, method, typeDecl)
+	}
 }
 
 func (p *BaseDocVisitor) visitClass(className *ast.Ident, fields *ast.FieldList, typeDecl *ast.TypeDecl) {
-	//p.VisitClass(className, fields, typeDecl)
+	p.VisitClass(className, fields, typeDecl)
 }
 
 func (p *BaseDocVisitor) visitMethod(className *ast.Ident, method *ast.FuncDecl, typeDecl *ast.TypeDecl) {
-	//p.VisitMethod(className, method, typeDecl)
+	p.VisitMethod(className, method, typeDecl)
 }
 
 func (p *BaseDocVisitor) visitStruct(structName *ast.Ident, fields *ast.FieldList, typeDecl *ast.TypeDecl) {
@@ -110,6 +114,13 @@ func (p *BaseDocVisitor) visitTypedefDecl(typedefDecl *ast.TypedefDecl) {
 	p.VisitTypedefDecl(typedefDecl)
 }
 
+func (p *BaseDocVisitor) visitVarDecl(varDecl *ast.VarDecl) {
+	if varDecl == nil {
+		return
+	}
+	p.VisitVarDecl(varDecl)
+}
+
 func (p *BaseDocVisitor) visitMacro(macro *ast.Macro) {
 	p.VisitMacro(macro)
 }
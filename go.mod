@@ -7,10 +7,10 @@ require (
 	github.com/goplus/gogen v1.16.4
 	github.com/goplus/llgo v0.10.0-pre.1.0.20250206090032-a345746cbd89
 	github.com/goplus/mod v0.13.12
+	golang.org/x/mod v0.19.0
 )
 
 require (
 	github.com/qiniu/x v1.13.10 // indirect
-	golang.org/x/mod v0.19.0 // indirect
 	golang.org/x/tools v0.19.0 // indirect
 )
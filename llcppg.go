@@ -19,6 +19,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -26,6 +27,8 @@ import (
 	"os/exec"
 
 	"github.com/goplus/llcppg/_xtool/llcppsymg/args"
+	"github.com/goplus/llcppg/_xtool/llcppsymg/syspath"
+	"github.com/goplus/llcppg/cfgcheck"
 	"github.com/goplus/llcppg/types"
 	"github.com/goplus/llgo/xtool/env"
 )
@@ -101,9 +104,10 @@ func gogensig(in io.Reader, cfg string, v verboseFlags) error {
 
 func main() {
 	var symbGen, codeGen, help bool
+	var tags string
 	var vSymg, vSigfetch, vGogen, vAll bool
 	flag.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: llcppg [-v|-vfetch|-vsymg|-vgogen] [-symbgen] [-codegen] [-h|--help] [config-file]")
+		fmt.Fprintln(os.Stderr, "Usage: llcppg [-v|-vfetch|-vsymg|-vgogen] [-symbgen] [-codegen] [-tags tag,...] [-h|--help] [config-file]")
 		fmt.Fprintln(os.Stderr, "Options:")
 		flag.PrintDefaults()
 	}
@@ -115,6 +119,7 @@ func main() {
 	flag.BoolVar(&codeGen, "codegen", false, "Only use (llcppsigfetch & gogensig) to generate go code binding")
 	flag.BoolVar(&help, "h", false, "Display help information")
 	flag.BoolVar(&help, "help", false, "Display help information")
+	flag.StringVar(&tags, "tags", "", "Comma-separated custom tags of the platform configs")
 	flag.Parse()
 
 	verbose := verboseFlags(0)
@@ -154,20 +159,42 @@ func main() {
 		cfgFile = args.LLCPPG_CFG
 	}
 
+	if tags != "" {
+		// the tools run by llcppg get the tags by the environment
+		os.Setenv(types.TagsEnv, tags)
+	}
+
 	do(cfgFile, mode, verbose)
 }
 
 func do(cfgFile string, mode modeFlags, verbose verboseFlags) {
-	f, err := os.Open(cfgFile)
+	data, err := os.ReadFile(cfgFile)
 	check(err)
-	defer f.Close()
 
-	var conf types.Config
-	json.NewDecoder(f).Decode(&conf)
+	conf, err := cfgcheck.Decode(data)
+	checkConf(cfgFile, err)
 	conf.CFlags = env.ExpandEnv(conf.CFlags)
 	conf.Libs = env.ExpandEnv(conf.Libs)
+	for _, platform := range conf.Platforms {
+		if platform != nil {
+			platform.CFlags = env.ExpandEnv(platform.CFlags)
+			platform.Libs = env.ExpandEnv(platform.Libs)
+		}
+	}
+
+	// llcppsymg reads the symbols of libs, and gogensig links the package to
+	// them
+	err = cfgcheck.Check(conf, &cfgcheck.Options{
+		IncPaths:  syspath.GetIncludePaths(),
+		LibPaths:  syspath.GetLibPaths(),
+		CheckLibs: mode&(ModeSymbGen|ModeCodegen) != 0,
+	})
+	checkConf(cfgFile, err)
 
-	b, err := json.MarshalIndent(&conf, "", "  ")
+	// llcppsymg and llcppsigfetch get the merged config, gogensig merges
+	// llcppg.cfg by the same GOOS, GOARCH and tags of the environment
+	conf.ResolvePlatform()
+	b, err := json.MarshalIndent(conf, "", "  ")
 	check(err)
 
 	if mode&ModeSymbGen != 0 {
@@ -179,9 +206,26 @@ func do(cfgFile string, mode modeFlags, verbose verboseFlags) {
 		r, w := io.Pipe()
 		go llcppsigfetch(b, verbose, w)
 
+		// gogensig reports the deps it can't import like checkConf
 		err = gogensig(r, cfgFile, verbose)
-		check(err)
+		checkExit(err)
+	}
+}
+
+// checkConf reports the problems of the config file and exits if there is any.
+func checkConf(cfgFile string, err error) {
+	if err == nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "invalid %s:\n", cfgFile)
+	if errs, ok := err.(cfgcheck.Errors); ok {
+		for _, e := range errs {
+			fmt.Fprintln(os.Stderr, "  "+e.Error())
+		}
+	} else {
+		fmt.Fprintln(os.Stderr, "  "+err.Error())
 	}
+	os.Exit(1)
 }
 
 func check(err error) {
@@ -189,3 +233,13 @@ func check(err error) {
 		panic(err)
 	}
 }
+
+// checkExit exits with the exit code of a tool which failed, as the tool has
+// reported why.
+func checkExit(err error) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	check(err)
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Filters selects the declarations which are converted. A nil filter selects
+// everything.
+type Filters struct {
+	Symbols *Filter `json:"symbols,omitempty"` // functions and variables, by C name or qualified C++ name like ns::Foo::bar
+	Types   *Filter `json:"types,omitempty"`   // records, enums and typedefs, by C name or qualified C++ name
+	Macros  *Filter `json:"macros,omitempty"`  // macros, by name
+	Headers *Filter `json:"headers,omitempty"` // header files, by path like foo/internal.h
+}
+
+// Filter selects the names matched by a pattern of Include, or all names if it's
+// empty, and not matched by any pattern of Exclude. A pattern is a glob, where *
+// matches any sequence of characters but /, or a regular expression if it's
+// enclosed in slashes, like /^foo_.*_internal$/.
+//
+// The patterns are compiled once, when f is decoded from JSON or by Compile. A
+// filter which isn't compiled yet is compiled by its first match, which panics
+// if a pattern is invalid.
+type Filter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+
+	include, exclude []*Pattern
+	compiled         bool
+}
+
+// Compile compiles the patterns of f, and returns a *PatternError, whose Path
+// is like exclude[1], if one of them is invalid.
+func (f *Filter) Compile() error {
+	include, err := compilePatterns("include", f.Include)
+	if err != nil {
+		return err
+	}
+	exclude, err := compilePatterns("exclude", f.Exclude)
+	if err != nil {
+		return err
+	}
+	f.include, f.exclude, f.compiled = include, exclude, true
+	return nil
+}
+
+// UnmarshalJSON decodes f and compiles its patterns.
+func (f *Filter) UnmarshalJSON(data []byte) error {
+	type filter Filter
+	if err := json.Unmarshal(data, (*filter)(f)); err != nil {
+		return err
+	}
+	return f.Compile()
+}
+
+func (f *Filter) mustCompile() {
+	if f.compiled {
+		return
+	}
+	if err := f.Compile(); err != nil {
+		panic(err)
+	}
+}
+
+// Match reports whether name is selected by f.
+func (f *Filter) Match(name string) bool {
+	if f == nil {
+		return true
+	}
+	f.mustCompile()
+	if len(f.include) > 0 && !matchAny(f.include, name) {
+		return false
+	}
+	return !matchAny(f.exclude, name)
+}
+
+// MatchPath reports whether the header file filename is selected by f. A pattern
+// matches the trailing elements of filename, so foo/internal.h matches both
+// /usr/include/foo/internal.h and foo/internal.h.
+func (f *Filter) MatchPath(filename string) bool {
+	if f == nil {
+		return true
+	}
+	f.mustCompile()
+	if len(f.include) > 0 && !matchAnyPath(f.include, filename) {
+		return false
+	}
+	return !matchAnyPath(f.exclude, filename)
+}
+
+// Symbol reports whether the function or variable name is converted.
+func (f *Filters) Symbol(name string) bool {
+	return f == nil || f.Symbols.Match(name)
+}
+
+// Type reports whether the type name is converted.
+func (f *Filters) Type(name string) bool {
+	return f == nil || f.Types.Match(name)
+}
+
+// Macro reports whether the macro name is converted.
+func (f *Filters) Macro(name string) bool {
+	return f == nil || f.Macros.Match(name)
+}
+
+// Header reports whether the declarations of the header file filename are
+// converted.
+func (f *Filters) Header(filename string) bool {
+	return f == nil || f.Headers.MatchPath(filename)
+}
+
+func matchAny(patterns []*Pattern, name string) bool {
+	for _, pattern := range patterns {
+		if pattern.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyPath(patterns []*Pattern, filename string) bool {
+	filename = path.Clean(strings.ReplaceAll(filename, "\\", "/"))
+	for {
+		if matchAny(patterns, filename) {
+			return true
+		}
+		i := strings.IndexByte(filename, '/')
+		if i < 0 {
+			return false
+		}
+		filename = filename[i+1:]
+	}
+}
+
+func compilePatterns(key string, patterns []string) ([]*Pattern, error) {
+	compiled := make([]*Pattern, len(patterns))
+	for i, pattern := range patterns {
+		p, err := CompilePattern(pattern)
+		if err != nil {
+			perr := err.(*PatternError)
+			perr.Path = fmt.Sprintf("%s[%d]", key, i)
+			return nil, perr
+		}
+		compiled[i] = p
+	}
+	return compiled, nil
+}
+
+// Pattern is a compiled glob or /regexp/ pattern of a Filter.
+type Pattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+// PatternError is an invalid pattern of a Filter, at Path of the filter if it's
+// known.
+type PatternError struct {
+	Path    string
+	Pattern string
+	Err     error
+}
+
+func (e *PatternError) Error() string {
+	msg := fmt.Sprintf("invalid pattern %q: %v", e.Pattern, e.Err)
+	if e.Path != "" {
+		return e.Path + ": " + msg
+	}
+	return msg
+}
+
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
+
+// CompilePattern compiles the glob or /regexp/ pattern, and returns a
+// *PatternError if it's invalid.
+func CompilePattern(pattern string) (*Pattern, error) {
+	if n := len(pattern); n >= 2 && pattern[0] == '/' && pattern[n-1] == '/' {
+		re, err := regexp.Compile(pattern[1 : n-1])
+		if err != nil {
+			return nil, &PatternError{Pattern: pattern, Err: err}
+		}
+		return &Pattern{re: re}, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, &PatternError{Pattern: pattern, Err: err}
+	}
+	return &Pattern{glob: pattern}, nil
+}
+
+// Match reports whether name matches p.
+func (p *Pattern) Match(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	ok, _ := path.Match(p.glob, name)
+	return ok
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+	f := &Filter{
+		Include: []string{"foo_*", "/^ns::/"},
+		Exclude: []string{"*_internal", "/::detail::/"},
+	}
+	for name, want := range map[string]bool{
+		"foo_open":           true,
+		"foo_open_internal":  false,
+		"bar_open":           false,
+		"ns::Foo::bar":       true,
+		"ns::detail::helper": false,
+	} {
+		if got := f.Match(name); got != want {
+			t.Errorf("Match(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestFilterMatchPath(t *testing.T) {
+	f := &Filter{Exclude: []string{"foo/internal.h", "*_priv.h"}}
+	for filename, want := range map[string]bool{
+		"/usr/include/foo/internal.h": false,
+		"foo/internal.h":              false,
+		"/usr/include/foo/public.h":   true,
+		"/usr/include/foo/io_priv.h":  false,
+		"/usr/include/bar/internal.h": true,
+	} {
+		if got := f.MatchPath(filename); got != want {
+			t.Errorf("MatchPath(%q) = %v, want %v", filename, got, want)
+		}
+	}
+}
+
+func TestNilFilters(t *testing.T) {
+	var f *Filters
+	if !f.Symbol("a") || !f.Type("a") || !f.Macro("a") || !f.Header("a.h") {
+		t.Error("nil filters should select everything")
+	}
+}
+
+func TestFilterCompile(t *testing.T) {
+	var f Filter
+	err := json.Unmarshal([]byte(`{"include": ["foo_*"], "exclude": ["*_internal", "/[/"]}`), &f)
+	want := `exclude[1]: invalid pattern "/[/": error parsing regexp: missing closing ]: ` + "`[`"
+	if err == nil || err.Error() != want {
+		t.Errorf("Unmarshal: got %v, want %s", err, want)
+	}
+	var perr *PatternError
+	if !errors.As(err, &perr) || perr.Pattern != "/[/" {
+		t.Errorf("Unmarshal: got %#v, want a *PatternError", err)
+	}
+	if _, err := CompilePattern("foo_[a-"); err == nil {
+		t.Error("CompilePattern: want an error of the invalid glob")
+	}
+
+	f = Filter{Include: []string{"foo_*"}}
+	if err := f.Compile(); err != nil || !f.Match("foo_open") || f.Match("bar_open") {
+		t.Errorf("Compile: %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Match should panic on an invalid pattern")
+		}
+	}()
+	(&Filter{Exclude: []string{"/(/"}}).Match("foo")
+}
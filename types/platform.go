@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// TagsEnv is the environment variable of the custom tags of the platform
+// configs, separated by commas. The -tags flag of llcppg sets it for all tools.
+const TagsEnv = "LLCPPG_TAGS"
+
+// PlatformConfig is merged over the base config if its condition is satisfied.
+// Its values are appended to the ones of the base config, which it can't
+// replace or remove.
+type PlatformConfig struct {
+	// When is the condition, terms separated by commas which must all be
+	// satisfied, each a GOOS, a GOARCH or a custom tag, negated if prefixed
+	// by !, like linux,amd64 or darwin,!threads.
+	When         string   `json:"when"`
+	CFlags       string   `json:"cflags,omitempty"`
+	Libs         string   `json:"libs,omitempty"`
+	Include      []string `json:"include,omitempty"`
+	TrimPrefixes []string `json:"trimPrefixes,omitempty"`
+}
+
+// Match reports whether the condition of p is satisfied by goos, goarch and tags.
+func (p *PlatformConfig) Match(goos, goarch string, tags []string) bool {
+	for _, term := range strings.Split(p.When, ",") {
+		term = strings.TrimSpace(term)
+		name := strings.TrimPrefix(term, "!")
+		has := name == goos || name == goarch
+		for _, tag := range tags {
+			has = has || name == tag
+		}
+		if has == (name != term) {
+			return false
+		}
+	}
+	return true
+}
+
+// MergePlatforms merges the platform configs of c whose conditions are
+// satisfied by goos, goarch and tags over c in their order, and removes them
+// from c, so merging again doesn't change c.
+func (c *Config) MergePlatforms(goos, goarch string, tags []string) {
+	for _, p := range c.Platforms {
+		if p == nil || !p.Match(goos, goarch, tags) {
+			continue
+		}
+		c.CFlags = joinFlags(c.CFlags, p.CFlags)
+		c.Libs = joinFlags(c.Libs, p.Libs)
+		c.Include = append(c.Include, p.Include...)
+		c.TrimPrefixes = append(c.TrimPrefixes, p.TrimPrefixes...)
+	}
+	c.Platforms = nil
+}
+
+// ResolvePlatform merges the platform configs of c for the TargetPlatform and
+// the tags of TagsEnv.
+func (c *Config) ResolvePlatform() {
+	goos, goarch := TargetPlatform()
+	c.MergePlatforms(goos, goarch, EnvTags())
+}
+
+// TargetPlatform returns the GOOS and GOARCH which the platform configs are
+// merged for: the GOOS and GOARCH environment variables if they are set, like
+// go build, or the ones of the running tool.
+func TargetPlatform() (goos, goarch string) {
+	goos, goarch = os.Getenv("GOOS"), os.Getenv("GOARCH")
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	return
+}
+
+// EnvTags returns the custom tags of TagsEnv.
+func EnvTags() []string {
+	return strings.FieldsFunc(os.Getenv(TagsEnv), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+func joinFlags(base, flags string) string {
+	if base == "" || flags == "" {
+		return base + flags
+	}
+	return base + " " + flags
+}
@@ -0,0 +1,79 @@
+package types
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestPlatformMatch(t *testing.T) {
+	tags := []string{"threads"}
+	for when, want := range map[string]bool{
+		"linux":              true,
+		"darwin":             false,
+		"linux,amd64":        true,
+		"linux,arm64":        false,
+		"threads":            true,
+		"linux, !threads":    false,
+		"!windows,!nodebug":  true,
+		"amd64,threads,!cgo": true,
+		"":                   false,
+	} {
+		p := &PlatformConfig{When: when}
+		if got := p.Match("linux", "amd64", tags); got != want {
+			t.Errorf("Match(%q) = %v, want %v", when, got, want)
+		}
+	}
+}
+
+func TestMergePlatforms(t *testing.T) {
+	conf := &Config{
+		CFlags:       "-I/usr/include/foo",
+		Include:      []string{"foo.h"},
+		TrimPrefixes: []string{"foo_"},
+		Platforms: []*PlatformConfig{
+			{When: "linux", Include: []string{"foo_linux.h"}, Libs: "-lfoo"},
+			{When: "darwin", Include: []string{"foo_darwin.h"}},
+			{When: "threads", CFlags: "-DFOO_USE_THREADS", Include: []string{"foo_threads.h"}, TrimPrefixes: []string{"foo_thread_"}},
+		},
+	}
+	conf.MergePlatforms("linux", "amd64", []string{"threads"})
+	conf.MergePlatforms("linux", "amd64", []string{"threads"})
+	want := &Config{
+		CFlags:       "-I/usr/include/foo -DFOO_USE_THREADS",
+		Libs:         "-lfoo",
+		Include:      []string{"foo.h", "foo_linux.h", "foo_threads.h"},
+		TrimPrefixes: []string{"foo_", "foo_thread_"},
+	}
+	if !reflect.DeepEqual(conf, want) {
+		t.Errorf("MergePlatforms() = %+v, want %+v", conf, want)
+	}
+}
+
+func TestEnvTags(t *testing.T) {
+	t.Setenv(TagsEnv, "threads, debug,,ssl")
+	if got, want := EnvTags(), []string{"threads", "debug", "ssl"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("EnvTags() = %v, want %v", got, want)
+	}
+}
+
+func TestTargetPlatform(t *testing.T) {
+	t.Setenv("GOOS", "")
+	t.Setenv("GOARCH", "")
+	if goos, goarch := TargetPlatform(); goos != runtime.GOOS || goarch != runtime.GOARCH {
+		t.Errorf("TargetPlatform() = %s, %s, want %s, %s", goos, goarch, runtime.GOOS, runtime.GOARCH)
+	}
+	t.Setenv("GOOS", "windows")
+	t.Setenv("GOARCH", "riscv64")
+	if goos, goarch := TargetPlatform(); goos != "windows" || goarch != "riscv64" {
+		t.Errorf("TargetPlatform() = %s, %s, want windows, riscv64", goos, goarch)
+	}
+	conf := &Config{Platforms: []*PlatformConfig{
+		{When: runtime.GOOS, Include: []string{"foo_host.h"}},
+		{When: "windows,riscv64", Include: []string{"foo_windows.h"}},
+	}}
+	conf.ResolvePlatform()
+	if want := []string{"foo_windows.h"}; !reflect.DeepEqual(conf.Include, want) {
+		t.Errorf("ResolvePlatform() includes %v, want %v", conf.Include, want)
+	}
+}
@@ -16,15 +16,92 @@
 
 package types
 
+import "strings"
+
 // Config represents a configuration for the llcppg tool.
 type Config struct {
 	Name         string   `json:"name"`
 	CFlags       string   `json:"cflags"`
 	Libs         string   `json:"libs"`
-	Include      []string `json:"include"`
+	Include      []string `json:"include"` // header files, or glob patterns like foo/*.h and **/*.h
 	Deps         []string `json:"deps"`
 	TrimPrefixes []string `json:"trimPrefixes"`
 	Cplusplus    bool     `json:"cplusplus"`
+	// OutParams marks out parameters of functions, keyed by the C function name.
+	// A wrapper returning them as additional results is generated for each function.
+	OutParams map[string][]string `json:"outParams,omitempty"`
+	// ErrorConventions describes how functions report failures through their return value.
+	// A wrapper returning a Go error is generated for each matched function.
+	ErrorConventions []*ErrorConvention `json:"errorConventions,omitempty"`
+	// Lifecycle configures how constructors are paired with destructors.
+	// An owned handle type is generated for each pair.
+	Lifecycle *Lifecycle `json:"lifecycle,omitempty"`
+	// FuncFields lists the C structs, * matches any sequence of characters, whose
+	// function pointer fields get typed getter and setter methods.
+	FuncFields []string `json:"funcFields,omitempty"`
+	// Exceptions lists the C++ functions and methods, by qualified name like ns::Foo::bar,
+	// * matches any sequence of characters, that may throw. Each call is wrapped by an
+	// extern "C" shim catching the exception, and a wrapper returning it as a Go error is generated.
+	Exceptions []string `json:"exceptions,omitempty"`
+	// StaticName is the pattern of the Go names of C++ static member functions and
+	// static data members, which are package-level functions and variables. {class}
+	// and {name} are replaced by the Go names of the class and the member,
+	// DefaultStaticName is used if it's empty.
+	StaticName string `json:"staticName,omitempty"`
+	// Filters selects the symbols, types, macros and headers which are converted.
+	Filters *Filters `json:"filters,omitempty"`
+	// IncludeAll treats every header under the -I paths of CFlags as a header of
+	// the package, like it's listed in Include, except the ones matched by the glob
+	// patterns of IncludeExclude, relative to the -I paths, like internal/*.h.
+	IncludeAll     bool     `json:"includeAll,omitempty"`
+	IncludeExclude []string `json:"includeExclude,omitempty"`
+	// Platforms are merged in order over the base config by ResolvePlatform,
+	// for the platforms and build variants which satisfy their conditions.
+	Platforms []*PlatformConfig `json:"platforms,omitempty"`
+}
+
+// DefaultStaticName is used if no StaticName is configured, eg. Foo_Create.
+const DefaultStaticName = "{class}_{name}"
+
+// StaticMemberName returns the Go name of the static member name of class by
+// the pattern, DefaultStaticName if pattern is empty.
+func StaticMemberName(pattern, class, name string) string {
+	if pattern == "" {
+		pattern = DefaultStaticName
+	}
+	return strings.NewReplacer("{class}", class, "{name}", name).Replace(pattern)
+}
+
+// Failure kinds of an ErrorConvention.
+const (
+	FailureNonZero  = "nonzero"  // a non-zero return value is an error code
+	FailureNegative = "negative" // a negative return value is an error code
+	FailureNull     = "null"     // a NULL return value is a failure
+)
+
+type ErrorConvention struct {
+	Funcs   []string `json:"funcs"`             // C function names, * matches any sequence of characters
+	Failure string   `json:"failure"`           // FailureNonZero, FailureNegative or FailureNull
+	Message string   `json:"message,omitempty"` // C function returning the message of a failure, eg. sqlite3_errstr
+	Type    string   `json:"type,omitempty"`    // Go error type name, Error or NullError by default
+}
+
+type Lifecycle struct {
+	Patterns  []*LifecyclePair `json:"patterns,omitempty"`  // name patterns, * is the stem shared by the pair
+	Pairs     []*LifecyclePair `json:"pairs,omitempty"`     // explicit pairs of C function names
+	Finalizer bool             `json:"finalizer,omitempty"` // release unreachable handles by a runtime finalizer
+}
+
+// LifecyclePair is a constructor and its destructor, eg. {"new": "*_new", "free": "*_free"}.
+type LifecyclePair struct {
+	New  string `json:"new"`
+	Free string `json:"free"`
+}
+
+// DefaultLifecyclePatterns are used if the lifecycle is configured without patterns.
+var DefaultLifecyclePatterns = []*LifecyclePair{
+	{New: "*_new", Free: "*_free"},
+	{New: "*_create", Free: "*_destroy"},
 }
 
 type SymbolInfo struct {